@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/ask"
+	"mspro-labs/brew-buddy/internal/config"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/searcher"
+)
+
+var (
+	askK      int
+	askAsOf   string
+	askHidden bool
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Ask a question about the catalogue and get an answer citing coffees",
+	Long: `Searches the catalogue for the question (filters and vibe, as in 'search'),
+gives the top coffees' attributes and descriptions to the generative model
+(see LLM_PROVIDER), and prints its answer with the coffees it cites.
+
+Examples:
+  brew-buddy ask "which of the current naturals would make a good espresso blend base and why?"
+  brew-buddy ask --k 12 "what's the most unusual coffee under $8 in stock?"
+
+The model only sees the retrieved coffees, so it can't recommend anything else.
+Citations of coffees it wasn't given are flagged.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runAsk(cmd.Context(), strings.Join(args, " "))
+	},
+}
+
+func init() {
+	askCmd.Flags().IntVar(&askK, "k", ask.DefaultK, "How many coffees to give the model as context")
+	askCmd.Flags().BoolVar(&askHidden, "include-hidden", false, "Include coffees on the ignore list")
+	askCmd.Flags().StringVar(&askAsOf, "as-of", "", "Ask about the catalogue as it was on this date (YYYY-MM-DD)")
+	rootCmd.AddCommand(askCmd)
+}
+
+func runAsk(ctx context.Context, question string) {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	asOf, err := parseAsOf(askAsOf)
+	if err != nil {
+		log.Fatalf("Invalid --as-of date: %v", err)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	gen, err := newGenerator(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize the generative model (check LLM_PROVIDER and its API key): %v", err)
+	}
+	defer gen.Close()
+
+	// Retrieval works from filters alone if the embedder can't start; a question without any fails
+	aiClient, err := newEmbedder(ctx, database)
+	if err != nil {
+		log.Printf("⚠️ AI unavailable, retrieving by filters and cached queries only: %v", err)
+		aiClient = nil
+	} else {
+		defer aiClient.Close()
+	}
+
+	parser := newQueryParser(ctx, database)
+	defer parser.Close()
+	q := parser.Parse(ctx, question)
+
+	cache := db.CachePolicy{TTL: appCfg.SearchCacheTTL, MaxEntries: appCfg.SearchCacheMax}
+	opts := ask.Options{K: askK, Search: searcher.Options{Cache: cache, AsOf: asOf, IncludeHidden: askHidden, Variants: loadGrouper()}}
+	answer, err := ask.Ask(ctx, database, gen, aiClient, q, opts)
+	if err != nil {
+		log.Fatalf("Ask failed: %v", err)
+	}
+
+	fmt.Printf("\n💬 %s\n", question)
+	fmt.Printf("   %s\n", describeQuery(answer.Query))
+	if answer.Unranked {
+		fmt.Println("⚠️ No embedder available: the coffees below only match the filters, not ranked by the rest of the question.")
+	}
+	fmt.Println("------------------------------------")
+	fmt.Println(answer.Text)
+	fmt.Println("------------------------------------")
+	fmt.Printf("Sources (%s, %d coffees):\n", answer.Model, len(answer.Sources))
+	for _, s := range answer.Sources {
+		mark := "  "
+		if s.Cited {
+			mark = "📌"
+		}
+		fmt.Printf("%s #%d %s ($%.2f, %s)\n   %s\n", mark, s.ID, s.Name, s.Price, s.StockStatus, s.URL)
+	}
+	for _, url := range answer.Unknown {
+		fmt.Printf("⚠️ The answer cites %s, which it wasn't given; treat that part with suspicion.\n", url)
+	}
+}
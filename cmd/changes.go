@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+var changesLimit int
+
+var changesCmd = &cobra.Command{
+	Use:   "changes [coffee]",
+	Short: "Show what vendors changed in their listings",
+	Long: `Lists field-level edits detected between scrapes (name, price, origin,
+description, ...), newest first. Pass a coffee ID or URL to see one coffee's history.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := ""
+		if len(args) == 1 {
+			ref = args[0]
+		}
+		runChanges(cmd.Context(), ref)
+	},
+}
+
+func init() {
+	changesCmd.Flags().IntVar(&changesLimit, "limit", 50, "Maximum number of changes to show (0 = all)")
+	rootCmd.AddCommand(changesCmd)
+}
+
+func runChanges(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffeeURL := ""
+	if ref != "" {
+		coffeeURL = mustFindCoffee(ctx, database, ref).URL
+	}
+	changes, err := db.ListCoffeeChanges(ctx, database, coffeeURL, changesLimit)
+	if err != nil {
+		log.Fatalf("Failed to load changes: %v", err)
+	}
+
+	fmt.Println("🕵️ Listing Changes")
+	fmt.Println("------------------------------------")
+	if len(changes) == 0 {
+		fmt.Println("No changes recorded.")
+		return
+	}
+	for _, ch := range changes {
+		name := ch.CoffeeName
+		if name == "" {
+			name = ch.CoffeeURL
+		}
+		fmt.Printf("[%s] %s: %s\n", ch.ChangedAt.Local().Format("2006-01-02 15:04"), name, ch.Field)
+		fmt.Printf("   - %s\n", truncate(orEmpty(ch.OldValue), 150))
+		fmt.Printf("   + %s\n", truncate(orEmpty(ch.NewValue), 150))
+	}
+}
+
+// orEmpty makes blank values visible in diffs.
+func orEmpty(s string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	return s
+}
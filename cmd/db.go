@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/backup"
+	"mspro-labs/brew-buddy/internal/config"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+var (
+	backupGzip  bool
+	backupKeep  int
+	restoreYes  bool
+	pruneVacuum bool
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance (backup, restore, prune)",
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup <path>",
+	Short: "Take an online backup of the database",
+	Long: `Writes a consistent copy of the database using SQLite's VACUUM INTO, which is
+safe while 'serve' or a scrape is running.
+
+If <path> is a directory (or ends in '/'), a timestamped file is created inside it
+and --keep controls how many of those backups are retained.
+
+Examples:
+  brew-buddy db backup /backups/ --gzip --keep 14
+  brew-buddy db backup ./coffee-copy.db`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runBackup(cmd.Context(), args[0])
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore <path>",
+	Short: "Replace the database with a backup",
+	Long: `Checks the backup's integrity and schema version, then replaces the live
+database with it. Gzipped backups (.gz) are decompressed automatically.
+Stop 'serve' and any scheduled scrapes first so nothing writes mid-restore.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRestore(cmd.Context(), args[0])
+	},
+}
+
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old data per the retention policy and checkpoint the WAL",
+	Long: `Deletes data older than the retention settings allow, then checkpoints the WAL
+and reports how much space was reclaimed. Each setting is a duration such as
+"90d" or "2160h"; unset or 0 keeps that data forever.
+
+  RETAIN_INACTIVE    delisted coffees with no notes, stock, roasts or relistings
+  RETAIN_EMBEDDINGS  embeddings of delisted coffees (they drop out of as-of search)
+  RETAIN_SNAPSHOTS   per-scrape listing snapshots used by --as-of
+  RETAIN_CHANGES     the field-level change log
+  RETAIN_INTERPRETATIONS  search interpretations cached for SEARCH_PARSER=llm, by last use
+  RETAIN_ENRICHMENTS      cached attribute extractions for text no coffee still has
+  SEARCH_CACHE_TTL / SEARCH_CACHE_MAX  cached search queries
+
+Freed pages are reused by SQLite before the file grows again. Use --vacuum to
+give them back to the filesystem (needs a moment of exclusive access).
+
+Run it at the end of every scrape with 'brew-buddy scrape --prune'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		database := openDB(ctx)
+		defer database.Close()
+		if err := runPrune(ctx, database, pruneVacuum); err != nil {
+			log.Fatalf("Prune failed: %v", err)
+		}
+	},
+}
+
+func init() {
+	dbBackupCmd.Flags().BoolVar(&backupGzip, "gzip", false, "Compress the backup with gzip")
+	dbBackupCmd.Flags().IntVar(&backupKeep, "keep", 0, "Number of backups to keep in the target directory (0 = all)")
+	dbRestoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Don't ask for confirmation")
+	dbPruneCmd.Flags().BoolVar(&pruneVacuum, "vacuum", false, "Also VACUUM to shrink the database file")
+
+	dbCmd.AddCommand(dbBackupCmd, dbRestoreCmd, dbPruneCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+func runBackup(ctx context.Context, dest string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	path, err := backup.Create(ctx, database, dest, backup.Options{Gzip: backupGzip, Keep: backupKeep})
+	if err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+	info, _ := os.Stat(path)
+	size := int64(0)
+	if info != nil {
+		size = info.Size()
+	}
+	fmt.Printf("💾 Backup written to %s (%.1f KB)\n", path, float64(size)/1024)
+}
+
+func runRestore(ctx context.Context, src string) {
+	if _, err := os.Stat(src); err != nil {
+		log.Fatalf("Cannot read backup: %v", err)
+	}
+	if !restoreYes {
+		fmt.Printf("This will replace the current database with %s. Continue? [y/N] ", src)
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" {
+			fmt.Println("Aborted.")
+			return
+		}
+	}
+
+	database := openDB(ctx)
+	defer database.Close()
+
+	version, err := backup.Restore(ctx, database, src)
+	if err != nil {
+		log.Fatalf("Restore failed: %v", err)
+	}
+	fmt.Printf("♻️ Restored database from %s (schema version %d)\n", src, version)
+}
+
+// runPrune applies the configured retention policy and reports what it reclaimed.
+func runPrune(ctx context.Context, database *sql.DB, vacuum bool) error {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		return err
+	}
+	before := dbFileSize(appCfg.DBPath)
+
+	result, err := db.Prune(ctx, database, db.RetentionPolicy{
+		InactiveCoffees:    appCfg.RetainInactive,
+		InactiveEmbeddings: appCfg.RetainEmbeddings,
+		Snapshots:          appCfg.RetainSnapshots,
+		Changes:            appCfg.RetainChanges,
+		Interpretations:    appCfg.RetainInterpretations,
+		Enrichments:        appCfg.RetainEnrichments,
+		Cache:              db.CachePolicy{TTL: appCfg.SearchCacheTTL, MaxEntries: appCfg.SearchCacheMax},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("🧹 Prune")
+	fmt.Println("------------------------------------")
+	fmt.Printf("Delisted coffees removed:  %d\n", result.Coffees)
+	fmt.Printf("Embeddings dropped:        %d\n", result.Embeddings)
+	fmt.Printf("Scrape snapshots removed:  %d\n", result.Snapshots)
+	fmt.Printf("Change log entries:        %d\n", result.Changes)
+	fmt.Printf("Interpretations dropped:   %d\n", result.Interpretations)
+	fmt.Printf("Extractions dropped:       %d\n", result.Enrichments)
+	fmt.Printf("Cached queries evicted:    %d\n", result.CachedQueries)
+
+	if vacuum {
+		if err := db.Vacuum(ctx, database); err != nil {
+			return fmt.Errorf("vacuum failed: %w", err)
+		}
+	}
+	busy, err := db.Checkpoint(ctx, database)
+	if err != nil {
+		return fmt.Errorf("WAL checkpoint failed: %w", err)
+	}
+	if busy {
+		log.Println("⚠️ WAL checkpoint was blocked by a reader; it will shrink on a later run.")
+	}
+
+	usage, err := db.GetSpaceUsage(ctx, database)
+	if err != nil {
+		return err
+	}
+	after := dbFileSize(appCfg.DBPath)
+	fmt.Println("------------------------------------")
+	fmt.Printf("Size on disk: %.1f KB -> %.1f KB (reclaimed %.1f KB)\n",
+		kb(before), kb(after), kb(before-after))
+	if usage.FreePages > 0 {
+		fmt.Printf("Free inside the file: %.1f KB (reused before it grows; --vacuum returns it)\n", kb(usage.FreeBytes()))
+	}
+	return nil
+}
+
+// dbFileSize is the size of the database file plus its WAL.
+func dbFileSize(path string) int64 {
+	var total int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+func kb(n int64) float64 {
+	return float64(n) / 1024
+}
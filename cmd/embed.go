@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"mspro-labs/brew-buddy/internal/ai"
@@ -11,42 +13,141 @@ import (
 	"mspro-labs/brew-buddy/internal/embedder"
 )
 
+var embedRetrain, embedReembed bool
+
 var embedCmd = &cobra.Command{
 	Use:   "embed",
 	Short: "Generate AI embeddings for new coffees",
-	Long:  `Finds coffees in the database that are missing semantic vectors and generates them using the Gemini API.`,
+	Long: `Finds coffees in the database that are missing semantic vectors, or whose name or description changed since they were embedded, and generates them with the configured embedding provider (Gemini by default; see EMBED_PROVIDER).
+
+With EMBED_PROVIDER=local, vectors come from a built-in model trained on your
+own catalogue the first time it's needed. Run 'embed --retrain' after the
+catalogue has grown a lot to retrain it and re-embed everything.
+
+Each vector is stored with the model that made it, and search only compares
+vectors from the same model. After changing EMBED_PROVIDER or EMBED_MODEL, run
+'embed --reembed' to move every coffee, delisted ones included, to the new model
+and drop the old vectors.
+
+Vectors are stored normalized, as float32 or, with VECTOR_ENCODING=int8, as
+int8 at a quarter of the size. After changing VECTOR_ENCODING the next 'embed'
+converts the stored vectors without embedding anything again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runEmbed(cmd.Context())
+	},
+}
+
+var embedPreviewCmd = &cobra.Command{
+	Use:   "preview <coffee>",
+	Short: "Show the text that gets embedded for a coffee",
+	Long: `Renders the embedding document for a coffee (by ID or URL) with the
+embed_template from config.yaml, and says whether each stored vector was made
+from this exact text. Vectors whose text differs are redone by the next 'embed'.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		runEmbed()
+		runEmbedPreview(cmd.Context(), args[0])
 	},
 }
 
 func init() {
+	embedCmd.AddCommand(embedPreviewCmd)
+	addLockFlags(embedCmd)
+	embedCmd.Flags().BoolVar(&embedRetrain, "retrain", false, "Retrain the local embedder on the current catalogue and re-embed every coffee")
+	embedCmd.Flags().BoolVar(&embedReembed, "reembed", false, "Re-embed every coffee with the current model and drop vectors from other models")
 	rootCmd.AddCommand(embedCmd)
 }
 
-func runEmbed() {
-	ctx := context.Background()
-
+func runEmbed(ctx context.Context) {
 	// 1. Config & DB
 	appCfg, err := config.GetAppConfig()
 	if err != nil {
 		log.Fatalf("Config error: %v", err)
 	}
-	database, err := db.Connect(appCfg.DBPath)
+	if embedRetrain && !strings.EqualFold(appCfg.EmbedProvider, ai.ProviderLocal) {
+		log.Fatalf("--retrain only applies to EMBED_PROVIDER=local")
+	}
+	database, err := db.Connect(ctx, appCfg.DBPath)
 	if err != nil {
 		log.Fatalf("Database error: %v", err)
 	}
 	defer database.Close()
 
-	// 2. Initialize AI
-	aiClient, err := ai.NewClient(ctx)
+	ctx, release := holdCatalogueLease(ctx, database, "embed")
+	defer release()
+	// log.Fatal skips deferred calls, so let go of the lock first
+	fatalf := func(format string, v ...any) {
+		release()
+		log.Fatalf(format, v...)
+	}
+
+	opts := embedOptions()
+	opts.Reembed = embedReembed
+
+	// 2. Optionally retrain the local model, which invalidates every vector
+	if embedRetrain {
+		model, cleared, err := embedder.RetrainLocal(ctx, database, opts.Document)
+		if err != nil {
+			fatalf("Retraining failed: %v", err)
+		}
+		log.Printf("🧮 Retrained the local embedder on %d coffees; %d need embedding again.", model.Documents, cleared)
+	}
+
+	// 3. Initialize AI
+	aiClient, err := newEmbedder(ctx, database)
 	if err != nil {
-		log.Fatalf("Failed to initialize AI client: %v", err)
+		fatalf("Failed to initialize AI client: %v", err)
 	}
 	defer aiClient.Close()
 
-	// 3. Run Shared Embedder Logic
-	if err := embedder.Run(ctx, database, aiClient); err != nil {
-		log.Fatalf("Embedding process failed: %v", err)
+	// 4. Run Shared Embedder Logic
+	summary, err := embedder.Run(ctx, database, aiClient, opts)
+	if err != nil {
+		fatalf("Embedding process failed: %v", err)
+	}
+	if len(summary.Failures) > 0 {
+		fatalf("%d coffee(s) could not be embedded; run 'brew-buddy embed' again to retry them", len(summary.Failures))
+	}
+
+	// 5. Point out vectors search can no longer use
+	counts, err := db.ListEmbeddingModels(ctx, database)
+	if err != nil {
+		fatalf("Failed to count vectors: %v", err)
+	}
+	for _, c := range counts {
+		if c.Model != aiClient.Model() {
+			log.Printf("⚠️ %d vector(s) from %s (%d dims) are unused by search; run 'brew-buddy embed --reembed' to replace them.",
+				c.Vectors, c.Model, c.Dims)
+		}
+	}
+}
+
+func runEmbedPreview(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	text, err := loadDocument().Render(coffee.CoffeeItem)
+	if err != nil {
+		log.Fatalf("Failed to render embed_template: %v", err)
+	}
+	vectors, err := db.GetCoffeeEmbeddings(ctx, database, coffee.URL)
+	if err != nil {
+		log.Fatalf("Failed to load vectors: %v", err)
+	}
+
+	fmt.Printf("📄 Embedding document for #%d %s (%d chars)\n", coffee.ID, coffee.Name, len(text))
+	fmt.Println("------------------------------------")
+	fmt.Println(text)
+	fmt.Println("------------------------------------")
+	if len(vectors) == 0 {
+		fmt.Println("Not embedded yet.")
+	}
+	hash := db.ContentHash(text)
+	for _, v := range vectors {
+		status := "✅ up to date"
+		if v.Hash != hash {
+			status = "♻️ stale, made from different text"
+		}
+		fmt.Printf("%s (%d dims, %s, %s): %s\n", v.Model, v.Dims, v.Encoding, v.CreatedAt.Local().Format("2006-01-02"), status)
 	}
 }
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/spf13/cobra"
+	"mspro-labs/brew-buddy/internal/config"
+	"mspro-labs/brew-buddy/internal/enrich"
+)
+
+var (
+	enrichRedo  bool
+	enrichLimit int
+)
+
+var enrichCmd = &cobra.Command{
+	Use:   "enrich",
+	Short: "Extract process, varietals, region, producer, altitude and tasting notes from descriptions",
+	Long: `Sends each coffee's description to a generative model (see LLM_PROVIDER) and
+stores the process, varietals, region, producer, altitude and tasting notes it finds.
+Replies must be strict JSON matching a fixed schema; anything else is rejected
+and the coffee is retried on the next run.
+
+Extracted values only show where the vendor's listing left a field empty, and
+they feed into the embedding document, so run 'embed' afterwards. Answers are
+cached by the text they came from: unchanged listings and identical
+descriptions are never sent twice.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runEnrich(cmd.Context())
+	},
+}
+
+func init() {
+	addLockFlags(enrichCmd)
+	enrichCmd.Flags().BoolVar(&enrichRedo, "redo", false, "Ask the model again even for coffees whose attributes are current")
+	enrichCmd.Flags().IntVar(&enrichLimit, "limit", 0, "Stop after this many coffees (0 = all)")
+	rootCmd.AddCommand(enrichCmd)
+}
+
+func runEnrich(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	ctx, release := holdCatalogueLease(ctx, database, "enrich")
+	defer release()
+	// log.Fatal skips deferred calls, so let go of the lock first
+	fatalf := func(format string, v ...any) {
+		release()
+		log.Fatalf(format, v...)
+	}
+
+	gen, err := newGenerator(ctx)
+	if err != nil {
+		fatalf("Failed to initialize the generative model (check LLM_PROVIDER and its API key): %v", err)
+	}
+	defer gen.Close()
+
+	summary, err := enrich.Run(ctx, database, gen, enrichOptions())
+	if err != nil {
+		fatalf("Enrichment failed: %v", err)
+	}
+	logEnrichSummary(gen.Model(), summary)
+	if len(summary.Failures) > 0 {
+		fatalf("%d coffee(s) could not be enriched; run 'brew-buddy enrich' again to retry them", len(summary.Failures))
+	}
+}
+
+// enrichOptions reads LLM_RPM and the command line flags.
+func enrichOptions() enrich.Options {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	return enrich.Options{RPM: appCfg.LLMRPM, Redo: enrichRedo, Limit: enrichLimit}
+}
+
+func logEnrichSummary(model string, summary enrich.Summary) {
+	if summary.Pending == 0 {
+		log.Println("✅ Every coffee's attributes are up to date.")
+		return
+	}
+	log.Printf("🧪 Enriched %d of %d coffee(s) with %s (%d from cache, %d request(s)).",
+		summary.Enriched, summary.Pending, model, summary.FromCache, summary.Requests)
+	for _, f := range summary.Failures {
+		log.Printf("   ❌ %s: %v", f.URL, f.Err)
+	}
+}
+
+// autoEnrich extracts attributes for new or changed listings, logging rather
+// than failing if the generative model is unavailable.
+func autoEnrich(ctx context.Context, database *sql.DB) {
+	log.Println("🧪 Starting attribute extraction...")
+	gen, err := newGenerator(ctx)
+	if err != nil {
+		log.Printf("⚠️ Warning: Could not initialize the generative model (check LLM_PROVIDER and its API key): %v", err)
+		return
+	}
+	defer gen.Close()
+
+	summary, err := enrich.Run(ctx, database, gen, enrichOptions())
+	if err != nil {
+		log.Printf("⚠️ Warning: Attribute extraction failed: %v", err)
+		return
+	}
+	logEnrichSummary(gen.Model(), summary)
+}
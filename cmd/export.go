@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/archive"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+	exportActive bool
+	exportOrigin string
+	exportSince  string
+	exportUntil  string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the coffee archive as CSV or JSON Lines",
+	Long: `Writes every coffee in the database (or a filtered subset) with its scrape
+history and your tasting notes. The output can be loaded into another instance
+with 'brew-buddy import'. Attributes found by 'enrich' are written in their own
+columns, apart from the fields as the vendor listed them.
+
+Examples:
+  brew-buddy export -o archive.jsonl
+  brew-buddy export --format csv --origin ethiopia --since 2025-03-01 --until 2025-03-31`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd.Context())
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Output format: csv or jsonl (default: from file extension, else jsonl)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportActive, "active", false, "Only export currently listed coffees")
+	exportCmd.Flags().StringVar(&exportOrigin, "origin", "", "Only export coffees whose origin contains this text")
+	exportCmd.Flags().StringVar(&exportSince, "since", "", "Only coffees seen on or after this date (YYYY-MM-DD)")
+	exportCmd.Flags().StringVar(&exportUntil, "until", "", "Only coffees first listed on or before this date (YYYY-MM-DD)")
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(ctx context.Context) {
+	filter := db.ExportFilter{ActiveOnly: exportActive, Origin: exportOrigin}
+	var err error
+	if filter.Since, err = parseDate(exportSince); err != nil {
+		log.Fatalf("Invalid --since: %v", err)
+	}
+	if filter.Until, err = parseDate(exportUntil); err != nil {
+		log.Fatalf("Invalid --until: %v", err)
+	}
+	if !filter.Until.IsZero() {
+		// Make the end date inclusive
+		filter.Until = filter.Until.AddDate(0, 0, 1).Add(-1)
+	}
+
+	format := exportFormat
+	if format == "" {
+		format = archive.FormatFromPath(exportOutput)
+	}
+
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffees, err := db.ExportCoffees(ctx, database, filter)
+	if err != nil {
+		log.Fatalf("Failed to load coffees: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if exportOutput != "" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", exportOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := archive.Write(out, format, coffees); err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	if exportOutput != "" {
+		fmt.Printf("📤 Exported %d coffees to %s\n", len(coffees), exportOutput)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var hideReason string
+
+var hideCmd = &cobra.Command{
+	Use:   "hide",
+	Short: "Hide coffees or whole vendors from listings and search",
+	Long: `Listings you'll never buy (sample packs, decaf, a vendor you don't use) can be
+put on an ignore list. Hidden coffees are left out of the inventory, 'list' and
+search results unless you ask for them with --include-hidden (or "Show hidden"
+on the web).
+
+Examples:
+  brew-buddy hide coffee 42 --reason "sample pack"
+  brew-buddy hide vendor example.com
+  brew-buddy hide list
+  brew-buddy hide rm 3`,
+}
+
+var hideCoffeeCmd = &cobra.Command{
+	Use:   "coffee <coffee>",
+	Short: "Hide one coffee (by ID or URL)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runHideCoffee(cmd.Context(), args[0])
+	},
+}
+
+var hideVendorCmd = &cobra.Command{
+	Use:   "vendor <host>",
+	Short: "Hide every coffee from a vendor's site",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runHideVendor(cmd.Context(), args[0])
+	},
+}
+
+var hideListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the ignore list",
+	Run: func(cmd *cobra.Command, args []string) {
+		runHideList(cmd.Context())
+	},
+}
+
+var hideRmCmd = &cobra.Command{
+	Use:   "rm <entry-id>",
+	Short: "Remove an entry from the ignore list",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runHideRm(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{hideCoffeeCmd, hideVendorCmd} {
+		c.Flags().StringVar(&hideReason, "reason", "", "Why it's hidden")
+	}
+	hideCmd.AddCommand(hideCoffeeCmd, hideVendorCmd, hideListCmd, hideRmCmd)
+	rootCmd.AddCommand(hideCmd)
+}
+
+func runHideCoffee(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	id, err := db.HideCoffee(ctx, database, coffee.URL, hideReason)
+	if err != nil {
+		log.Fatalf("Failed to hide coffee: %v", err)
+	}
+	fmt.Printf("🙈 Hid %s (entry #%d)\n", coffee.Name, id)
+}
+
+func runHideVendor(ctx context.Context, host string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	id, err := db.HideVendor(ctx, database, host, hideReason)
+	if err != nil {
+		log.Fatalf("Failed to hide vendor: %v", err)
+	}
+	fmt.Printf("🙈 Hid every coffee from %s (entry #%d)\n", host, id)
+}
+
+func runHideList(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	entries, err := db.ListIgnoreEntries(ctx, database)
+	if err != nil {
+		log.Fatalf("Failed to load ignore list: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing is hidden.")
+		return
+	}
+	fmt.Println("🙈 Ignore List")
+	fmt.Println("------------------------------------")
+	for _, e := range entries {
+		what := e.Value
+		if e.Kind == models.IgnoreCoffee && e.Name != "" {
+			what = e.Name
+		}
+		fmt.Printf("#%-4d %-7s %s\n", e.ID, e.Kind, what)
+		if e.Reason != "" {
+			fmt.Printf("      %s\n", e.Reason)
+		}
+	}
+}
+
+func runHideRm(ctx context.Context, arg string) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid entry ID %q", arg)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	n, err := db.DeleteIgnoreEntry(ctx, database, id)
+	if err != nil {
+		log.Fatalf("Failed to remove entry: %v", err)
+	}
+	if n == 0 {
+		log.Fatalf("No ignore list entry #%d", id)
+	}
+	fmt.Printf("👀 Removed entry #%d\n", id)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/archive"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+var importFormat string
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a coffee archive from CSV or JSON Lines",
+	Long: `Loads coffees written by 'brew-buddy export' (use '-' for stdin).
+Coffees are matched by URL: new ones are added, known ones are updated, and
+tasting notes are merged without duplicating existing ones.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runImport(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Input format: csv or jsonl (default: from file extension, else jsonl)")
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImport(ctx context.Context, path string) {
+	format := importFormat
+	if format == "" {
+		format = archive.FormatFromPath(path)
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	coffees, err := archive.Read(in, format)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+
+	database := openDB(ctx)
+	defer database.Close()
+
+	inserted, updated, err := db.ImportCoffees(ctx, database, coffees)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("📥 Imported %d coffees (%d new, %d updated)\n", len(coffees), inserted, updated)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/variants"
+)
+
+var (
+	listAsOf   string
+	listHidden bool
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the coffee catalogue, now or as of a past date",
+	Long: `Lists the coffees currently on offer. With --as-of, rebuilds the catalogue
+from the last scrape made on or before that date, with the prices and stock
+status seen then (including coffees that have since been delisted).
+Coffees on the ignore list (see 'brew-buddy hide') are left out unless
+--include-hidden is given. Sizes and samples of the same coffee are listed
+once, with the other variants underneath (see variant_patterns in the site
+config).
+
+Examples:
+  brew-buddy list
+  brew-buddy list --as-of 2025-03-01`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runList(cmd.Context())
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listAsOf, "as-of", "", "Show the catalogue as it was on this date (YYYY-MM-DD)")
+	listCmd.Flags().BoolVar(&listHidden, "include-hidden", false, "Include coffees on the ignore list")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(ctx context.Context) {
+	asOf, err := parseAsOf(listAsOf)
+	if err != nil {
+		log.Fatalf("Invalid --as-of date: %v", err)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	var coffees []models.InventoryItem
+	if asOf.IsZero() {
+		coffees, err = db.GetActiveCoffees(ctx, database, listHidden)
+		fmt.Println("☕ Current Inventory")
+	} else {
+		var run db.ScrapeRun
+		coffees, run, err = db.GetCoffeesAsOf(ctx, database, asOf, listHidden)
+		fmt.Printf("☕ Inventory as of %s\n", asOf.Format("2006-01-02"))
+		if err == nil {
+			fmt.Println(describeRun(run))
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to load coffees: %v", err)
+	}
+	fmt.Println("------------------------------------")
+	if len(coffees) == 0 {
+		fmt.Println("No coffees found.")
+		return
+	}
+	rows := groupInventory(loadGrouper(), coffees)
+	for _, c := range rows {
+		hidden := ""
+		if c.Hidden {
+			hidden = "  (hidden)"
+		}
+		fmt.Printf("#%-5d %-40s %-15s $%6.2f  %s%s\n",
+			c.ID, truncate(c.Name, 37), truncate(c.Origin, 12), c.Price, c.StockStatus, hidden)
+		for _, v := range c.Variants {
+			fmt.Printf("   └ #%-5d %-34s $%6.2f  %s\n", v.ID, truncate(v.Label, 31), v.Price, v.StockStatus)
+		}
+	}
+	fmt.Println("------------------------------------")
+	fmt.Printf("%d coffee(s), %d listing(s)\n", len(rows), len(coffees))
+}
+
+// inventoryRow is one logical coffee: the listing shown for it, plus the other
+// sizes or samples it's also sold as.
+type inventoryRow struct {
+	models.InventoryItem
+	Variants []variantListing
+}
+
+// variantListing is another listing of a row's coffee.
+type variantListing struct {
+	ID          int64
+	Label       string // e.g. "5 lb" or "Sample", the full name if the pattern can't tell
+	Price       float64
+	StockStatus string
+}
+
+// groupInventory collapses variants of the same coffee into one row. The row
+// shows an in-stock, full-size listing when there is one.
+func groupInventory(grouper *variants.Grouper, coffees []models.InventoryItem) []inventoryRow {
+	groups := variants.Group(grouper, coffees, func(c models.InventoryItem) variants.Listing {
+		return variants.Listing{URL: c.URL, Name: c.Name, Description: c.Description}
+	})
+	rows := make([]inventoryRow, 0, len(groups))
+	for _, g := range groups {
+		primary := 0
+		for i, c := range g {
+			if primaryRank(grouper, c) < primaryRank(grouper, g[primary]) {
+				primary = i
+			}
+		}
+		row := inventoryRow{InventoryItem: g[primary]}
+		for i, c := range g {
+			if i == primary {
+				continue
+			}
+			label := grouper.Label(c.Name)
+			if label == "" {
+				label = c.Name
+			}
+			row.Variants = append(row.Variants, variantListing{ID: c.ID, Label: label, Price: c.Price, StockStatus: c.StockStatus})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// primaryRank orders a group's listings for display, lowest first: in stock
+// beats sold out, and a full bag beats a sample.
+func primaryRank(grouper *variants.Grouper, c models.InventoryItem) int {
+	rank := 0
+	if c.StockStatus != "In Stock" {
+		rank += 2
+	}
+	if strings.Contains(strings.ToLower(grouper.Label(c.Name)), "sample") {
+		rank++
+	}
+	return rank
+}
+
+// describeRun says which scrape an as-of catalogue was rebuilt from.
+func describeRun(run db.ScrapeRun) string {
+	if run.ID == 0 {
+		return "(no scrape recorded that early; approximated from first/last seen dates)"
+	}
+	return fmt.Sprintf("(from the scrape of %s, %d listing(s))", run.RunAt.Local().Format("2006-01-02 15:04"), run.Listings)
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// The catalogue lock lapses this long after its holder's last heartbeat, so a
+// crashed or killed run blocks others for at most leaseTTL.
+const (
+	leaseTTL       = 2 * time.Minute
+	leaseHeartbeat = 30 * time.Second
+	leasePoll      = 5 * time.Second
+)
+
+var (
+	lockWait   bool
+	lockForce  bool
+	breakStale bool
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Show who holds the scrape/embed lock",
+	Long: `'scrape' and 'embed' take a lock in the database while they run, so a manual
+run can't interleave with the scheduled one. The holder renews it every
+30 seconds; if it stops (crash, kill -9) the lock goes stale after 2 minutes
+and the next run takes it over.
+
+Examples:
+  brew-buddy lock
+  brew-buddy lock break --stale
+  brew-buddy lock break`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLockStatus(cmd.Context())
+	},
+}
+
+var lockBreakCmd = &cobra.Command{
+	Use:   "break [name]",
+	Short: "Remove a lock, e.g. one left by a crashed run",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := db.CatalogueLease
+		if len(args) == 1 {
+			name = args[0]
+		}
+		runLockBreak(cmd.Context(), name)
+	},
+}
+
+func init() {
+	lockBreakCmd.Flags().BoolVar(&breakStale, "stale", false, "Only remove the lock if its holder stopped renewing it")
+	lockCmd.AddCommand(lockBreakCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+// addLockFlags adds --wait and --force to a command that takes the catalogue lock.
+func addLockFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&lockWait, "wait", false, "If another scrape or embed is running, wait for it to finish")
+	c.Flags().BoolVar(&lockForce, "force", false, "Take the lock even if another run holds it")
+}
+
+// holdCatalogueLease takes the catalogue lock for purpose, honouring --wait and
+// --force, and renews it in the background until release is called. The
+// returned context is cancelled if the lock is lost, so the work stops rather
+// than interleave with whoever took it.
+func holdCatalogueLease(ctx context.Context, database *sql.DB, purpose string) (context.Context, func()) {
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("%s:%d", host, os.Getpid())
+
+	for waited := false; ; {
+		_, err := db.AcquireLease(ctx, database, db.CatalogueLease, holder, purpose, leaseTTL, lockForce)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, db.ErrLeaseHeld) {
+			log.Fatalf("Failed to take the %s lock: %v", db.CatalogueLease, err)
+		}
+		if !lockWait {
+			log.Fatalf("🔒 %v\nUse --wait to run after it, --force to take the lock anyway, or 'brew-buddy lock break' if it's stale.", err)
+		}
+		if !waited {
+			log.Printf("⏳ %v; waiting...", err)
+			waited = true
+		}
+		select {
+		case <-ctx.Done():
+			log.Fatalf("Gave up waiting for the %s lock", db.CatalogueLease)
+		case <-time.After(leasePoll):
+		}
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(leaseHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leaseCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := db.RenewLease(leaseCtx, database, db.CatalogueLease, holder, leaseTTL)
+			if errors.Is(err, db.ErrLeaseHeld) || errors.Is(err, db.ErrNotFound) {
+				log.Printf("❌ Lost the %s lock: %v", db.CatalogueLease, err)
+				cancel()
+				return
+			} else if err != nil && leaseCtx.Err() == nil {
+				log.Printf("⚠️ Warning: Failed to renew the %s lock: %v", db.CatalogueLease, err)
+			}
+		}
+	}()
+
+	release := func() {
+		cancel()
+		<-done
+		// Release even after an interrupt, or the next run waits out the TTL
+		if err := db.ReleaseLease(context.WithoutCancel(ctx), database, db.CatalogueLease, holder); err != nil {
+			log.Printf("⚠️ Warning: Failed to release the %s lock: %v", db.CatalogueLease, err)
+		}
+	}
+	return leaseCtx, release
+}
+
+func runLockStatus(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	leases, err := db.ListLeases(ctx, database)
+	if err != nil {
+		log.Fatalf("Failed to load locks: %v", err)
+	}
+	if len(leases) == 0 {
+		fmt.Println("🔓 No locks held.")
+		return
+	}
+	now := time.Now()
+	fmt.Println("🔒 Locks")
+	fmt.Println("------------------------------------")
+	for _, l := range leases {
+		state := "held"
+		if l.Stale(now) {
+			state = "STALE"
+		}
+		fmt.Printf("%-10s %-5s %s by %s\n", l.Name, state, l.Purpose, l.Holder)
+		fmt.Printf("           since %s, last heartbeat %s ago, expires %s\n",
+			l.AcquiredAt.Local().Format("2006-01-02 15:04:05"),
+			now.Sub(l.HeartbeatAt).Round(time.Second),
+			l.ExpiresAt.Local().Format("15:04:05"))
+	}
+}
+
+func runLockBreak(ctx context.Context, name string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	n, err := db.BreakLease(ctx, database, name, breakStale)
+	if err != nil {
+		log.Fatalf("Failed to break lock: %v", err)
+	}
+	if n == 0 {
+		if breakStale {
+			fmt.Printf("The %s lock isn't stale; left it alone.\n", name)
+		} else {
+			fmt.Printf("No %s lock to break.\n", name)
+		}
+		return
+	}
+	fmt.Printf("🔓 Broke the %s lock.\n", name)
+}
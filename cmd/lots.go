@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/matcher"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var lotsCmd = &cobra.Command{
+	Use:   "lots",
+	Short: "Link relisted coffees to their earlier listings",
+	Long: `When a vendor moves a product or relists next year's crop under a new URL,
+Brew Buddy proposes a link between the new listing and the old, inactive one
+(by normalized name, origin, region, producer and description similarity). Accepted links
+put both listings in the same lot, so notes and ratings follow the coffee.
+
+Matching runs automatically after every scrape.
+
+Examples:
+  brew-buddy lots review
+  brew-buddy lots accept 12
+  brew-buddy lots reject 13
+  brew-buddy lots show 42`,
+}
+
+var lotsMatchCmd = &cobra.Command{
+	Use:   "match",
+	Short: "Look for new links now",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		database := openDB(ctx)
+		defer database.Close()
+		added, err := matcher.Run(ctx, database)
+		if err != nil {
+			log.Fatalf("Matching failed: %v", err)
+		}
+		fmt.Printf("🔗 %d new link proposal(s). Review them with 'brew-buddy lots review'.\n", added)
+	},
+}
+
+var lotsReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "List pending link proposals",
+	Run: func(cmd *cobra.Command, args []string) {
+		runLotsReview(cmd.Context())
+	},
+}
+
+var lotsAcceptCmd = &cobra.Command{
+	Use:   "accept <link-id>",
+	Short: "Accept a proposed link",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runLotsDecide(cmd.Context(), args[0], true)
+	},
+}
+
+var lotsRejectCmd = &cobra.Command{
+	Use:   "reject <link-id>",
+	Short: "Reject a proposed link",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runLotsDecide(cmd.Context(), args[0], false)
+	},
+}
+
+var lotsShowCmd = &cobra.Command{
+	Use:   "show <coffee>",
+	Short: "Show every listing in a coffee's lot",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runLotsShow(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	lotsCmd.AddCommand(lotsMatchCmd, lotsReviewCmd, lotsAcceptCmd, lotsRejectCmd, lotsShowCmd)
+	rootCmd.AddCommand(lotsCmd)
+}
+
+func runLotsReview(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	links, err := db.ListLotLinks(ctx, database, models.LinkPending)
+	if err != nil {
+		log.Fatalf("Failed to load links: %v", err)
+	}
+	fmt.Println("🔗 Pending Lot Links")
+	fmt.Println("------------------------------------")
+	if len(links) == 0 {
+		fmt.Println("Nothing to review.")
+		return
+	}
+	for _, l := range links {
+		fmt.Printf("#%d [%.0f%%] %s (#%d)\n", l.ID, l.Score*100, l.NewName, l.NewID)
+		fmt.Printf("     looks like %s (#%d)\n", l.OldName, l.OldID)
+		fmt.Printf("     %s\n", l.Reasons)
+	}
+}
+
+func runLotsDecide(ctx context.Context, idArg string, accept bool) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid link ID %q", idArg)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	if accept {
+		if err := db.AcceptLotLink(ctx, database, id); err != nil {
+			log.Fatalf("Failed to accept link: %v", err)
+		}
+		fmt.Printf("✅ Linked. Link #%d accepted.\n", id)
+		return
+	}
+	if err := db.RejectLotLink(ctx, database, id); err != nil {
+		log.Fatalf("Failed to reject link: %v", err)
+	}
+	fmt.Printf("❌ Link #%d rejected.\n", id)
+}
+
+func runLotsShow(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	members, err := db.GetLotMembers(ctx, database, coffee.LotID)
+	if err != nil {
+		log.Fatalf("Failed to load lot: %v", err)
+	}
+	fmt.Printf("📦 Lot #%d\n", coffee.LotID)
+	fmt.Println("------------------------------------")
+	for _, m := range members {
+		status := "inactive"
+		if m.IsActive {
+			status = "active"
+		}
+		fmt.Printf("#%d %s (%s)\n   %s\n", m.ID, m.Name, status, m.URL)
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var (
+	manualVendor string
+	manualItem   models.CoffeeItem
+)
+
+var manualCmd = &cobra.Command{
+	Use:   "manual",
+	Short: "Add coffees that can't be scraped",
+	Long: `Coffees bought from local importers or co-ops without a website can be entered
+by hand. They get a synthetic ID (manual://<vendor>/<name>-<random>), are never
+deactivated by scrapes, and are embedded so they show up in search.
+
+Examples:
+  brew-buddy manual add "Kenya AA Kiambu" --vendor "Local Co-op" --origin Kenya \
+    --process Washed --price 7.50 --description "Blackcurrant, grapefruit, syrupy"
+  brew-buddy manual list`,
+}
+
+var manualAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a coffee by hand",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runManualAdd(cmd.Context(), args[0])
+	},
+}
+
+var manualListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List hand-entered coffees",
+	Run: func(cmd *cobra.Command, args []string) {
+		runManualList(cmd.Context())
+	},
+}
+
+func init() {
+	f := manualAddCmd.Flags()
+	f.StringVar(&manualVendor, "vendor", "", "Who sells it (used in the synthetic ID)")
+	f.StringVar(&manualItem.Origin, "origin", "", "Country of origin")
+	f.StringVar(&manualItem.Region, "region", "", "Region")
+	f.StringVar(&manualItem.Processing, "process", "", "Processing method, e.g. Washed")
+	f.StringVar(&manualItem.TastingNotes, "tasting-notes", "", "Tasting notes")
+	f.StringVar(&manualItem.Description, "description", "", "Free-text description (used for search)")
+	f.StringVar(&manualItem.StockStatus, "stock", "In Stock", "Stock status")
+	f.Float64Var(&manualItem.Price, "price", 0, "Price per lb")
+	f.Float64Var(&manualItem.Score, "score", 0, "Cupping score")
+	manualCmd.AddCommand(manualAddCmd, manualListCmd)
+	rootCmd.AddCommand(manualCmd)
+}
+
+func runManualAdd(ctx context.Context, name string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	item := manualItem
+	item.Name = name
+	id, err := db.AddManualCoffee(ctx, database, manualVendor, item)
+	if err != nil {
+		log.Fatalf("Failed to add coffee: %v", err)
+	}
+	coffee, err := db.GetCoffee(ctx, database, id)
+	if err != nil {
+		log.Fatalf("Failed to load coffee: %v", err)
+	}
+	fmt.Printf("☕ Added #%d %s (%s)\n", coffee.ID, coffee.Name, coffee.URL)
+
+	autoEmbed(ctx, database)
+}
+
+func runManualList(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffees, err := db.GetActiveCoffees(ctx, database, true)
+	if err != nil {
+		log.Fatalf("Failed to load coffees: %v", err)
+	}
+	fmt.Println("✍️ Hand-entered Coffees")
+	fmt.Println("------------------------------------")
+	found := 0
+	for _, c := range coffees {
+		if !c.IsManual() {
+			continue
+		}
+		fmt.Printf("#%-5d %-40s %-15s $%6.2f  %s\n",
+			c.ID, truncate(c.Name, 37), truncate(c.Origin, 12), c.Price, c.URL)
+		found++
+	}
+	if found == 0 {
+		fmt.Println("None yet. Add one with 'brew-buddy manual add'.")
+	}
+}
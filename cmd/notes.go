@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var (
+	noteRating    int
+	noteText      string
+	notePurchased string
+)
+
+var notesCmd = &cobra.Command{
+	Use:   "notes",
+	Short: "Manage personal tasting notes",
+	Long: `Add, list, edit and remove your own tasting notes and ratings.
+Coffees can be referenced by their database ID or product URL.
+
+Examples:
+  brew-buddy notes add 42 --rating 4 --notes "Blueberry bomb, great as espresso"
+  brew-buddy notes list https://example.com/kenya-aa
+  brew-buddy notes edit 7 --rating 5
+  brew-buddy notes rm 7`,
+}
+
+var notesAddCmd = &cobra.Command{
+	Use:   "add <coffee>",
+	Short: "Add a tasting note to a coffee",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runNotesAdd(cmd.Context(), args[0])
+	},
+}
+
+var notesListCmd = &cobra.Command{
+	Use:   "list [coffee]",
+	Short: "List tasting notes (all, or for one coffee)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := ""
+		if len(args) == 1 {
+			ref = args[0]
+		}
+		runNotesList(cmd.Context(), ref)
+	},
+}
+
+var notesEditCmd = &cobra.Command{
+	Use:   "edit <note-id>",
+	Short: "Edit an existing tasting note",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runNotesEdit(cmd.Context(), cmd, args[0])
+	},
+}
+
+var notesRmCmd = &cobra.Command{
+	Use:   "rm <note-id>",
+	Short: "Remove a tasting note",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runNotesRm(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{notesAddCmd, notesEditCmd} {
+		c.Flags().IntVar(&noteRating, "rating", 0, fmt.Sprintf("Rating from 1 to %d (0 for none)", models.MaxRating))
+		c.Flags().StringVar(&noteText, "notes", "", "Free-text tasting notes")
+		c.Flags().StringVar(&notePurchased, "purchased", "", "Purchase date (YYYY-MM-DD), defaults to today")
+	}
+	notesCmd.AddCommand(notesAddCmd, notesListCmd, notesEditCmd, notesRmCmd)
+	rootCmd.AddCommand(notesCmd)
+}
+
+func runNotesAdd(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	purchased, err := parseDate(notePurchased)
+	if err != nil {
+		log.Fatalf("Invalid --purchased date: %v", err)
+	}
+
+	id, err := db.AddNote(ctx, database, models.Note{
+		CoffeeURL:   coffee.URL,
+		Rating:      noteRating,
+		Notes:       noteText,
+		PurchasedAt: purchased,
+	})
+	if err != nil {
+		log.Fatalf("Failed to add note: %v", err)
+	}
+	fmt.Printf("📝 Added note #%d to %s\n", id, coffee.Name)
+}
+
+func runNotesList(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	var notes []models.Note
+	var err error
+	if ref != "" {
+		// Include notes from earlier listings of the same lot
+		notes, err = db.ListLotNotes(ctx, database, mustFindCoffee(ctx, database, ref).LotID)
+	} else {
+		notes, err = db.ListNotes(ctx, database, "")
+	}
+	if err != nil {
+		log.Fatalf("Failed to list notes: %v", err)
+	}
+	fmt.Println("📝 Tasting Notes")
+	fmt.Println("------------------------------------")
+	if len(notes) == 0 {
+		fmt.Println("No notes found.")
+		return
+	}
+	for _, n := range notes {
+		rating := "unrated"
+		if n.Rating > 0 {
+			rating = fmt.Sprintf("%d/%d", n.Rating, models.MaxRating)
+		}
+		name := n.CoffeeName
+		if name == "" {
+			name = n.CoffeeURL
+		}
+		fmt.Printf("#%d [%s] %s (%s)\n", n.ID, n.PurchasedAt.Local().Format("2006-01-02"), name, rating)
+		if n.Notes != "" {
+			fmt.Printf("   %s\n", n.Notes)
+		}
+	}
+}
+
+func runNotesEdit(ctx context.Context, cmd *cobra.Command, idArg string) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid note ID %q", idArg)
+	}
+
+	database := openDB(ctx)
+	defer database.Close()
+
+	note, err := db.GetNote(ctx, database, id)
+	if errors.Is(err, db.ErrNotFound) {
+		log.Fatalf("No note #%d", id)
+	} else if err != nil {
+		log.Fatalf("Failed to load note: %v", err)
+	}
+
+	// Only overwrite the fields that were actually passed
+	if cmd.Flags().Changed("rating") {
+		note.Rating = noteRating
+	}
+	if cmd.Flags().Changed("notes") {
+		note.Notes = noteText
+	}
+	if cmd.Flags().Changed("purchased") {
+		if note.PurchasedAt, err = parseDate(notePurchased); err != nil {
+			log.Fatalf("Invalid --purchased date: %v", err)
+		}
+	}
+
+	if err := db.UpdateNote(ctx, database, note); err != nil {
+		log.Fatalf("Failed to update note: %v", err)
+	}
+	fmt.Printf("✏️ Updated note #%d\n", id)
+}
+
+func runNotesRm(ctx context.Context, idArg string) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid note ID %q", idArg)
+	}
+
+	database := openDB(ctx)
+	defer database.Close()
+
+	affected, err := db.DeleteNote(ctx, database, id)
+	if err != nil {
+		log.Fatalf("Failed to remove note: %v", err)
+	}
+	fmt.Printf("🗑️ Done. Removed %d note(s).\n", affected)
+}
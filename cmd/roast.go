@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var (
+	roastCharge     float64
+	roastYield      float64
+	roastFirstCrack string
+	roastDrop       string
+	roastLevel      string
+	roastNotes      string
+	roastDate       string
+	roastWithdraw   bool
+)
+
+var roastCmd = &cobra.Command{
+	Use:   "roast",
+	Short: "Log roast batches",
+	Long: `Keeps a roast log for the coffees you buy. Weights are in grams and
+times are minutes:seconds from charge. Weight loss and development time
+ratio are calculated for you.
+
+Examples:
+  brew-buddy roast add 42 --charge 250 --yield 212 --first-crack 8:30 --drop 10:15 --level "City+" --withdraw
+  brew-buddy roast list 42
+  brew-buddy roast rm 3`,
+}
+
+var roastAddCmd = &cobra.Command{
+	Use:   "add <coffee>",
+	Short: "Log a roast batch",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRoastAdd(cmd.Context(), args[0])
+	},
+}
+
+var roastListCmd = &cobra.Command{
+	Use:   "list [coffee]",
+	Short: "List roast batches (all, or for one coffee)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := ""
+		if len(args) == 1 {
+			ref = args[0]
+		}
+		runRoastList(cmd.Context(), ref)
+	},
+}
+
+var roastRmCmd = &cobra.Command{
+	Use:   "rm <roast-id>",
+	Short: "Remove a roast batch",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRoastRm(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	f := roastAddCmd.Flags()
+	f.Float64Var(&roastCharge, "charge", 0, "Green (charge) weight in grams")
+	f.Float64Var(&roastYield, "yield", 0, "Roasted (yield) weight in grams")
+	f.StringVar(&roastFirstCrack, "first-crack", "", "Time of first crack (mm:ss)")
+	f.StringVar(&roastDrop, "drop", "", "Drop time (mm:ss)")
+	f.StringVar(&roastLevel, "level", "", "Roast level, e.g. City+")
+	f.StringVar(&roastNotes, "notes", "", "Free-text notes")
+	f.StringVar(&roastDate, "date", "", "Roast date (YYYY-MM-DD), defaults to today")
+	f.BoolVar(&roastWithdraw, "withdraw", false, "Also withdraw the charge weight from the stock ledger")
+	roastAddCmd.MarkFlagRequired("charge")
+
+	roastCmd.AddCommand(roastAddCmd, roastListCmd, roastRmCmd)
+	rootCmd.AddCommand(roastCmd)
+}
+
+// recordRoast saves a roast and, if asked, withdraws its charge weight from the stock ledger.
+// With a withdrawal, nothing is saved unless both succeed.
+func recordRoast(ctx context.Context, database *sql.DB, r models.Roast, withdraw bool) (int64, error) {
+	if withdraw {
+		return db.AddRoastWithWithdrawal(ctx, database, r)
+	}
+	return db.AddRoast(ctx, database, r)
+}
+
+func runRoastAdd(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	roastedAt, err := parseDate(roastDate)
+	if err != nil {
+		log.Fatalf("Invalid --date: %v", err)
+	}
+	firstCrack, err := models.ParseMinSec(roastFirstCrack)
+	if err != nil {
+		log.Fatalf("Invalid --first-crack: %v", err)
+	}
+	drop, err := models.ParseMinSec(roastDrop)
+	if err != nil {
+		log.Fatalf("Invalid --drop: %v", err)
+	}
+
+	r := models.Roast{
+		CoffeeURL:     coffee.URL,
+		RoastedAt:     roastedAt,
+		ChargeGrams:   roastCharge,
+		YieldGrams:    roastYield,
+		FirstCrackSec: firstCrack,
+		DropSec:       drop,
+		RoastLevel:    roastLevel,
+		Notes:         roastNotes,
+	}
+	id, err := recordRoast(ctx, database, r, roastWithdraw)
+	if err != nil {
+		log.Fatalf("Failed to log roast: %v", err)
+	}
+	fmt.Printf("🔥 Logged roast #%d of %s", id, coffee.Name)
+	if r.YieldGrams > 0 {
+		fmt.Printf(" (%.1f%% loss", r.WeightLossPct())
+		if r.DTRPct() > 0 {
+			fmt.Printf(", DTR %.1f%%", r.DTRPct())
+		}
+		fmt.Print(")")
+	}
+	fmt.Println()
+}
+
+func runRoastList(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffeeURL := ""
+	if ref != "" {
+		coffeeURL = mustFindCoffee(ctx, database, ref).URL
+	}
+	roasts, err := db.ListRoasts(ctx, database, coffeeURL)
+	if err != nil {
+		log.Fatalf("Failed to list roasts: %v", err)
+	}
+	fmt.Println("🔥 Roast Log")
+	fmt.Println("------------------------------------")
+	if len(roasts) == 0 {
+		fmt.Println("No roasts logged.")
+		return
+	}
+	for _, r := range roasts {
+		fmt.Printf("#%d [%s] %s: %.0fg → %.0fg (%.1f%% loss)  FC %s  drop %s  DTR %.1f%%  %s\n",
+			r.ID, r.RoastedAt.Local().Format("2006-01-02"), r.CoffeeName,
+			r.ChargeGrams, r.YieldGrams, r.WeightLossPct(),
+			models.FormatMinSec(r.FirstCrackSec), models.FormatMinSec(r.DropSec), r.DTRPct(), r.RoastLevel)
+		if r.Notes != "" {
+			fmt.Printf("   %s\n", r.Notes)
+		}
+	}
+}
+
+func runRoastRm(ctx context.Context, idArg string) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid roast ID %q", idArg)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	affected, err := db.DeleteRoast(ctx, database, id)
+	if err != nil {
+		log.Fatalf("Failed to remove roast: %v", err)
+	}
+	fmt.Printf("🗑️ Done. Removed %d roast(s).\n", affected)
+}
@@ -1,9 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/config"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/embedder"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/variants"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -22,8 +39,16 @@ to find coffees based on flavor profiles.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The first interrupt cancels the command's context, which aborts in-flight
+// database queries; a second one kills the process as usual.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -37,3 +62,132 @@ func init() {
 	// Example global flag (we'll wire this up to your actual config later):
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml)")
 }
+
+// openDB loads the app config and connects to the database, exiting on failure.
+func openDB(ctx context.Context) *sql.DB {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	database, err := db.Connect(ctx, appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("Database error: %v", err)
+	}
+	return database
+}
+
+// newEmbedder creates the embedding provider chosen by the EMBED_* settings.
+// The local provider's model is loaded from (or first trained on) the database.
+func newEmbedder(ctx context.Context, database *sql.DB) (ai.Embedder, error) {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := ai.Options{
+		Provider: appCfg.EmbedProvider,
+		Model:    appCfg.EmbedModel,
+		BaseURL:  appCfg.EmbedURL,
+		APIKey:   appCfg.EmbedAPIKey,
+	}
+	if strings.EqualFold(opts.Provider, ai.ProviderLocal) {
+		if opts.Local, err = embedder.LoadLocalModel(ctx, database, loadDocument()); err != nil {
+			return nil, fmt.Errorf("failed to load the local embedder: %w", err)
+		}
+	}
+	return ai.New(ctx, opts)
+}
+
+// newGenerator creates the generative model client from the LLM_* settings.
+func newGenerator(ctx context.Context) (ai.Generator, error) {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		return nil, err
+	}
+	return ai.NewGenerator(ctx, ai.Options{
+		Provider: appCfg.LLMProvider,
+		Model:    appCfg.LLMModel,
+		BaseURL:  appCfg.LLMURL,
+		APIKey:   appCfg.LLMAPIKey,
+	})
+}
+
+// embedOptions reads the EMBED_* batching and rate limit settings and the
+// site config's embedding template.
+func embedOptions() embedder.Options {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	return embedder.Options{
+		BatchSize: appCfg.EmbedBatchSize,
+		Workers:   appCfg.EmbedWorkers,
+		RPM:       appCfg.EmbedRPM,
+		TPM:       appCfg.EmbedTPM,
+		Document:  loadDocument(),
+		Encoding:  appCfg.VectorEncoding,
+	}
+}
+
+// loadOptionalSiteConfig loads the site config for commands that only use its
+// optional settings. A missing file gives an empty config.
+func loadOptionalSiteConfig() *config.SiteConfig {
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	siteCfg, err := config.LoadSiteConfig(appCfg.ConfigPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &config.SiteConfig{}
+	} else if err != nil {
+		log.Fatalf("Failed to load site config: %v", err)
+	}
+	return siteCfg
+}
+
+// loadGrouper builds the variant grouper with the site config's extra patterns.
+// Without a site config only the built-in patterns are used.
+func loadGrouper() *variants.Grouper {
+	grouper, err := variants.New(loadOptionalSiteConfig().VariantPatterns)
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	return grouper
+}
+
+// loadDocument parses the site config's embedding template, or the default one.
+func loadDocument() *embedder.Document {
+	doc, err := embedder.NewDocument(loadOptionalSiteConfig().EmbedTemplate)
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	return doc
+}
+
+// parseDate parses a YYYY-MM-DD date in local time. Empty input yields the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// parseAsOf parses an --as-of date, returning the end of that day so the
+// catalogue includes every scrape made on it. Empty input yields the zero time.
+func parseAsOf(s string) (time.Time, error) {
+	day, err := parseDate(s)
+	if err != nil || day.IsZero() {
+		return day, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// mustFindCoffee resolves a coffee reference or exits with a friendly message.
+func mustFindCoffee(ctx context.Context, database *sql.DB, ref string) models.InventoryItem {
+	coffee, err := db.FindCoffee(ctx, database, ref)
+	if errors.Is(err, db.ErrNotFound) {
+		log.Fatalf("No coffee found for %q", ref)
+	} else if err != nil {
+		log.Fatalf("Failed to look up coffee: %v", err)
+	}
+	return coffee
+}
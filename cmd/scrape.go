@@ -1,33 +1,39 @@
 package cmd
 
 import (
-	"log"
 	"context"
+	"database/sql"
+	"log"
 
 	"github.com/spf13/cobra"
-	
-	"mspro-labs/brew-buddy/internal/ai"
+
 	"mspro-labs/brew-buddy/internal/config"
 	"mspro-labs/brew-buddy/internal/db"
 	"mspro-labs/brew-buddy/internal/embedder"
+	"mspro-labs/brew-buddy/internal/matcher"
 	"mspro-labs/brew-buddy/internal/scraper"
 )
 
+var scrapePrune, scrapeEnrich bool
+
 // scrapeCmd represents the scrape command
 var scrapeCmd = &cobra.Command{
 	Use:   "scrape",
 	Short: "Run the scraper once auto-runs embed",
 	Long:  `Connects to the target site, scrapes current inventory, updates the local database, and runs embed on new finds.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runScrape()
+		runScrape(cmd.Context())
 	},
 }
 
 func init() {
+	addLockFlags(scrapeCmd)
+	scrapeCmd.Flags().BoolVar(&scrapeEnrich, "enrich", false, "Extract attributes from new or changed descriptions before embedding (see 'enrich')")
+	scrapeCmd.Flags().BoolVar(&scrapePrune, "prune", false, "Apply the retention policy after saving (see 'db prune')")
 	rootCmd.AddCommand(scrapeCmd)
 }
 
-func runScrape() {
+func runScrape(ctx context.Context) {
 	// 1. Load Config
 	appCfg, err := config.GetAppConfig()
 	if err != nil {
@@ -39,21 +45,30 @@ func runScrape() {
 	}
 
 	// 2. Connect to DB
-	database, err := db.Connect(appCfg.DBPath)
+	database, err := db.Connect(ctx, appCfg.DBPath)
 	if err != nil {
 		log.Fatalf("Database error: %v", err)
 	}
 	defer database.Close()
 
+	// Nothing else may touch the active flags until we're done
+	ctx, release := holdCatalogueLease(ctx, database, "scrape")
+	defer release()
+	// log.Fatal skips deferred calls, so let go of the lock first
+	fatalf := func(format string, v ...any) {
+		release()
+		log.Fatalf(format, v...)
+	}
+
 	// 3. Prep DB (Mark old items inactive)
-	if err := db.MarkAllAsInactive(database); err != nil {
-		log.Fatalf("Failed to mark inactive: %v", err)
+	if err := db.MarkAllAsInactive(ctx, database); err != nil {
+		fatalf("Failed to mark inactive: %v", err)
 	}
 
 	// 4. Run Scraper
 	items, err := scraper.Run(siteCfg)
 	if err != nil {
-		log.Fatalf("Scraping failed: %v", err)
+		fatalf("Scraping failed: %v", err)
 	}
 	log.Printf("Scraper found %d valid items.", len(items))
 
@@ -63,23 +78,47 @@ func runScrape() {
 	}
 
 	// 5. Save to DB
-	count, err := db.SaveData(database, items)
+	count, err := db.SaveData(ctx, database, items)
 	if err != nil {
-		log.Fatalf("Failed to save data: %v", err)
+		fatalf("Failed to save data: %v", err)
 	}
 	log.Printf("SUCCESS: Upserted %d records.", count)
 
-	// 6. Auto-run Embedder
+	// 6. Optionally extract attributes, which the embedding document can use
+	if scrapeEnrich {
+		autoEnrich(ctx, database)
+	}
+
+	// 7. Auto-run Embedder
+	autoEmbed(ctx, database)
+
+	// 8. Propose links between new listings and retired ones
+	added, err := matcher.Run(ctx, database)
+	if err != nil {
+		log.Printf("⚠️ Warning: Lot matching failed: %v", err)
+	} else if added > 0 {
+		log.Printf("🔗 %d possible relisting(s) found. Review with 'brew-buddy lots review'.", added)
+	}
+
+	// 9. Optionally apply the retention policy
+	if scrapePrune {
+		if err := runPrune(ctx, database, false); err != nil {
+			log.Printf("⚠️ Warning: Prune failed: %v", err)
+		}
+	}
+}
+
+// autoEmbed embeds any new coffees, logging rather than failing if the AI is unavailable.
+func autoEmbed(ctx context.Context, database *sql.DB) {
 	log.Println("🤖 Starting automatic embedding...")
-	ctx := context.Background()
-	aiClient, err := ai.NewClient(ctx)
+	aiClient, err := newEmbedder(ctx, database)
 	if err != nil {
-		log.Printf("⚠️ Warning: Could not initialize AI for auto-embedding (check GEMINI_API_KEY): %v", err)
+		log.Printf("⚠️ Warning: Could not initialize AI for auto-embedding (check EMBED_PROVIDER and its API key): %v", err)
 		return // Don't fail the whole scrape if AI fails
 	}
 	defer aiClient.Close()
 
-	if err := embedder.Run(ctx, database, aiClient); err != nil {
+	if _, err := embedder.Run(ctx, database, aiClient, embedOptions()); err != nil {
 		log.Printf("⚠️ Warning: Auto-embedding failed: %v", err)
 	}
 }
@@ -5,19 +5,21 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"mspro-labs/brew-buddy/internal/ai"
 	"mspro-labs/brew-buddy/internal/config"
 	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/searcher"
 )
 
-type searchResult struct {
-	item  db.CoffeeVector
-	score float32
-}
+var (
+	historySort  string
+	searchAsOf   string
+	searchHidden bool
+	searchRaw    bool
+)
 
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
@@ -26,35 +28,55 @@ var searchCmd = &cobra.Command{
 Examples:
   brew-buddy search "funky and fruity with berry notes"
   brew-buddy search "classic comforting chocolate"
+  brew-buddy search --as-of 2025-03-01 "juicy Ethiopian"
+  brew-buddy search "washed Kenyan under $9/lb in stock, bright and juicy"
+
+Origins, processes, a price ceiling, "in stock" and vendors are applied as
+filters, and only the rest of the query (the vibe) is ranked by meaning. The
+interpretation is printed above the results. Set SEARCH_PARSER=llm to have the
+generative model (see LLM_PROVIDER) interpret queries, or pass --raw to rank
+the whole text with no filters.
 
 History commands:
-  brew-buddy search history
+  brew-buddy search history [--sort popular|recent]
   brew-buddy search clear "query string"
-  brew-buddy search clear all`,
+  brew-buddy search clear expired
+  brew-buddy search clear all
+
+Query embeddings are cached (case and spacing insensitive). Set SEARCH_CACHE_TTL
+(e.g. "720h") and SEARCH_CACHE_MAX to control expiry and size.`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		handleSearch(args)
+		handleSearch(cmd.Context(), args)
 	},
 }
 
 func init() {
+	searchCmd.Flags().StringVar(&historySort, "sort", db.SortPopular, "History order: popular or recent")
+	searchCmd.Flags().BoolVar(&searchHidden, "include-hidden", false, "Include coffees on the ignore list")
+	searchCmd.Flags().BoolVar(&searchRaw, "raw", false, "Rank the whole query by meaning, without extracting filters")
+	searchCmd.Flags().StringVar(&searchAsOf, "as-of", "", "Search the catalogue as it was on this date (YYYY-MM-DD)")
 	rootCmd.AddCommand(searchCmd)
 }
 
-func handleSearch(args []string) {
+func handleSearch(ctx context.Context, args []string) {
 	// 1. Setup
-	appCfg, _ := config.GetAppConfig()
-	database, err := db.Connect(appCfg.DBPath)
+	appCfg, err := config.GetAppConfig()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	database, err := db.Connect(ctx, appCfg.DBPath)
 	if err != nil {
 		log.Fatalf("Database error: %v", err)
 	}
 	defer database.Close()
 
+	cache := db.CachePolicy{TTL: appCfg.SearchCacheTTL, MaxEntries: appCfg.SearchCacheMax}
 	command := strings.ToLower(args[0])
 
 	// 2. Commands
 	if command == "history" {
-		entries, err := db.ListSearchHistory(database)
+		entries, err := db.ListSearchHistory(ctx, database, historySort)
 		if err != nil {
 			log.Fatalf("Failed to list history: %v", err)
 		}
@@ -65,23 +87,26 @@ func handleSearch(args []string) {
 			return
 		}
 		for _, e := range entries {
-			fmt.Printf("[%s] %s\n", e.CreatedAt.Format("2006-01-02 15:04"), e.QueryText)
+			fmt.Printf("[%4d hits, last used %s] %s\n", e.HitCount, e.LastUsedAt.Local().Format("2006-01-02 15:04"), e.QueryText)
 		}
 		return
 	}
 
 	if command == "clear" {
 		if len(args) < 2 {
-			log.Fatal("Usage: brew-buddy search clear \"query text\" (or 'all')")
+			log.Fatal("Usage: brew-buddy search clear \"query text\" (or 'expired' / 'all')")
 		}
-		target := strings.ToLower(strings.TrimSpace(strings.Join(args[1:], " ")))
+		target := strings.TrimSpace(strings.Join(args[1:], " "))
 		var affected int64
 		var err error
 
-		if target == "all" {
-			affected, err = db.ClearAllSearchHistory(database)
-		} else {
-			affected, err = db.ClearSearchHistory(database, target)
+		switch db.NormalizeQuery(target) {
+		case "all":
+			affected, err = db.ClearAllSearchHistory(ctx, database)
+		case "expired":
+			affected, err = db.EvictSearchCache(ctx, database, cache)
+		default:
+			affected, err = db.ClearSearchHistory(ctx, database, target)
 		}
 
 		if err != nil {
@@ -93,79 +118,109 @@ func handleSearch(args []string) {
 
 	// 3. Perform regular search
 	query := strings.Join(args, " ")
-	if err := performSearch(database, query); err != nil {
+	asOf, err := parseAsOf(searchAsOf)
+	if err != nil {
+		log.Fatalf("Invalid --as-of date: %v", err)
+	}
+	opts := searcher.Options{Cache: cache, AsOf: asOf, IncludeHidden: searchHidden, Variants: loadGrouper()}
+	if err := performSearch(ctx, database, query, opts); err != nil {
 		log.Fatalf("Search failed: %v", err)
 	}
 }
 
-func performSearch(database *sql.DB, queryText string) error {
-	ctx := context.Background()
-
-	// A. Try Cache
-	queryVectorFloats, _ := tryCache(database, queryText)
-
-	// B. If miss, use AI
-	if queryVectorFloats == nil {
-		fmt.Println("🤖 Cache miss. Asking Gemini...")
-		aiClient, err := ai.NewClient(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to init AI: %w", err)
-		}
-		defer aiClient.Close()
-
-		// Embed and get both blob (for DB) and floats (for math now)
-		blob, floats, err := aiClient.EmbedString(ctx, queryText)
-		if err != nil {
-			return fmt.Errorf("embedding failed: %w", err)
-		}
+func performSearch(ctx context.Context, database *sql.DB, queryText string, opts searcher.Options) error {
+	q := searcher.Raw(queryText)
+	if !searchRaw {
+		parser := newQueryParser(ctx, database)
+		defer parser.Close()
+		q = parser.Parse(ctx, queryText)
+	}
 
-		// Save to cache for next time
-		_ = db.SaveCachedQuery(database, queryText, blob)
-		queryVectorFloats = floats
+	// Cached queries and filter-only searches work without the AI, so only warn if it can't start
+	aiClient, err := newEmbedder(ctx, database)
+	if err != nil {
+		log.Printf("⚠️ AI unavailable, only cached queries will work: %v", err)
+		aiClient = nil
 	} else {
-		fmt.Println("⚡ Cache hit! Using saved vector.")
+		defer aiClient.Close()
 	}
 
-	// C. Compare against all coffees
-	coffees, err := db.GetCoffeeVectors(database)
+	results, err := searcher.Perform(ctx, database, aiClient, q, opts)
 	if err != nil {
-		return fmt.Errorf("failed to load coffees: %w", err)
+		return err
 	}
 
-	var results []searchResult
-	for _, coffee := range coffees {
-		coffeeFloats, err := ai.BytesToFloats(coffee.Vector)
-		if err != nil {
-			continue
-		}
-		score := ai.CosineSimilarity(queryVectorFloats, coffeeFloats)
-		results = append(results, searchResult{item: coffee, score: score})
+	if opts.AsOf.IsZero() {
+		fmt.Printf("\n🔍 Top matches for: \"%s\"\n", queryText)
+	} else {
+		fmt.Printf("\n🔍 Top matches for: \"%s\" as of %s\n", queryText, opts.AsOf.Format("2006-01-02"))
 	}
-
-	// D. Sort & Display
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
-	})
-
-	fmt.Printf("\n🔍 Top matches for: \"%s\"\n\n", queryText)
+	fmt.Printf("   %s\n\n", describeQuery(q))
 	for i, r := range results {
-		if i >= 5 {
-			break
+		if q.Vibe == "" {
+			fmt.Printf("#%d $%.2f %s (%s) · %s\n", i+1, r.Item.Price, r.Item.Name, r.Item.Origin, r.Item.StockStatus)
+		} else {
+			fmt.Printf("#%d [%.1f%% match] %s (%s) $%.2f\n", i+1, r.Score*100, r.Item.Name, r.Item.Origin, r.Item.Price)
 		}
-		fmt.Printf("#%d [%.1f%% match] %s (%s)\n", i+1, r.score*100, r.item.Name, r.item.Origin)
-		fmt.Printf("   %s\n\n", truncate(r.item.Description, 150))
+		if len(r.Variants) > 0 {
+			fmt.Printf("   Also sold as: %s\n", strings.Join(r.Variants, ", "))
+		}
+		fmt.Printf("   %s\n\n", truncate(r.Item.Description, 150))
 	}
 
 	return nil
 }
 
-// Helper to try loading from cache and converting immediately
-func tryCache(database *sql.DB, text string) ([]float32, error) {
-	blob, err := db.GetCachedQuery(database, text)
+// describeQuery explains how a query was interpreted, in one line.
+func describeQuery(q searcher.Query) string {
+	terms := q.Terms()
+	if len(terms) == 0 {
+		terms = []string{"no filters"}
+	}
+	vibe := "listed cheapest first"
+	if q.Vibe != "" {
+		vibe = fmt.Sprintf("ranked by %q", q.Vibe)
+	}
+	return fmt.Sprintf("Understood (%s): %s; %s", q.Parser, strings.Join(terms, ", "), vibe)
+}
+
+// queryParser interprets searches with the rules, or with SEARCH_PARSER=llm
+// with the generative model, falling back to the rules.
+type queryParser struct {
+	database *sql.DB
+	gen      ai.Generator
+}
+
+// newQueryParser sets up the configured parser. If the generative model
+// can't start, the rules are used instead.
+func newQueryParser(ctx context.Context, database *sql.DB) *queryParser {
+	p := &queryParser{database: database}
+	appCfg, err := config.GetAppConfig()
+	if err != nil || appCfg.SearchParser != "llm" {
+		return p
+	}
+	if p.gen, err = newGenerator(ctx); err != nil {
+		log.Printf("⚠️ Generative model unavailable, interpreting searches with rules: %v", err)
+	}
+	return p
+}
+
+// Parse splits a search into filters and vibe.
+func (p *queryParser) Parse(ctx context.Context, text string) searcher.Query {
+	vendors, err := db.ListVendors(ctx, p.database)
 	if err != nil {
-		return nil, err
+		log.Printf("Warning: failed to list vendors: %v", err)
+	}
+	if p.gen == nil {
+		return searcher.Parse(text, vendors)
+	}
+	return searcher.Interpret(ctx, p.database, p.gen, text, vendors)
+}
+
+func (p *queryParser) Close() {
+	if p.gen != nil {
+		p.gen.Close()
 	}
-	return ai.BytesToFloats(blob)
 }
 
 func truncate(s string, max int) string {
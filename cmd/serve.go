@@ -2,7 +2,8 @@ package cmd
 
 import (
 	"context"
-//	"fmt"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
@@ -10,23 +11,33 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"mspro-labs/brew-buddy/internal/ai"
 	"mspro-labs/brew-buddy/internal/config"
 	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
 	"mspro-labs/brew-buddy/internal/searcher"
 	"mspro-labs/brew-buddy/internal/web"
 )
 
 // Helper for templates
 var funcMap = template.FuncMap{
-	"mul": func(a, b float32) float32 { return a * b },
+	"mul":   func(a, b float32) float32 { return a * b },
+	"mul64": func(a, b float64) float64 { return a * b },
+	"rating": func(r int) string {
+		if r <= 0 {
+			return "–"
+		}
+		return fmt.Sprintf("%d/%d", r, models.MaxRating)
+	},
+	"date":   func(t time.Time) string { return t.Local().Format("2006-01-02") },
+	"minsec": models.FormatMinSec,
+	"manual": models.IsManualURL,
 }
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the Web UI server",
 	Run: func(cmd *cobra.Command, args []string) {
-		runServer()
+		runServer(cmd.Context())
 	},
 }
 
@@ -34,26 +45,44 @@ func init() {
 	rootCmd.AddCommand(serveCmd)
 }
 
-func runServer() {
+func runServer(ctx context.Context) {
 	// 1. Setup
 	appCfg, err := config.GetAppConfig()
 	if err != nil {
 		log.Fatalf("Config error: %v", err)
 	}
-	database, err := db.Connect(appCfg.DBPath)
+	database, err := db.Connect(ctx, appCfg.DBPath)
 	if err != nil {
 		log.Fatalf("Database error: %v", err)
 	}
 	defer database.Close()
+	cache := db.CachePolicy{TTL: appCfg.SearchCacheTTL, MaxEntries: appCfg.SearchCacheMax}
+	grouper := loadGrouper()
 
 	// 2. Initialize AI
 	// We need this alive as long as the server is running.
-	ctx := context.Background()
-	aiClient, err := ai.NewClient(ctx)
+	// Without it the UI still works; search falls back to cached queries.
+	aiClient, err := newEmbedder(ctx, database)
+	if err != nil {
+		log.Printf("⚠️ AI unavailable, only cached queries will work (EMBED_PROVIDER=local needs no API): %v", err)
+		aiClient = nil
+	} else {
+		defer aiClient.Close()
+	}
+
+	// Searches are split into filters and vibe; with SEARCH_PARSER=llm this
+	// needs the generative model too
+	parser := newQueryParser(ctx, database)
+	defer parser.Close()
+
+	// The generative model answers questions on /ask; the rest works without it
+	gen, err := newGenerator(ctx)
 	if err != nil {
-		log.Fatalf("Failed to initialize AI: %v", err)
+		log.Printf("⚠️ Generative model unavailable, /ask is disabled: %v", err)
+		gen = nil
+	} else {
+		defer gen.Close()
 	}
-	defer aiClient.Close()
 
 	// 3. Pre-build Templates (SEPARATELY to avoid block collisions)
 	// A. Base Template (shared layout + funcs)
@@ -63,20 +92,17 @@ func runServer() {
 		log.Fatalf("Failed to parse base template: %v", err)
 	}
 
-	// B. Home Template (= base + home.html)
-	homeTmpl, _ := base.Clone()
-	homeTmpl, err = homeTmpl.ParseFS(web.GetTemplatesFS(), "templates/home.html")
-	if err != nil {
-		log.Fatalf("Failed to parse home template: %v", err)
-	}
+	// B. Page Templates (= base + page)
+	homeTmpl := mustParsePage(base, "home.html")
+	searchTmpl := mustParsePage(base, "search.html")
+	coffeeTmpl := mustParsePage(base, "coffee.html")
+	stockTmpl := mustParsePage(base, "stock.html")
+	roastsTmpl := mustParsePage(base, "roasts.html")
+	lotsTmpl := mustParsePage(base, "lots.html")
+	manualTmpl := mustParsePage(base, "manual.html")
+	hiddenTmpl := mustParsePage(base, "hidden.html")
+	askTmpl := mustParsePage(base, "ask.html")
 
-	// C. Search Template (= base + search.html)
-	searchTmpl, _ := base.Clone()
-	searchTmpl, err = searchTmpl.ParseFS(web.GetTemplatesFS(), "templates/search.html")
-	if err != nil {
-		log.Fatalf("Failed to parse search template: %v", err)
-	}
-		
 	// 4. Define Routes
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
@@ -84,17 +110,28 @@ func runServer() {
 			return
 		}
 
-		// 1. Fetch data
-		coffees, err := db.GetActiveCoffees(database)
+		// 1. Fetch data (the current catalogue, or as it was on ?as_of=YYYY-MM-DD)
+		page := homePage{AsOf: r.URL.Query().Get("as_of"), ShowHidden: r.URL.Query().Get("hidden") != ""}
+		asOf, err := parseAsOf(page.AsOf)
+		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+		var coffees []models.InventoryItem
+		if asOf.IsZero() {
+			coffees, err = db.GetActiveCoffees(r.Context(), database, page.ShowHidden)
+		} else {
+			coffees, page.Run, err = db.GetCoffeesAsOf(r.Context(), database, asOf, page.ShowHidden)
+		}
 		if err != nil {
 			log.Printf("DB error: %v", err)
 			http.Error(w, "Failed to load coffees", 500)
 			return
 		}
+		page.Coffees = groupInventory(grouper, coffees)
 
 		// 2. Render 'base.html' (which includes home.html)
-		// We pass 'coffees' as the data (.) for the template
-		if err := homeTmpl.ExecuteTemplate(w, "base.html", coffees); err != nil {
+		if err := homeTmpl.ExecuteTemplate(w, "base.html", page); err != nil {
 			log.Printf("Template error: %v", err)
 		}
 	})
@@ -106,19 +143,36 @@ func runServer() {
 			return
 		}
 
-		// Run Search
-		results, err := searcher.Perform(r.Context(), database, aiClient, query)
+		asOfParam := r.URL.Query().Get("as_of")
+		asOf, err := parseAsOf(asOfParam)
 		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+
+		// Interpret the query, unless asked to rank the whole text
+		raw := r.URL.Query().Get("raw") != ""
+		q := searcher.Raw(query)
+		if !raw {
+			q = parser.Parse(r.Context(), query)
+		}
+
+		// Run Search
+		results, err := searcher.Perform(r.Context(), database, aiClient, q, searcher.Options{Cache: cache, AsOf: asOf, IncludeHidden: r.URL.Query().Get("hidden") != "", Variants: grouper})
+		if errors.Is(err, searcher.ErrNoEmbedder) {
+			http.Error(w, "Search is unavailable: no embedding provider is configured and this query isn't cached", http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
 			log.Printf("Search error: %v", err)
 			http.Error(w, "Search failed", 500)
 			return
 		}
 
-		// Filter low-scoring results before rendering
+		// Filter low-scoring results before rendering; a filter-only search has no scores
 		var filtered []searcher.Result
 		for _, res := range results {
 			// 0.2 = 20% match threshold. Adjust as desired.
-			if res.Score >= 0.2 {
+			if q.Vibe == "" || res.Score >= 0.2 {
 				filtered = append(filtered, res)
 			}
 		}
@@ -126,9 +180,15 @@ func runServer() {
 		// Render results using a struct to pass both query and results to template
 		data := struct {
 			Query   string
+			AsOf    string
+			Raw     bool
+			Parsed  searcher.Query
 			Results []searcher.Result
 		}{
 			Query:   query,
+			AsOf:    asOfParam,
+			Raw:     raw,
+			Parsed:  q,
 			Results: filtered,
 		}
 
@@ -137,13 +197,52 @@ func runServer() {
 		}
 	})
 
-	// 4. Start Server
+	registerCoffeeRoutes(database, coffeeTmpl)
+	registerStockRoutes(database, stockTmpl)
+	registerRoastRoutes(database, roastsTmpl)
+	registerLotRoutes(database, lotsTmpl)
+	registerManualRoutes(database, aiClient, embedOptions(), manualTmpl)
+	registerHideRoutes(database, hiddenTmpl)
+	registerAskRoutes(database, gen, aiClient, parser, searcher.Options{Cache: cache, Variants: grouper}, askTmpl)
+
+	// 5. Start Server
 	port := ":8080"
 	log.Printf("🌍 Web UI started at http://localhost%s", port)
 	server := &http.Server{
 		Addr:         port,
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: 2 * time.Minute, // Room for /ask to wait on the generative model
+	}
+	go func() {
+		<-ctx.Done()
+		log.Println("👋 Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// homePage is the inventory table, either current or rebuilt as of a past date.
+type homePage struct {
+	Coffees    []inventoryRow // Variants of one coffee share a row
+	AsOf       string         // YYYY-MM-DD as submitted, empty for the current catalogue
+	Run        db.ScrapeRun
+	ShowHidden bool
+}
+
+// mustParsePage clones the base layout and parses a page template into it.
+// Each page gets its own clone so their "content" blocks don't collide.
+func mustParsePage(base *template.Template, page string) *template.Template {
+	tmpl, err := base.Clone()
+	if err != nil {
+		log.Fatalf("Failed to clone base template: %v", err)
+	}
+	tmpl, err = tmpl.ParseFS(web.GetTemplatesFS(), "templates/"+page)
+	if err != nil {
+		log.Fatalf("Failed to parse %s template: %v", page, err)
 	}
-	log.Fatal(server.ListenAndServe())
+	return tmpl
 }
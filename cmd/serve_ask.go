@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/ask"
+	"mspro-labs/brew-buddy/internal/searcher"
+)
+
+// registerAskRoutes wires up the question page. Without a generative model the
+// page still renders but questions get a 503.
+func registerAskRoutes(database *sql.DB, gen ai.Generator, aiClient ai.Embedder, parser *queryParser, search searcher.Options, askTmpl *template.Template) {
+	http.HandleFunc("GET /ask", func(w http.ResponseWriter, r *http.Request) {
+		page := struct {
+			Question string
+			AsOf     string
+			Answer   *ask.Answer
+		}{Question: r.URL.Query().Get("q"), AsOf: r.URL.Query().Get("as_of")}
+
+		if page.Question != "" {
+			if gen == nil {
+				http.Error(w, "Ask is unavailable: no generative model is configured (see LLM_PROVIDER)", http.StatusServiceUnavailable)
+				return
+			}
+			opts := ask.Options{Search: search}
+			var err error
+			if opts.Search.AsOf, err = parseAsOf(page.AsOf); err != nil {
+				http.Error(w, "Invalid date", http.StatusBadRequest)
+				return
+			}
+			answer, err := ask.Ask(r.Context(), database, gen, aiClient, parser.Parse(r.Context(), page.Question), opts)
+			if errors.Is(err, searcher.ErrNoEmbedder) {
+				http.Error(w, "Ask is unavailable for this question: no embedding provider is configured to rank it", http.StatusServiceUnavailable)
+				return
+			} else if err != nil {
+				log.Printf("Ask error: %v", err)
+				http.Error(w, "Failed to answer", 500)
+				return
+			}
+			page.Answer = &answer
+		}
+
+		if err := askTmpl.ExecuteTemplate(w, "base.html", page); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+}
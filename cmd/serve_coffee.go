@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// coffeePage is the data passed to coffee.html.
+type coffeePage struct {
+	Coffee    models.InventoryItem
+	Notes     []models.Note
+	Roasts    []models.Roast
+	Changes   []models.CoffeeChange
+	Lot       []models.InventoryItem // Other listings of the same lot
+	Links     []models.LotLink       // Pending relisting proposals involving this coffee
+	MaxRating int
+}
+
+// registerCoffeeRoutes wires up the coffee detail view and its note and roast forms.
+func registerCoffeeRoutes(database *sql.DB, coffeeTmpl *template.Template) {
+	http.HandleFunc("GET /coffee/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		coffee, err := db.GetCoffee(r.Context(), database, id)
+		if errors.Is(err, db.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load coffee", 500)
+			return
+		}
+		notes, err := db.ListLotNotes(r.Context(), database, coffee.LotID)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load notes", 500)
+			return
+		}
+
+		roasts, err := db.ListRoasts(r.Context(), database, coffee.URL)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load roasts", 500)
+			return
+		}
+
+		changes, err := db.ListCoffeeChanges(r.Context(), database, coffee.URL, 0)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load changes", 500)
+			return
+		}
+
+		members, err := db.GetLotMembers(r.Context(), database, coffee.LotID)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load lot", 500)
+			return
+		}
+		links, err := db.ListPendingLinksFor(r.Context(), database, coffee.URL)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load links", 500)
+			return
+		}
+
+		data := coffeePage{Coffee: coffee, Notes: notes, Roasts: roasts, Changes: changes, Links: links, MaxRating: models.MaxRating}
+		for _, m := range members {
+			if m.ID != coffee.ID {
+				data.Lot = append(data.Lot, m)
+			}
+		}
+		if err := coffeeTmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+
+	http.HandleFunc("POST /coffee/{id}/notes", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		coffee, err := db.GetCoffee(r.Context(), database, id)
+		if errors.Is(err, db.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load coffee", 500)
+			return
+		}
+
+		note, err := noteFromForm(r, models.Note{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		note.CoffeeURL = coffee.URL
+		if _, err := db.AddNote(r.Context(), database, note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/coffee/%d", coffee.ID), http.StatusSeeOther)
+	})
+
+	http.HandleFunc("POST /coffee/{id}/roasts", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		coffee, err := db.GetCoffee(r.Context(), database, id)
+		if errors.Is(err, db.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load coffee", 500)
+			return
+		}
+
+		roast, err := roastFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		roast.CoffeeURL = coffee.URL
+		if _, err := recordRoast(r.Context(), database, roast, r.FormValue("withdraw") != ""); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/coffee/%d", coffee.ID), http.StatusSeeOther)
+	})
+
+	http.HandleFunc("POST /notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := loadNote(w, r, database)
+		if !ok {
+			return
+		}
+		note, err := noteFromForm(r, existing)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		note.ID = existing.ID
+		if err := db.UpdateNote(r.Context(), database, note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		redirectToCoffeeURL(w, r, database, existing.CoffeeURL)
+	})
+
+	http.HandleFunc("POST /notes/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := loadNote(w, r, database)
+		if !ok {
+			return
+		}
+		if _, err := db.DeleteNote(r.Context(), database, existing.ID); err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to delete note", 500)
+			return
+		}
+		redirectToCoffeeURL(w, r, database, existing.CoffeeURL)
+	})
+}
+
+// loadNote fetches the note named in the request path, writing an error response if it can't.
+func loadNote(w http.ResponseWriter, r *http.Request, database *sql.DB) (models.Note, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return models.Note{}, false
+	}
+	note, err := db.GetNote(r.Context(), database, id)
+	if errors.Is(err, db.ErrNotFound) {
+		http.NotFound(w, r)
+		return models.Note{}, false
+	} else if err != nil {
+		log.Printf("DB error: %v", err)
+		http.Error(w, "Failed to load note", 500)
+		return models.Note{}, false
+	}
+	return note, true
+}
+
+// noteFromForm reads the rating, notes and purchase date fields of a note form
+// over the note being edited, so a blank rating or date keeps its stored value.
+// Pass a zero note for a new one.
+func noteFromForm(r *http.Request, existing models.Note) (models.Note, error) {
+	n := models.Note{Rating: existing.Rating, PurchasedAt: existing.PurchasedAt}
+	if err := r.ParseForm(); err != nil {
+		return n, err
+	}
+	if v := strings.TrimSpace(r.FormValue("rating")); v != "" {
+		rating, err := strconv.Atoi(v)
+		if err != nil {
+			return n, fmt.Errorf("invalid rating %q", v)
+		}
+		n.Rating = rating
+	}
+	n.Notes = strings.TrimSpace(r.FormValue("notes"))
+	if v := strings.TrimSpace(r.FormValue("purchased_at")); v != "" {
+		purchased, err := parseDate(v)
+		if err != nil {
+			return n, fmt.Errorf("invalid purchase date: %w", err)
+		}
+		n.PurchasedAt = purchased
+	}
+	return n, nil
+}
+
+// roastFromForm reads the fields of the roast entry form.
+func roastFromForm(r *http.Request) (models.Roast, error) {
+	var roast models.Roast
+	if err := r.ParseForm(); err != nil {
+		return roast, err
+	}
+	var err error
+	if roast.RoastedAt, err = parseDate(strings.TrimSpace(r.FormValue("roasted_at"))); err != nil {
+		return roast, fmt.Errorf("invalid roast date: %w", err)
+	}
+	if roast.ChargeGrams, err = strconv.ParseFloat(strings.TrimSpace(r.FormValue("charge_grams")), 64); err != nil {
+		return roast, fmt.Errorf("invalid charge weight")
+	}
+	if v := strings.TrimSpace(r.FormValue("yield_grams")); v != "" {
+		if roast.YieldGrams, err = strconv.ParseFloat(v, 64); err != nil {
+			return roast, fmt.Errorf("invalid yield weight")
+		}
+	}
+	if roast.FirstCrackSec, err = models.ParseMinSec(r.FormValue("first_crack")); err != nil {
+		return roast, err
+	}
+	if roast.DropSec, err = models.ParseMinSec(r.FormValue("drop")); err != nil {
+		return roast, err
+	}
+	roast.RoastLevel = strings.TrimSpace(r.FormValue("roast_level"))
+	roast.Notes = strings.TrimSpace(r.FormValue("notes"))
+	return roast, nil
+}
+
+// redirectToCoffeeURL sends the browser back to the detail view of the coffee with the given URL.
+func redirectToCoffeeURL(w http.ResponseWriter, r *http.Request, database *sql.DB, coffeeURL string) {
+	coffee, err := db.FindCoffee(r.Context(), database, coffeeURL)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/coffee/%d", coffee.ID), http.StatusSeeOther)
+}
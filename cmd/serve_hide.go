@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// registerHideRoutes wires up the hide buttons and the ignore list page.
+func registerHideRoutes(database *sql.DB, hiddenTmpl *template.Template) {
+	http.HandleFunc("GET /hidden", func(w http.ResponseWriter, r *http.Request) {
+		entries, err := db.ListIgnoreEntries(r.Context(), database)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load ignore list", 500)
+			return
+		}
+		if err := hiddenTmpl.ExecuteTemplate(w, "base.html", entries); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+
+	// change hides or unhides a coffee, or its whole vendor
+	change := func(apply func(*http.Request, string, string) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			coffee, err := db.GetCoffee(r.Context(), database, id)
+			if errors.Is(err, db.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				log.Printf("DB error: %v", err)
+				http.Error(w, "Failed to load coffee", 500)
+				return
+			}
+			if err := apply(r, coffee.URL, coffee.Vendor()); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			redirectBack(w, r, "/")
+		}
+	}
+	http.HandleFunc("POST /coffee/{id}/hide", change(func(r *http.Request, url, _ string) error {
+		_, err := db.HideCoffee(r.Context(), database, url, r.FormValue("reason"))
+		return err
+	}))
+	http.HandleFunc("POST /coffee/{id}/unhide", change(func(r *http.Request, url, _ string) error {
+		_, err := db.UnhideCoffee(r.Context(), database, url)
+		return err
+	}))
+	http.HandleFunc("POST /coffee/{id}/hide-vendor", change(func(r *http.Request, _, vendor string) error {
+		_, err := db.HideVendor(r.Context(), database, vendor, r.FormValue("reason"))
+		return err
+	}))
+	http.HandleFunc("POST /coffee/{id}/unhide-vendor", change(func(r *http.Request, _, vendor string) error {
+		_, err := db.UnhideVendor(r.Context(), database, vendor)
+		return err
+	}))
+
+	http.HandleFunc("POST /hidden/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := db.DeleteIgnoreEntry(r.Context(), database, id); err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to remove entry", 500)
+			return
+		}
+		http.Redirect(w, r, "/hidden", http.StatusSeeOther)
+	})
+}
+
+// redirectBack returns to the page named in the form's "back" value, or to fallback
+// when it is missing or points off-site.
+func redirectBack(w http.ResponseWriter, r *http.Request, fallback string) {
+	back := r.FormValue("back")
+	if !isLocalPath(back) {
+		back = fallback
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// isLocalPath reports whether a redirect target stays on this site. Browsers
+// treat "//host" and "/\host" as another host, so those are refused too.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, `/\`)
+}
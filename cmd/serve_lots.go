@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// registerLotRoutes wires up the relisting review page and its accept/reject actions.
+func registerLotRoutes(database *sql.DB, lotsTmpl *template.Template) {
+	http.HandleFunc("GET /lots", func(w http.ResponseWriter, r *http.Request) {
+		links, err := db.ListLotLinks(r.Context(), database, models.LinkPending)
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load links", 500)
+			return
+		}
+		if err := lotsTmpl.ExecuteTemplate(w, "base.html", links); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+
+	decide := func(accept bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			if accept {
+				err = db.AcceptLotLink(r.Context(), database, id)
+			} else {
+				err = db.RejectLotLink(r.Context(), database, id)
+			}
+			if errors.Is(err, db.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// Go back to wherever the button was pressed (review page or coffee page)
+			redirectBack(w, r, "/lots")
+		}
+	}
+	http.HandleFunc("POST /lots/{id}/accept", decide(true))
+	http.HandleFunc("POST /lots/{id}/reject", decide(false))
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/embedder"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// registerManualRoutes wires up the form for adding coffees by hand.
+func registerManualRoutes(database *sql.DB, aiClient ai.Embedder, embedOpts embedder.Options, manualTmpl *template.Template) {
+	http.HandleFunc("GET /coffee/new", func(w http.ResponseWriter, r *http.Request) {
+		if err := manualTmpl.ExecuteTemplate(w, "base.html", nil); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+
+	http.HandleFunc("POST /coffee", func(w http.ResponseWriter, r *http.Request) {
+		item, err := manualFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := db.AddManualCoffee(r.Context(), database, r.FormValue("vendor"), item)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Embed in the background so the new coffee shows up in search shortly
+		if aiClient != nil {
+			go func() {
+				if _, err := embedder.Run(context.WithoutCancel(r.Context()), database, aiClient, embedOpts); err != nil {
+					log.Printf("⚠️ Embedding after manual entry failed: %v", err)
+				}
+			}()
+		}
+		http.Redirect(w, r, fmt.Sprintf("/coffee/%d", id), http.StatusSeeOther)
+	})
+}
+
+// manualFromForm reads the fields of the manual entry form.
+func manualFromForm(r *http.Request) (models.CoffeeItem, error) {
+	var item models.CoffeeItem
+	if err := r.ParseForm(); err != nil {
+		return item, err
+	}
+	var err error
+	if v := strings.TrimSpace(r.FormValue("price")); v != "" {
+		if item.Price, err = strconv.ParseFloat(v, 64); err != nil {
+			return item, fmt.Errorf("invalid price")
+		}
+	}
+	if v := strings.TrimSpace(r.FormValue("score")); v != "" {
+		if item.Score, err = strconv.ParseFloat(v, 64); err != nil {
+			return item, fmt.Errorf("invalid score")
+		}
+	}
+	item.Name = strings.TrimSpace(r.FormValue("name"))
+	item.Origin = strings.TrimSpace(r.FormValue("origin"))
+	item.Region = strings.TrimSpace(r.FormValue("region"))
+	item.Processing = strings.TrimSpace(r.FormValue("processing"))
+	item.TastingNotes = strings.TrimSpace(r.FormValue("tasting_notes"))
+	item.Description = strings.TrimSpace(r.FormValue("description"))
+	item.StockStatus = "In Stock"
+	return item, nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// registerRoastRoutes wires up the roast log page.
+func registerRoastRoutes(database *sql.DB, roastsTmpl *template.Template) {
+	http.HandleFunc("GET /roasts", func(w http.ResponseWriter, r *http.Request) {
+		roasts, err := db.ListRoasts(r.Context(), database, "")
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load roasts", 500)
+			return
+		}
+		if err := roastsTmpl.ExecuteTemplate(w, "base.html", roasts); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+}
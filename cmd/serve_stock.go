@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// stockPage is the data passed to stock.html.
+type stockPage struct {
+	Summaries                        []models.StockSummary
+	TotalLbs, TotalBasis, TotalValue float64
+}
+
+// registerStockRoutes wires up the green coffee stock page.
+func registerStockRoutes(database *sql.DB, stockTmpl *template.Template) {
+	http.HandleFunc("GET /stock", func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := db.GetStockSummaries(r.Context(), database, r.URL.Query().Get("all") != "")
+		if err != nil {
+			log.Printf("DB error: %v", err)
+			http.Error(w, "Failed to load stock", 500)
+			return
+		}
+
+		data := stockPage{Summaries: summaries}
+		for _, s := range summaries {
+			data.TotalLbs += s.RemainingLbs()
+			data.TotalBasis += s.CostBasis()
+			data.TotalValue += s.EstimatedValue()
+		}
+		if err := stockTmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+			log.Printf("Template error: %v", err)
+		}
+	})
+}
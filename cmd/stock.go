@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+var (
+	stockLbs    float64
+	stockPrice  float64
+	stockVendor string
+	stockDate   string
+	stockKind   string
+	stockNotes  string
+	stockAll    bool
+)
+
+var stockCmd = &cobra.Command{
+	Use:   "stock",
+	Short: "Track green coffee on hand",
+	Long: `Keeps a ledger of green coffee purchases and withdrawals (roasts, samples, gifts)
+so you always know how much of each coffee is left and what it cost.
+Coffees can be referenced by their database ID or product URL.
+
+Examples:
+  brew-buddy stock buy 42 --lbs 5 --price 38.50 --vendor "Sweet Maria's"
+  brew-buddy stock use 42 --lbs 0.5 --kind roast
+  brew-buddy stock list
+  brew-buddy stock history 42`,
+}
+
+var stockBuyCmd = &cobra.Command{
+	Use:   "buy <coffee>",
+	Short: "Record a purchase",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runStockAdd(cmd.Context(), args[0], models.LedgerPurchase)
+	},
+}
+
+var stockUseCmd = &cobra.Command{
+	Use:   "use <coffee>",
+	Short: "Record a withdrawal (roast, sample or gift)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch stockKind {
+		case models.LedgerRoast, models.LedgerSample, models.LedgerGift:
+		default:
+			log.Fatalf("--kind must be one of roast, sample or gift")
+		}
+		runStockAdd(cmd.Context(), args[0], stockKind)
+	},
+}
+
+var stockListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show remaining weight, cost basis and estimated value per coffee",
+	Run: func(cmd *cobra.Command, args []string) {
+		runStockList(cmd.Context())
+	},
+}
+
+var stockHistoryCmd = &cobra.Command{
+	Use:   "history [coffee]",
+	Short: "Show ledger entries (all, or for one coffee)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := ""
+		if len(args) == 1 {
+			ref = args[0]
+		}
+		runStockHistory(cmd.Context(), ref)
+	},
+}
+
+var stockRmCmd = &cobra.Command{
+	Use:   "rm <entry-id>",
+	Short: "Remove a ledger entry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runStockRm(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{stockBuyCmd, stockUseCmd} {
+		c.Flags().Float64Var(&stockLbs, "lbs", 0, "Weight in pounds")
+		c.Flags().StringVar(&stockDate, "date", "", "Date (YYYY-MM-DD), defaults to today")
+		c.Flags().StringVar(&stockNotes, "notes", "", "Optional note")
+		c.MarkFlagRequired("lbs")
+	}
+	stockBuyCmd.Flags().Float64Var(&stockPrice, "price", 0, "Total price paid, including shipping")
+	stockBuyCmd.Flags().StringVar(&stockVendor, "vendor", "", "Who you bought it from")
+	stockUseCmd.Flags().StringVar(&stockKind, "kind", models.LedgerRoast, "Withdrawal kind: roast, sample or gift")
+	stockListCmd.Flags().BoolVar(&stockAll, "all", false, "Include coffees that are used up")
+
+	stockCmd.AddCommand(stockBuyCmd, stockUseCmd, stockListCmd, stockHistoryCmd, stockRmCmd)
+	rootCmd.AddCommand(stockCmd)
+}
+
+func runStockAdd(ctx context.Context, ref, kind string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffee := mustFindCoffee(ctx, database, ref)
+	occurred, err := parseDate(stockDate)
+	if err != nil {
+		log.Fatalf("Invalid --date: %v", err)
+	}
+
+	entry := models.LedgerEntry{
+		CoffeeURL:  coffee.URL,
+		Kind:       kind,
+		WeightLbs:  stockLbs,
+		OccurredAt: occurred,
+		Notes:      stockNotes,
+	}
+	if kind == models.LedgerPurchase {
+		entry.PricePaid = stockPrice
+		entry.Vendor = stockVendor
+	}
+
+	id, err := db.AddLedgerEntry(ctx, database, entry)
+	if err != nil {
+		log.Fatalf("Failed to record %s: %v", kind, err)
+	}
+	remaining, _ := db.RemainingStock(ctx, database, coffee.URL)
+	fmt.Printf("📦 Recorded %s #%d: %.2f lb of %s (%.2f lb left)\n", kind, id, stockLbs, coffee.Name, remaining)
+}
+
+func runStockList(ctx context.Context) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	summaries, err := db.GetStockSummaries(ctx, database, stockAll)
+	if err != nil {
+		log.Fatalf("Failed to load stock: %v", err)
+	}
+	fmt.Println("📦 Green Coffee Stock")
+	fmt.Println("------------------------------------")
+	if len(summaries) == 0 {
+		fmt.Println("Nothing in stock. Record a purchase with 'brew-buddy stock buy'.")
+		return
+	}
+
+	var totalLbs, totalBasis, totalValue float64
+	unvalued := false
+	for _, s := range summaries {
+		value := "       n/a"
+		if s.PackLbs > 0 {
+			value = fmt.Sprintf("$%9.2f", s.EstimatedValue())
+		} else {
+			unvalued = true
+		}
+		fmt.Printf("%-40s %7.2f lb  basis $%8.2f ($%.2f/lb)  est. value %s\n",
+			truncate(s.CoffeeName, 37), s.RemainingLbs(), s.CostBasis(), s.CostPerLb(), value)
+		totalLbs += s.RemainingLbs()
+		totalBasis += s.CostBasis()
+		totalValue += s.EstimatedValue()
+	}
+	fmt.Println("------------------------------------")
+	fmt.Printf("%-40s %7.2f lb  basis $%8.2f            est. value $%9.2f\n", "Total", totalLbs, totalBasis, totalValue)
+	if unvalued {
+		fmt.Println("n/a: the listing name doesn't give a pack size, so its price can't be turned into a value.")
+	}
+}
+
+func runStockHistory(ctx context.Context, ref string) {
+	database := openDB(ctx)
+	defer database.Close()
+
+	coffeeURL := ""
+	if ref != "" {
+		coffeeURL = mustFindCoffee(ctx, database, ref).URL
+	}
+	entries, err := db.ListLedgerEntries(ctx, database, coffeeURL)
+	if err != nil {
+		log.Fatalf("Failed to load ledger: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No ledger entries found.")
+		return
+	}
+	for _, e := range entries {
+		sign := "-"
+		if e.Kind == models.LedgerPurchase {
+			sign = "+"
+		}
+		fmt.Printf("#%d [%s] %s%.2f lb %-8s %s", e.ID, e.OccurredAt.Local().Format("2006-01-02"), sign, e.WeightLbs, e.Kind, e.CoffeeName)
+		if e.Kind == models.LedgerPurchase {
+			fmt.Printf(" ($%.2f", e.PricePaid)
+			if e.Vendor != "" {
+				fmt.Printf(" from %s", e.Vendor)
+			}
+			fmt.Print(")")
+		}
+		if e.Notes != "" {
+			fmt.Printf(" - %s", e.Notes)
+		}
+		fmt.Println()
+	}
+}
+
+func runStockRm(ctx context.Context, idArg string) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid entry ID %q", idArg)
+	}
+	database := openDB(ctx)
+	defer database.Close()
+
+	affected, err := db.DeleteLedgerEntry(ctx, database, id)
+	if err != nil {
+		log.Fatalf("Failed to remove entry: %v", err)
+	}
+	fmt.Printf("🗑️ Done. Removed %d entry(s).\n", affected)
+}
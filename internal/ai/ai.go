@@ -1,69 +1,112 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"math"
-	"os"
+	"strings"
 
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
+	"mspro-labs/brew-buddy/internal/vector"
 )
 
-// Client wraps the GenAI client.
-type Client struct {
-	genaiClient *genai.Client
-	model       *genai.EmbeddingModel
+// Embedder turns text into a vector. Implementations talk to different providers;
+// New picks one from Options.
+type Embedder interface {
+	// EmbedString generates a vector for the given text and returns it as a byte slice (for DB storage,
+	// normalized; see vector.Decode). It also returns the raw []float32 if needed immediately.
+	EmbedString(ctx context.Context, text string) ([]byte, []float32, error)
+	// Model names the provider and model, e.g. "openai/text-embedding-3-small".
+	// Vectors are only comparable with others from the same model.
+	Model() string
+	// Close releases any connection held by the embedder.
+	Close()
 }
 
-// NewClient creates a connected AI client.
-func NewClient(ctx context.Context) (*Client, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+// BatchEmbedder is an Embedder that can embed several texts in one request.
+// Vectors come back in the order of the texts.
+type BatchEmbedder interface {
+	Embedder
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedBatch embeds texts in one request if e supports it, otherwise one at a time.
+func EmbedBatch(ctx context.Context, e Embedder, texts []string) ([][]float32, error) {
+	if b, ok := e.(BatchEmbedder); ok {
+		return b.EmbedBatch(ctx, texts)
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		_, floats, err := e.EmbedString(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = floats
 	}
+	return vectors, nil
+}
 
-	c, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// embedOne is EmbedString for a BatchEmbedder.
+func embedOne(ctx context.Context, e BatchEmbedder, text string) ([]byte, []float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AI client: %w", err)
+		return nil, nil, err
 	}
+	return encode(vectors[0])
+}
+
+// Providers accepted by New.
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai" // Any server with an OpenAI-compatible /v1/embeddings endpoint
+	ProviderOllama = "ollama"
+	ProviderLocal  = "local" // Built-in hashed TF-IDF, no network needed
+)
 
-	return &Client{
-		genaiClient: c,
-		model:       c.EmbeddingModel("text-embedding-004"),
-	}, nil
+// Options choose and configure an embedding provider. Empty fields take the
+// provider's defaults.
+type Options struct {
+	Provider string // One of the Provider constants, default gemini
+	Model    string
+	BaseURL  string      // API root for the HTTP providers, e.g. "http://ollama:11434"
+	APIKey   string      // Falls back to GEMINI_API_KEY / OPENAI_API_KEY
+	Local    *LocalModel // Trained vectorizer for the local provider
 }
 
-// Close terminates the connection.
-func (c *Client) Close() {
-	if c.genaiClient != nil {
-		c.genaiClient.Close()
+// New creates an embedder for the configured provider.
+func New(ctx context.Context, opts Options) (Embedder, error) {
+	switch strings.ToLower(opts.Provider) {
+	case "", ProviderGemini:
+		return NewGemini(ctx, opts)
+	case ProviderOpenAI:
+		return NewOpenAI(opts), nil
+	case ProviderOllama:
+		return NewOllama(opts), nil
+	case ProviderLocal:
+		if opts.Local == nil {
+			return nil, fmt.Errorf("the local provider needs a trained model")
+		}
+		return &Local{Vectorizer: opts.Local}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (want gemini, openai, ollama or local)", opts.Provider)
 	}
 }
 
-// EmbedString generates a vector for the given text and returns it as a byte slice (for DB storage).
-// It also returns the raw []float32 if needed immediately.
-func (c *Client) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
-	res, err := c.model.EmbedContent(ctx, genai.Text(text))
-	if err != nil {
-		return nil, nil, err
-	}
-	if res.Embedding == nil {
+// encode packs a vector for storage alongside the floats themselves.
+func encode(values []float32) ([]byte, []float32, error) {
+	if len(values) == 0 {
 		return nil, nil, fmt.Errorf("AI returned empty embedding")
 	}
-
-	blob, err := FloatsToBytes(res.Embedding.Values)
+	blob, err := vector.Encode(values, vector.Float32)
 	if err != nil {
 		return nil, nil, err
 	}
-	return blob, res.Embedding.Values, nil
+	return blob, values, nil
 }
 
 // --- Vector Math Helpers ---
 
 // CosineSimilarity calculates the similarity between two vectors (0.0 to 1.0).
+// Stored vectors are already unit length; score those with vector.Vector.Dot.
 func CosineSimilarity(a, b []float32) float32 {
 	if len(a) != len(b) || len(a) == 0 {
 		return 0
@@ -79,23 +122,3 @@ func CosineSimilarity(a, b []float32) float32 {
 	}
 	return dotProduct / (float32(math.Sqrt(float64(magA))) * float32(math.Sqrt(float64(magB))))
 }
-
-// FloatsToBytes converts a []float32 slice to a []byte slice (BLOB) for SQLite.
-func FloatsToBytes(floats []float32) ([]byte, error) {
-	buf := new(bytes.Buffer)
-	err := binary.Write(buf, binary.LittleEndian, floats)
-	if err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// BytesToFloats converts the stored byte slice back to []float32.
-func BytesToFloats(b []byte) ([]float32, error) {
-	if len(b)%4 != 0 {
-		return nil, fmt.Errorf("invalid byte length for float32 slice")
-	}
-	floats := make([]float32, len(b)/4)
-	err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &floats)
-	return floats, err
-}
@@ -0,0 +1,269 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// stubServer answers one path with reply, recording the request it got.
+func stubServer(t *testing.T, path string, status int, reply string, got *map[string]any, auth *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if auth != nil {
+			*auth = r.Header.Get("Authorization")
+		}
+		if got != nil {
+			json.NewDecoder(r.Body).Decode(got)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAI(t *testing.T) {
+	var req map[string]any
+	var auth string
+	srv := stubServer(t, "/v1/embeddings", 200, `{"data":[{"index":0,"embedding":[0.5,-1]}]}`, &req, &auth)
+
+	e, err := New(context.Background(), Options{Provider: "openai", BaseURL: srv.URL + "/v1/", Model: "bge-small", APIKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, floats, err := e.EmbedString(context.Background(), "fruity")
+	if err != nil {
+		t.Fatalf("EmbedString failed: %v", err)
+	}
+	if !reflect.DeepEqual(floats, []float32{0.5, -1}) {
+		t.Errorf("floats = %v", floats)
+	}
+	if back, _ := vector.Decode(blob); !reflect.DeepEqual(back.Floats(), vector.Normalize(floats)) {
+		t.Errorf("blob decodes to %v", back.Floats())
+	}
+	if req["model"] != "bge-small" || !reflect.DeepEqual(req["input"], []any{"fruity"}) {
+		t.Errorf("request = %v", req)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if e.Model() != "openai/bge-small" {
+		t.Errorf("Model() = %q", e.Model())
+	}
+}
+
+func TestOllama(t *testing.T) {
+	var req map[string]any
+	srv := stubServer(t, "/api/embed", 200, `{"model":"nomic-embed-text","embeddings":[[1,2,3]]}`, &req, nil)
+
+	e, err := New(context.Background(), Options{Provider: "ollama", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, floats, err := e.EmbedString(context.Background(), "chocolate")
+	if err != nil {
+		t.Fatalf("EmbedString failed: %v", err)
+	}
+	if !reflect.DeepEqual(floats, []float32{1, 2, 3}) {
+		t.Errorf("floats = %v", floats)
+	}
+	if req["model"] != DefaultOllamaModel || e.Model() != "ollama/"+DefaultOllamaModel {
+		t.Errorf("Expected the default model, got %v (%s)", req["model"], e.Model())
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	testCases := []struct {
+		provider, path, reply, message string
+	}{
+		{"openai", "/embeddings", `{"error":{"message":"Rate limit reached","type":"requests"}}`, "Rate limit reached"},
+		{"ollama", "/api/embed", `{"error":"model \"nope\" not found"}`, `model "nope" not found`},
+	}
+	for _, tc := range testCases {
+		srv := stubServer(t, tc.path, http.StatusTooManyRequests, tc.reply, nil, nil)
+		e, err := New(context.Background(), Options{Provider: tc.provider, BaseURL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = e.EmbedString(context.Background(), "x")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != tc.message {
+			t.Errorf("%s: expected an APIError with %q, got %v", tc.provider, tc.message, err)
+		}
+	}
+
+	// An empty vector is an error, not something to store
+	srv := stubServer(t, "/embeddings", 200, `{"data":[{"index":0,"embedding":[]}]}`, nil, nil)
+	e, _ := New(context.Background(), Options{Provider: "openai", BaseURL: srv.URL})
+	if _, _, err := e.EmbedString(context.Background(), "x"); err == nil {
+		t.Error("Expected an error for an empty embedding")
+	}
+
+	if _, err := New(context.Background(), Options{Provider: "word2vec"}); err == nil {
+		t.Error("Expected an unknown provider to be rejected")
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	var req map[string]any
+	srv := stubServer(t, "/v1/chat/completions", 200, `{"choices":[{"message":{"role":"assistant","content":"{\"ok\":true}"}}]}`, &req, nil)
+	g, err := NewGenerator(context.Background(), Options{Provider: "openai", BaseURL: srv.URL + "/v1", APIKey: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := g.Generate(context.Background(), Prompt{System: "Be terse", User: "Hi", JSON: true})
+	if err != nil || reply != `{"ok":true}` {
+		t.Fatalf("Generate = %q, %v", reply, err)
+	}
+	msgs, _ := req["messages"].([]any)
+	if req["model"] != DefaultOpenAIChatModel || len(msgs) != 2 || !reflect.DeepEqual(req["response_format"], map[string]any{"type": "json_object"}) {
+		t.Errorf("request = %v", req)
+	}
+
+	req = nil
+	srv = stubServer(t, "/api/chat", 200, `{"message":{"role":"assistant","content":"Hello"}}`, &req, nil)
+	g, _ = NewGenerator(context.Background(), Options{Provider: "ollama", BaseURL: srv.URL, Model: "qwen2.5"})
+	if reply, err := g.Generate(context.Background(), Prompt{User: "Hi"}); err != nil || reply != "Hello" {
+		t.Fatalf("Generate = %q, %v", reply, err)
+	}
+	if req["stream"] != false || req["format"] != nil || g.Model() != "ollama/qwen2.5" {
+		t.Errorf("request = %v, model %s", req, g.Model())
+	}
+
+	if _, err := NewGenerator(context.Background(), Options{Provider: "local"}); err == nil {
+		t.Error("Expected the local provider to have no generator")
+	}
+}
+
+func TestLocal(t *testing.T) {
+	corpus := []string{
+		"Coffee Name: Kenya AA\nDescription: Blackcurrant, grapefruit and tomato, juicy and bright.",
+		"Coffee Name: Brazil Cerrado\nDescription: Milk chocolate, roasted nuts and caramel, low acidity.",
+		"Coffee Name: Ethiopia Guji Natural\nDescription: Blueberry jam, strawberry and florals.",
+		"Coffee Name: Sumatra Mandheling\nDescription: Earthy, cedar, dark chocolate and spice.",
+	}
+	model := TrainLocal(corpus, 0)
+	if model.Dims != DefaultLocalDims || model.Documents != len(corpus) {
+		t.Fatalf("TrainLocal = %d dims, %d docs", model.Dims, model.Documents)
+	}
+
+	// The model survives a round trip through the database encoding
+	blob, _ := model.MarshalBinary()
+	var loaded LocalModel
+	if err := loaded.UnmarshalBinary(blob); err != nil || !reflect.DeepEqual(&loaded, model) {
+		t.Fatalf("UnmarshalBinary = %v, model changed: %v", err, !reflect.DeepEqual(&loaded, model))
+	}
+	if err := loaded.UnmarshalBinary(blob[:len(blob)-1]); err == nil {
+		t.Error("Expected a truncated model to be rejected")
+	}
+
+	e, err := New(context.Background(), Options{Provider: "local", Local: &loaded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	best := func(query string) int {
+		_, q, err := e.EmbedString(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bestIdx, bestScore := -1, float32(-1)
+		for i, doc := range corpus {
+			_, v, _ := e.EmbedString(context.Background(), doc)
+			if s := CosineSimilarity(q, v); s > bestScore {
+				bestIdx, bestScore = i, s
+			}
+		}
+		return bestIdx
+	}
+	for query, want := range map[string]int{
+		"chocolatey and nutty":  1,
+		"berries, blueberries":  2,
+		"bright juicy currants": 0,
+		"earthy spicy":          3,
+	} {
+		if got := best(query); got != want {
+			t.Errorf("Best match for %q = %d, want %d", query, got, want)
+		}
+	}
+
+	if _, err := New(context.Background(), Options{Provider: "local"}); err == nil {
+		t.Error("Expected the local provider to need a model")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := range 12 {
+		d := Backoff(attempt, 0)
+		if d <= 0 || d > backoffMax+time.Millisecond {
+			t.Errorf("Backoff(%d) = %s", attempt, d)
+		}
+	}
+	if d := Backoff(0, 10*time.Second); d < 10*time.Second || d > 11*time.Second+time.Millisecond {
+		t.Errorf("Expected the retry hint to be honoured, got %s", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep after cancel = %v", err)
+	}
+}
+
+func TestRetryHint(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	headers := []struct {
+		key, value string
+		want       time.Duration
+	}{
+		{"Retry-After", "3", 3 * time.Second},
+		{"Retry-After", now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{"Retry-After-Ms", "250", 250 * time.Millisecond},
+		{"Retry-After", "soon", 0},
+	}
+	for _, h := range headers {
+		header := http.Header{}
+		header.Set(h.key, h.value)
+		if got := parseRetryAfter(header, now); got != h.want {
+			t.Errorf("%s: %s = %s, want %s", h.key, h.value, got, h.want)
+		}
+	}
+
+	testCases := []struct {
+		err   error
+		retry bool
+	}{
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{&APIError{StatusCode: http.StatusUnauthorized}, false},
+		{context.Canceled, false},
+		{errors.New("malformed"), false},
+	}
+	for _, tc := range testCases {
+		if retry, _ := RetryHint(tc.err); retry != tc.retry {
+			t.Errorf("RetryHint(%v) = %v, want %v", tc.err, retry, tc.retry)
+		}
+	}
+
+	// The hint survives the trip from a stub server
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	_, err := NewOllama(Options{BaseURL: srv.URL}).EmbedBatch(context.Background(), []string{"x", "y"})
+	if retry, after := RetryHint(err); !retry || after != 7*time.Second {
+		t.Errorf("RetryHint = %v, %s; want true, 7s", retry, after)
+	}
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// DefaultGeminiModel is used when no model is configured for Gemini.
+const DefaultGeminiModel = "text-embedding-004"
+
+// Gemini embeds text with Google's Generative AI API.
+type Gemini struct {
+	genaiClient *genai.Client
+	model       *genai.EmbeddingModel
+	modelName   string
+}
+
+// NewGemini creates a connected Gemini client.
+func NewGemini(ctx context.Context, opts Options) (*Gemini, error) {
+	model := opts.Model
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+	c, err := newGenaiClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Gemini{
+		genaiClient: c,
+		model:       c.EmbeddingModel(model),
+		modelName:   model,
+	}, nil
+}
+
+// newGenaiClient connects to the Gemini API with the configured key or GEMINI_API_KEY.
+func newGenaiClient(ctx context.Context, opts Options) (*genai.Client, error) {
+	apiKey := opts.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+	}
+	c, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+	return c, nil
+}
+
+// Close terminates the connection.
+func (c *Gemini) Close() {
+	if c.genaiClient != nil {
+		c.genaiClient.Close()
+	}
+}
+
+// Model implements Embedder.
+func (c *Gemini) Model() string { return ProviderGemini + "/" + c.modelName }
+
+// EmbedString implements Embedder.
+func (c *Gemini) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	res, err := c.model.EmbedContent(ctx, genai.Text(text))
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Embedding == nil {
+		return nil, nil, fmt.Errorf("AI returned empty embedding")
+	}
+	return encode(res.Embedding.Values)
+}
+
+// EmbedBatch implements BatchEmbedder.
+func (c *Gemini) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	batch := c.model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+	res, err := c.model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(res.Embeddings))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, emb := range res.Embeddings {
+		if emb != nil {
+			vectors[i] = emb.Values
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Default generative models, used when no model is configured.
+const (
+	DefaultGeminiChatModel = "gemini-2.0-flash"
+	DefaultOpenAIChatModel = "gpt-4o-mini"
+	DefaultOllamaChatModel = "llama3.2"
+)
+
+// Generator produces text with a generative (chat) model. Implementations talk
+// to different providers; NewGenerator picks one from Options.
+type Generator interface {
+	// Generate answers a single prompt. Replies are as deterministic as the
+	// provider allows (temperature 0).
+	Generate(ctx context.Context, p Prompt) (string, error)
+	// Model names the provider and model, e.g. "gemini/gemini-2.0-flash".
+	Model() string
+	// Close releases any connection held by the generator.
+	Close()
+}
+
+// Prompt is one request to a Generator.
+type Prompt struct {
+	System string // Instructions, sent as the system prompt
+	User   string
+	JSON   bool // Constrain the reply to a single JSON object
+}
+
+// NewGenerator creates a generator for the configured provider. The local
+// provider only embeds, so it has no generator.
+func NewGenerator(ctx context.Context, opts Options) (Generator, error) {
+	switch strings.ToLower(opts.Provider) {
+	case "", ProviderGemini:
+		return NewGeminiGenerator(ctx, opts)
+	case ProviderOpenAI:
+		if opts.APIKey == "" {
+			opts.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return &OpenAIChat{newHTTPProvider(opts, ProviderOpenAI, DefaultOpenAIURL, DefaultOpenAIChatModel)}, nil
+	case ProviderOllama:
+		return &OllamaChat{newHTTPProvider(opts, ProviderOllama, DefaultOllamaURL, DefaultOllamaChatModel)}, nil
+	default:
+		return nil, fmt.Errorf("unknown generative provider %q (want gemini, openai or ollama)", opts.Provider)
+	}
+}
+
+// GeminiGenerator generates text with Google's Generative AI API.
+type GeminiGenerator struct {
+	genaiClient *genai.Client
+	modelName   string
+}
+
+// NewGeminiGenerator creates a connected Gemini generator.
+func NewGeminiGenerator(ctx context.Context, opts Options) (*GeminiGenerator, error) {
+	if opts.Model == "" {
+		opts.Model = DefaultGeminiChatModel
+	}
+	c, err := newGenaiClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiGenerator{genaiClient: c, modelName: opts.Model}, nil
+}
+
+// Model implements Generator.
+func (g *GeminiGenerator) Model() string { return ProviderGemini + "/" + g.modelName }
+
+// Close implements Generator.
+func (g *GeminiGenerator) Close() {
+	if g.genaiClient != nil {
+		g.genaiClient.Close()
+	}
+}
+
+// Generate implements Generator.
+func (g *GeminiGenerator) Generate(ctx context.Context, p Prompt) (string, error) {
+	// The system prompt lives on the model, so each request gets its own
+	model := g.genaiClient.GenerativeModel(g.modelName)
+	model.SetTemperature(0)
+	if p.System != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(p.System))
+	}
+	if p.JSON {
+		model.ResponseMIMEType = "application/json"
+	}
+	res, err := model.GenerateContent(ctx, genai.Text(p.User))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if len(res.Candidates) > 0 && res.Candidates[0].Content != nil {
+		for _, part := range res.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok {
+				b.WriteString(string(text))
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("AI returned an empty reply")
+	}
+	return b.String(), nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func chatMessages(p Prompt) []chatMessage {
+	var msgs []chatMessage
+	if p.System != "" {
+		msgs = append(msgs, chatMessage{Role: "system", Content: p.System})
+	}
+	return append(msgs, chatMessage{Role: "user", Content: p.User})
+}
+
+// OpenAIChat generates text through an OpenAI-compatible /chat/completions endpoint.
+type OpenAIChat struct {
+	httpProvider
+}
+
+type openAIChatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Generate implements Generator.
+func (c *OpenAIChat) Generate(ctx context.Context, p Prompt) (string, error) {
+	req := openAIChatRequest{Model: c.model, Messages: chatMessages(p)}
+	if p.JSON {
+		req.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+	var resp openAIChatResponse
+	if err := c.post(ctx, "/chat/completions", req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("AI returned an empty reply")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// OllamaChat generates text with an Ollama server's /api/chat endpoint.
+type OllamaChat struct {
+	httpProvider
+}
+
+type ollamaChatRequest struct {
+	Model    string             `json:"model"`
+	Messages []chatMessage      `json:"messages"`
+	Stream   bool               `json:"stream"`
+	Format   string             `json:"format,omitempty"`
+	Options  map[string]float64 `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message chatMessage `json:"message"`
+}
+
+// Generate implements Generator.
+func (c *OllamaChat) Generate(ctx context.Context, p Prompt) (string, error) {
+	req := ollamaChatRequest{Model: c.model, Messages: chatMessages(p), Options: map[string]float64{"temperature": 0}}
+	if p.JSON {
+		req.Format = "json"
+	}
+	var resp ollamaChatResponse
+	if err := c.post(ctx, "/api/chat", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("AI returned an empty reply")
+	}
+	return resp.Message.Content, nil
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpTimeout bounds a single request to an HTTP provider.
+const httpTimeout = 60 * time.Second
+
+// APIError is a non-2xx response from an HTTP provider.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // What the server asked us to wait before retrying, 0 if it didn't say
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("AI API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// httpProvider holds what the HTTP providers share.
+type httpProvider struct {
+	client   *http.Client
+	provider string
+	baseURL  string
+	apiKey   string
+	model    string
+}
+
+func newHTTPProvider(opts Options, provider, defaultURL, defaultModel string) httpProvider {
+	e := httpProvider{
+		client:   &http.Client{Timeout: httpTimeout},
+		provider: provider,
+		baseURL:  strings.TrimRight(opts.BaseURL, "/"),
+		apiKey:   opts.APIKey,
+		model:    opts.Model,
+	}
+	if e.baseURL == "" {
+		e.baseURL = defaultURL
+	}
+	if e.model == "" {
+		e.model = defaultModel
+	}
+	return e
+}
+
+// Model implements Embedder.
+func (e httpProvider) Model() string { return e.provider + "/" + e.model }
+
+// Close implements Embedder; HTTP providers hold no connection of their own.
+func (e httpProvider) Close() {}
+
+// post sends body as JSON to baseURL+path and decodes the JSON reply into out.
+func (e httpProvider) post(ctx context.Context, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return &APIError{StatusCode: resp.StatusCode, Message: errorMessage(data), RetryAfter: parseRetryAfter(resp.Header, time.Now())}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode AI response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage pulls the message out of the error bodies OpenAI-style and
+// Ollama servers send, falling back to the raw text.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && len(parsed.Error) > 0 {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message // {"error": {"message": "..."}}
+		}
+		var flat string
+		if json.Unmarshal(parsed.Error, &flat) == nil && flat != "" {
+			return flat // {"error": "..."}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+
+	"mspro-labs/brew-buddy/internal/textutil"
+)
+
+// DefaultLocalDims is the vector size of the built-in embedder.
+const DefaultLocalDims = 512
+
+// Feature weights: whole words carry most of the meaning, character trigrams
+// let "berry" and "berries" or "washed" and "wash" overlap.
+const (
+	weightWord    = 1.0
+	weightTrigram = 0.5
+)
+
+var reToken = regexp.MustCompile(`[a-z0-9]+`)
+
+// LocalModel is a hashed TF-IDF vectorizer over words and character trigrams.
+// It needs no network: the only thing learned is how many documents of our own
+// catalogue contain each hashed feature, which down-weights words every listing
+// uses ("coffee", "notes"). Vectors are only comparable between texts embedded
+// with the same model, so it is trained once and kept (see MarshalBinary).
+type LocalModel struct {
+	Dims      int
+	Documents int
+	DocFreq   []uint32 // Per dimension: how many training documents hit it
+}
+
+// TrainLocal learns document frequencies from a corpus.
+func TrainLocal(corpus []string, dims int) *LocalModel {
+	if dims <= 0 {
+		dims = DefaultLocalDims
+	}
+	m := &LocalModel{Dims: dims, Documents: len(corpus), DocFreq: make([]uint32, dims)}
+	for _, doc := range corpus {
+		seen := make(map[int]bool)
+		features(doc, dims, func(bucket int, _, _ float64) {
+			if !seen[bucket] {
+				seen[bucket] = true
+				m.DocFreq[bucket]++
+			}
+		})
+	}
+	return m
+}
+
+// features calls emit for every hashed feature of text with its bucket, sign and weight.
+func features(text string, dims int, emit func(bucket int, sign, weight float64)) {
+	hash := func(kind byte, s string, weight float64) {
+		h := fnv.New32a()
+		h.Write([]byte{kind})
+		h.Write([]byte(s))
+		sum := h.Sum32()
+		// The top bit picks a sign so collisions tend to cancel rather than pile up
+		sign := 1.0
+		if sum&(1<<31) != 0 {
+			sign = -1
+		}
+		emit(int(sum%uint32(dims)), sign, weight)
+	}
+	for _, word := range reToken.FindAllString(textutil.Fold(text), -1) {
+		hash('w', word, weightWord)
+		padded := "^" + word + "$"
+		for i := 0; i+3 <= len(padded); i++ {
+			hash('c', padded[i:i+3], weightTrigram)
+		}
+	}
+}
+
+// Name identifies the vectors the model makes. Retraining keeps the name, so
+// it has to clear the old vectors itself.
+func (m *LocalModel) Name() string {
+	return fmt.Sprintf("%s/tfidf-%d", ProviderLocal, m.Dims)
+}
+
+// idf is the smoothed inverse document frequency of a bucket.
+func (m *LocalModel) idf(bucket int) float64 {
+	return math.Log(float64(1+m.Documents)/float64(1+m.DocFreq[bucket])) + 1
+}
+
+// Vectorize embeds text as a unit-length vector of sublinear TF times IDF.
+func (m *LocalModel) Vectorize(text string) []float32 {
+	tf := make([]float64, m.Dims)
+	features(text, m.Dims, func(bucket int, sign, weight float64) {
+		tf[bucket] += sign * weight
+	})
+	vec := make([]float32, m.Dims)
+	var norm float64
+	for i, v := range tf {
+		if v == 0 {
+			continue
+		}
+		w := math.Copysign(1+math.Log(math.Abs(v)+1), v) * m.idf(i)
+		vec[i] = float32(w)
+		norm += w * w
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// MarshalBinary encodes the model for storage in the database.
+func (m *LocalModel) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8+4*len(m.DocFreq))
+	binary.LittleEndian.PutUint32(buf[0:], uint32(m.Dims))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(m.Documents))
+	for i, df := range m.DocFreq {
+		binary.LittleEndian.PutUint32(buf[8+4*i:], df)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a model written by MarshalBinary.
+func (m *LocalModel) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("local model too short")
+	}
+	dims := int(binary.LittleEndian.Uint32(data[0:]))
+	if dims <= 0 || len(data) != 8+4*dims {
+		return fmt.Errorf("local model is corrupt (%d dims in %d bytes)", dims, len(data))
+	}
+	m.Dims = dims
+	m.Documents = int(binary.LittleEndian.Uint32(data[4:]))
+	m.DocFreq = make([]uint32, dims)
+	for i := range m.DocFreq {
+		m.DocFreq[i] = binary.LittleEndian.Uint32(data[8+4*i:])
+	}
+	return nil
+}
+
+// Local embeds text with a LocalModel, entirely in-process.
+type Local struct {
+	Vectorizer *LocalModel
+}
+
+// Model implements Embedder.
+func (e *Local) Model() string { return e.Vectorizer.Name() }
+
+// EmbedString implements Embedder.
+func (e *Local) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return encode(e.Vectorizer.Vectorize(text))
+}
+
+// EmbedBatch implements BatchEmbedder.
+func (e *Local) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = e.Vectorizer.Vectorize(text)
+	}
+	return vectors, nil
+}
+
+// Close implements Embedder.
+func (e *Local) Close() {}
@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// Defaults for the Ollama provider.
+const (
+	DefaultOllamaURL   = "http://localhost:11434"
+	DefaultOllamaModel = "nomic-embed-text"
+)
+
+// Ollama embeds text with a local or in-cluster Ollama server.
+type Ollama struct {
+	httpProvider
+}
+
+// NewOllama creates an Ollama embedder. BaseURL is the server root, e.g.
+// "http://ollama:11434".
+func NewOllama(opts Options) *Ollama {
+	return &Ollama{newHTTPProvider(opts, ProviderOllama, DefaultOllamaURL, DefaultOllamaModel)}
+}
+
+type ollamaRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// EmbedString implements Embedder.
+func (e *Ollama) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	return embedOne(ctx, e, text)
+}
+
+// EmbedBatch implements BatchEmbedder.
+func (e *Ollama) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp ollamaResponse
+	if err := e.post(ctx, "/api/embed", ollamaRequest{Model: e.model, Input: texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
+	}
+	return resp.Embeddings, nil
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Defaults for the OpenAI-compatible provider.
+const (
+	DefaultOpenAIURL   = "https://api.openai.com/v1"
+	DefaultOpenAIModel = "text-embedding-3-small"
+)
+
+// OpenAI embeds text through an OpenAI-compatible /embeddings endpoint, which
+// OpenAI itself and most self-hosted inference servers provide.
+type OpenAI struct {
+	httpProvider
+}
+
+// NewOpenAI creates an OpenAI-compatible embedder. BaseURL is the API root
+// including the version, e.g. "http://vllm:8000/v1".
+func NewOpenAI(opts Options) *OpenAI {
+	if opts.APIKey == "" {
+		opts.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &OpenAI{newHTTPProvider(opts, ProviderOpenAI, DefaultOpenAIURL, DefaultOpenAIModel)}
+}
+
+type openAIRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// EmbedString implements Embedder.
+func (e *OpenAI) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	return embedOne(ctx, e, text)
+}
+
+// EmbedBatch implements BatchEmbedder.
+func (e *OpenAI) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp openAIResponse
+	if err := e.post(ctx, "/embeddings", openAIRequest{Model: e.model, Input: texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+	// Entries carry their input's index and aren't guaranteed to be in order
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("embedding response has a bad index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
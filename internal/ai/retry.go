@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+)
+
+// RetryHint reports whether an AI request error is worth retrying (rate limits,
+// overloaded or briefly unreachable servers) and how long the server asked us
+// to wait first, if it said.
+func RetryHint(err error) (retry bool, after time.Duration) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+
+	var httpErr *APIError
+	if errors.As(err, &httpErr) {
+		return retryableStatus(httpErr.StatusCode), httpErr.RetryAfter
+	}
+
+	var googleErr *apierror.APIError
+	if errors.As(err, &googleErr) {
+		if info := googleErr.Details().RetryInfo; info != nil {
+			after = info.GetRetryDelay().AsDuration()
+		}
+		if code := googleErr.HTTPCode(); code > 0 {
+			return retryableStatus(code), after
+		}
+		if st := googleErr.GRPCStatus(); st != nil {
+			switch st.Code() {
+			case codes.ResourceExhausted, codes.Unavailable, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+				return true, after
+			}
+		}
+		return false, after
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr), 0
+}
+
+// Backoff between retries when the server gives no hint.
+const (
+	backoffBase = time.Second
+	backoffMax  = time.Minute
+)
+
+// Backoff is how long to wait before retry number attempt+1 of a request that
+// failed with the given RetryHint: the server's hint if it gave one, otherwise
+// exponential with full jitter. Either way a little randomness keeps parallel
+// callers from retrying in lockstep.
+func Backoff(attempt int, hint time.Duration) time.Duration {
+	if hint > 0 {
+		return hint + rand.N(hint/10+time.Millisecond)
+	}
+	ceiling := min(backoffMax, backoffBase<<min(attempt, 10))
+	return rand.N(ceiling) + time.Millisecond
+}
+
+// Sleep waits for d or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout ||
+		(code >= 500 && code != http.StatusNotImplemented)
+}
+
+// parseRetryAfter reads the delay a server asks for, from Retry-After (seconds
+// or an HTTP date) or the millisecond variant some OpenAI-compatible servers send.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(h.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
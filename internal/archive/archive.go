@@ -0,0 +1,296 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// Supported formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// record is the on-disk shape of one coffee, shared by both formats.
+type record struct {
+	URL            string    `json:"url"`
+	Name           string    `json:"name"`
+	Price          float64   `json:"price"`
+	Score          float64   `json:"score,omitempty"`
+	Origin         string    `json:"origin,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	TastingNotes   string    `json:"tasting_notes,omitempty"`
+	Processing     string    `json:"processing,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	StockStatus    string    `json:"stock_status,omitempty"`
+	IsActive       bool      `json:"is_active"`
+	FirstScrapedAt time.Time `json:"first_scraped_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	Notes          []note    `json:"notes,omitempty"`
+
+	// Extracted attributes, kept apart from the scraped fields above
+	EnrichedProcessing   string `json:"enriched_processing,omitempty"`
+	EnrichedRegion       string `json:"enriched_region,omitempty"`
+	EnrichedTastingNotes string `json:"enriched_tasting_notes,omitempty"`
+	Varietals            string `json:"varietals,omitempty"`
+	Producer             string `json:"producer,omitempty"`
+	AltitudeMin          int    `json:"altitude_min_m,omitempty"`
+	AltitudeMax          int    `json:"altitude_max_m,omitempty"`
+	EnrichHash           string `json:"enrich_hash,omitempty"`
+}
+
+type note struct {
+	Rating      int       `json:"rating,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+	PurchasedAt time.Time `json:"purchased_at"`
+}
+
+// csvHeader lists the CSV columns in order. Notes are stored as a JSON array in the last column.
+var csvHeader = []string{
+	"url", "name", "price", "score", "origin", "region", "tasting_notes", "processing",
+	"description", "stock_status", "is_active", "first_scraped_at", "last_seen_at",
+	"enriched_processing", "enriched_region", "enriched_tasting_notes", "varietals", "producer",
+	"altitude_min_m", "altitude_max_m", "enrich_hash", "notes",
+}
+
+// FormatFromPath guesses the format from a file extension, defaulting to JSON Lines.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	default:
+		return FormatJSONL
+	}
+}
+
+func fromModel(c models.CoffeeRecord) record {
+	r := record{
+		URL: c.URL, Name: c.Name, Price: c.Price, Score: c.Score,
+		Origin: c.Origin, Region: c.Region, TastingNotes: c.TastingNotes, Processing: c.Processing,
+		Description: c.Description, StockStatus: c.StockStatus,
+		IsActive: c.IsActive, FirstScrapedAt: c.FirstScrapedAt.UTC(), LastSeenAt: c.LastSeenAt.UTC(),
+		EnrichedProcessing: c.EnrichedProcessing, EnrichedRegion: c.EnrichedRegion, EnrichedTastingNotes: c.EnrichedTastingNotes,
+		Varietals: c.Varietals, Producer: c.Producer, AltitudeMin: c.AltitudeMin, AltitudeMax: c.AltitudeMax,
+		EnrichHash: c.EnrichHash,
+	}
+	for _, n := range c.Notes {
+		r.Notes = append(r.Notes, note{Rating: n.Rating, Notes: n.Notes, PurchasedAt: n.PurchasedAt.UTC()})
+	}
+	return r
+}
+
+func (r record) toModel() models.CoffeeRecord {
+	c := models.CoffeeRecord{
+		CoffeeItem: models.CoffeeItem{
+			URL: r.URL, Name: r.Name, Price: r.Price, Score: r.Score,
+			Origin: r.Origin, Region: r.Region, TastingNotes: r.TastingNotes, Processing: r.Processing,
+			Description: r.Description, StockStatus: r.StockStatus,
+			Varietals: r.Varietals, AltitudeMin: r.AltitudeMin, AltitudeMax: r.AltitudeMax,
+		},
+		IsActive: r.IsActive, FirstScrapedAt: r.FirstScrapedAt, LastSeenAt: r.LastSeenAt,
+		EnrichedProcessing: r.EnrichedProcessing, EnrichedRegion: r.EnrichedRegion, EnrichedTastingNotes: r.EnrichedTastingNotes,
+		Producer: r.Producer, EnrichHash: r.EnrichHash,
+	}
+	for _, n := range r.Notes {
+		c.Notes = append(c.Notes, models.Note{CoffeeURL: r.URL, Rating: n.Rating, Notes: n.Notes, PurchasedAt: n.PurchasedAt})
+	}
+	return c
+}
+
+// Write encodes coffees to w in the given format.
+func Write(w io.Writer, format string, coffees []models.CoffeeRecord) error {
+	switch format {
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, c := range coffees {
+			if err := enc.Encode(fromModel(c)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, c := range coffees {
+			r := fromModel(c)
+			notes := ""
+			if len(r.Notes) > 0 {
+				b, err := json.Marshal(r.Notes)
+				if err != nil {
+					return err
+				}
+				notes = string(b)
+			}
+			row := []string{
+				r.URL, r.Name, formatFloat(r.Price), formatFloat(r.Score), r.Origin, r.Region,
+				r.TastingNotes, r.Processing, r.Description, r.StockStatus,
+				strconv.FormatBool(r.IsActive), formatTime(r.FirstScrapedAt), formatTime(r.LastSeenAt),
+				r.EnrichedProcessing, r.EnrichedRegion, r.EnrichedTastingNotes, r.Varietals, r.Producer,
+				formatInt(r.AltitudeMin), formatInt(r.AltitudeMax), r.EnrichHash, notes,
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q (use csv or jsonl)", format)
+	}
+}
+
+// Read decodes coffees from r in the given format.
+func Read(r io.Reader, format string) ([]models.CoffeeRecord, error) {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r)
+	case FormatCSV:
+		return readCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown format %q (use csv or jsonl)", format)
+	}
+}
+
+func readJSONL(r io.Reader) ([]models.CoffeeRecord, error) {
+	var coffees []models.CoffeeRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		coffees = append(coffees, rec.toModel())
+	}
+	return coffees, scanner.Err()
+}
+
+func readCSV(r io.Reader) ([]models.CoffeeRecord, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(strings.ToLower(h))] = i
+	}
+	if _, ok := col["url"]; !ok {
+		return nil, fmt.Errorf("CSV is missing the required 'url' column")
+	}
+
+	var coffees []models.CoffeeRecord
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+
+		rec := record{
+			URL: get("url"), Name: get("name"), Origin: get("origin"), Region: get("region"),
+			TastingNotes: get("tasting_notes"), Processing: get("processing"),
+			Description: get("description"), StockStatus: get("stock_status"),
+			EnrichedProcessing: get("enriched_processing"), EnrichedRegion: get("enriched_region"),
+			EnrichedTastingNotes: get("enriched_tasting_notes"), Varietals: get("varietals"),
+			Producer: get("producer"), EnrichHash: get("enrich_hash"),
+		}
+		if rec.Price, err = parseFloat(get("price")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %w", line, err)
+		}
+		if rec.Score, err = parseFloat(get("score")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid score: %w", line, err)
+		}
+		if rec.AltitudeMin, err = parseInt(get("altitude_min_m")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid altitude_min_m: %w", line, err)
+		}
+		if rec.AltitudeMax, err = parseInt(get("altitude_max_m")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid altitude_max_m: %w", line, err)
+		}
+		if v := get("is_active"); v != "" {
+			if rec.IsActive, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid is_active: %w", line, err)
+			}
+		}
+		if rec.FirstScrapedAt, err = parseTime(get("first_scraped_at")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid first_scraped_at: %w", line, err)
+		}
+		if rec.LastSeenAt, err = parseTime(get("last_seen_at")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid last_seen_at: %w", line, err)
+		}
+		if v := get("notes"); v != "" {
+			if err := json.Unmarshal([]byte(v), &rec.Notes); err != nil {
+				return nil, fmt.Errorf("line %d: invalid notes: %w", line, err)
+			}
+		}
+		coffees = append(coffees, rec.toModel())
+	}
+	return coffees, nil
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseFloat(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func formatInt(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestRoundTrip(t *testing.T) {
+	seen := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	coffees := []models.CoffeeRecord{
+		{
+			CoffeeItem: models.CoffeeItem{
+				URL: "https://example.com/guji", Name: "Ethiopia Guji, \"Natural\"", Price: 7.25,
+				Origin: "Ethiopia", Description: "Blueberry,\nstrawberry jam",
+				Varietals: "74110, 74112", AltitudeMin: 1900, AltitudeMax: 2200,
+			},
+			IsActive: true, FirstScrapedAt: seen, LastSeenAt: seen.Add(72 * time.Hour),
+			EnrichedProcessing: "Natural", EnrichedRegion: "Guji", Producer: "Hambela Estate", EnrichHash: "abc123",
+			Notes: []models.Note{{CoffeeURL: "https://example.com/guji", Rating: 5, Notes: "Fruit bomb", PurchasedAt: seen}},
+		},
+		{
+			CoffeeItem:     models.CoffeeItem{URL: "https://example.com/huila", Name: "Colombia Huila", Price: 6.5},
+			FirstScrapedAt: seen, LastSeenAt: seen,
+		},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, coffees); err != nil {
+			t.Fatalf("%s: Write failed: %v", format, err)
+		}
+		got, err := Read(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: Read failed: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, coffees) {
+			t.Errorf("%s: round trip mismatch\n got: %+v\nwant: %+v", format, got, coffees)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package ask answers free-form questions about the catalogue. It retrieves
+// the coffees a search for the question finds, grounds a prompt in their
+// attributes and descriptions, and has a generative model answer citing the
+// coffees by URL.
+package ask
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/searcher"
+)
+
+// DefaultK is how many coffees are retrieved for a zero Options.K.
+const DefaultK = 8
+
+// maxDescription bounds each description in the prompt, in bytes.
+const maxDescription = 600
+
+const systemPrompt = `You are Brew Buddy, a knowledgeable assistant for a home coffee roaster choosing green coffee.
+Answer the question using only the coffees listed below; they are what the catalogue search found.
+- Cite every coffee you mention by its URL in square brackets, e.g. [https://example.com/kenya-aa].
+- Don't mention coffees that aren't listed. If none of them fit, say so plainly.
+- Be concise: a short answer, then a sentence or two of reasoning per coffee.`
+
+// Options control how an answer is found.
+type Options struct {
+	K      int              // Coffees retrieved as context, 0 = DefaultK
+	Search searcher.Options // Catalogue, ignore list and caching for the retrieval
+}
+
+// Source is a coffee given to the model as context.
+type Source struct {
+	models.InventoryItem
+	Cited bool // The answer refers to it
+}
+
+// Answer is the model's reply with the coffees behind it.
+type Answer struct {
+	Question string
+	Query    searcher.Query // How the question was searched
+	Text     string
+	Model    string
+	Sources  []Source
+	Unknown  []string // URLs cited that weren't in the context, which the model made up
+	Unranked bool     // No embedder could rank the question's vibe; the sources only match its filters
+}
+
+// Ask retrieves coffees for a question interpreted as q, and has gen answer
+// it from them. Without an embedder able to rank q's vibe, it falls back to
+// the coffees matching q's filters and marks the answer Unranked; a question
+// with no filters to fall back on fails with searcher.ErrNoEmbedder.
+func Ask(ctx context.Context, database *sql.DB, gen ai.Generator, embedder ai.Embedder, q searcher.Query, opts Options) (Answer, error) {
+	answer := Answer{Question: q.Text, Query: q, Model: gen.Model()}
+	if opts.K <= 0 {
+		opts.K = DefaultK
+	}
+	opts.Search.Limit = opts.K
+
+	// 1. Retrieve
+	results, err := searcher.Perform(ctx, database, embedder, q, opts.Search)
+	if errors.Is(err, searcher.ErrNoEmbedder) {
+		if q.Filter.IsZero() {
+			// Anything retrieved now would just be the cheapest coffees
+			return answer, fmt.Errorf("can't rank coffees for %q: %w", q.Vibe, err)
+		}
+		answer.Query.Vibe, answer.Unranked = "", true
+		results, err = searcher.Perform(ctx, database, nil, answer.Query, opts.Search)
+	}
+	if err != nil {
+		return answer, fmt.Errorf("failed to retrieve coffees: %w", err)
+	}
+	for _, r := range results {
+		item, err := db.FindCoffee(ctx, database, r.Item.URL)
+		if err != nil {
+			return answer, err
+		}
+		// Price and stock as of the search, in case it's looking back
+		item.Price, item.StockStatus = r.Item.Price, r.Item.StockStatus
+		answer.Sources = append(answer.Sources, Source{InventoryItem: item})
+	}
+	if len(answer.Sources) == 0 {
+		answer.Text = "No coffees in the catalogue match that question, so there's nothing to answer from."
+		return answer, nil
+	}
+
+	// 2. Generate
+	text, err := gen.Generate(ctx, ai.Prompt{System: systemPrompt, User: Prompt(q.Text, answer.Sources)})
+	if err != nil {
+		return answer, err
+	}
+	answer.Text = strings.TrimSpace(text)
+
+	// 3. Check the citations
+	known := make(map[string]int)
+	for i, s := range answer.Sources {
+		known[s.URL] = i
+	}
+	for _, url := range citations(answer.Text) {
+		if i, ok := known[url]; ok {
+			answer.Sources[i].Cited = true
+		} else {
+			answer.Unknown = append(answer.Unknown, url)
+		}
+	}
+	return answer, nil
+}
+
+// Prompt is the grounded user prompt: every source's attributes and
+// description, then the question.
+func Prompt(question string, sources []Source) string {
+	var b strings.Builder
+	b.WriteString("Coffees:\n")
+	for i, s := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s\nURL: %s\n", i+1, s.Name, s.URL)
+		origin := s.Origin
+		if s.Region != "" {
+			origin += ", " + s.Region
+		}
+		field(&b, "Origin", origin)
+		field(&b, "Process", s.Processing)
+		field(&b, "Varietals", s.Varietals)
+		if s.AltitudeMax > 0 {
+			fmt.Fprintf(&b, "Altitude: %d-%d m\n", s.AltitudeMin, s.AltitudeMax)
+		}
+		field(&b, "Tasting notes", s.TastingNotes)
+		fmt.Fprintf(&b, "Price: $%.2f (%s)\n", s.Price, s.StockStatus)
+		if s.Score > 0 {
+			fmt.Fprintf(&b, "Cupping score: %.1f\n", s.Score)
+		}
+		if s.Rating > 0 {
+			fmt.Fprintf(&b, "My rating: %d/%d\n", s.Rating, models.MaxRating)
+		}
+		field(&b, "Description", truncate(s.Description, maxDescription))
+	}
+	fmt.Fprintf(&b, "\nQuestion: %s\n", strings.TrimSpace(question))
+	return b.String()
+}
+
+func field(b *strings.Builder, name, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		fmt.Fprintf(b, "%s: %s\n", name, value)
+	}
+}
+
+// truncate shortens s to at most max bytes without splitting a character.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !isRuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "…"
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+// reCitation finds URLs in square brackets, the way the prompt asks for them.
+var reCitation = regexp.MustCompile(`\[((?:https?|manual)://[^\]\s]+)\]`)
+
+// citations returns the URLs an answer cites, in order, without repeats.
+func citations(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, m := range reCitation.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			urls = append(urls, m[1])
+		}
+	}
+	return urls
+}
+
+// Segment is a run of answer text, or a citation of a source.
+type Segment struct {
+	Text   string
+	Source *Source // Set for a citation of a known source
+}
+
+// Segments splits the answer into text and citations for display, so cited
+// coffees can be linked without trusting the model's markup.
+func (a Answer) Segments() []Segment {
+	var segments []Segment
+	rest := a.Text
+	for {
+		loc := reCitation.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		url := rest[loc[2]:loc[3]]
+		var source *Source
+		for i := range a.Sources {
+			if a.Sources[i].URL == url {
+				source = &a.Sources[i]
+			}
+		}
+		if source == nil {
+			segments = append(segments, Segment{Text: rest[:loc[1]]})
+		} else {
+			segments = append(segments, Segment{Text: rest[:loc[0]]}, Segment{Text: source.Name, Source: source})
+		}
+		rest = rest[loc[1]:]
+	}
+	return append(segments, Segment{Text: rest})
+}
@@ -0,0 +1,119 @@
+package ask
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/searcher"
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// fakeEmbedder puts texts mentioning chocolate near each other.
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	v := []float32{1, 0}
+	if strings.Contains(strings.ToLower(text), "chocolate") || strings.Contains(text, "espresso") {
+		v = []float32{0, 1}
+	}
+	blob, err := vector.Encode(v, vector.Float32)
+	return blob, v, err
+}
+
+func (fakeEmbedder) Model() string { return "fake/embed" }
+func (fakeEmbedder) Close()        {}
+
+// fakeGenerator records the prompt and replies with a canned answer.
+type fakeGenerator struct {
+	reply  string
+	prompt ai.Prompt
+}
+
+func (f *fakeGenerator) Generate(ctx context.Context, p ai.Prompt) (string, error) {
+	f.prompt = p
+	return f.reply, nil
+}
+
+func (f *fakeGenerator) Model() string { return "fake/chat" }
+func (f *fakeGenerator) Close()        {}
+
+func TestAsk(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/brazil", Name: "Brazil Cerrado", Origin: "Brazil", Processing: "Natural", Price: 6,
+			StockStatus: "In Stock", Description: "Milk chocolate and hazelnut, heavy body."},
+		{URL: "https://example.com/guji", Name: "Ethiopia Guji", Origin: "Ethiopia", Processing: "Natural", Price: 7.5,
+			StockStatus: "In Stock", Description: "Blueberry jam."},
+		{URL: "https://example.com/kenya", Name: "Kenya AA", Origin: "Kenya", Processing: "Washed", Price: 9,
+			StockStatus: "In Stock", Description: "Blackcurrant and tomato, bright chocolate finish."},
+	}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		blob, _, _ := fakeEmbedder{}.EmbedString(ctx, item.Description)
+		if err := db.UpdateEmbedding(ctx, database, item.URL, "fake/embed", "", blob); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := &fakeGenerator{reply: "Use the Brazil Cerrado [https://example.com/brazil]: chocolate and body for a base. " +
+		"The Guji [https://example.com/guji] is too fruity. Also try [https://example.com/invented]."}
+	question := "which of the current naturals would make a good espresso blend base and why?"
+	q := searcher.Parse(question, nil)
+	answer, err := Ask(ctx, database, gen, fakeEmbedder{}, q, Options{K: 2})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+
+	// Only the naturals are retrieved, the chocolatey one first
+	if len(answer.Sources) != 2 || answer.Sources[0].URL != items[0].URL || answer.Sources[1].URL != items[1].URL {
+		t.Fatalf("Sources = %+v, want the Brazil then the Guji", answer.Sources)
+	}
+	for _, want := range []string{"Brazil Cerrado", "URL: https://example.com/brazil", "Process: Natural", "Milk chocolate", "$6.00 (In Stock)", "Question: " + question} {
+		if !strings.Contains(gen.prompt.User, want) {
+			t.Errorf("Prompt is missing %q:\n%s", want, gen.prompt.User)
+		}
+	}
+	if strings.Contains(gen.prompt.User, "Kenya") {
+		t.Errorf("Prompt includes a washed coffee:\n%s", gen.prompt.User)
+	}
+	if !answer.Sources[0].Cited || !answer.Sources[1].Cited {
+		t.Errorf("Expected both sources cited: %+v", answer.Sources)
+	}
+	if len(answer.Unknown) != 1 || answer.Unknown[0] != "https://example.com/invented" {
+		t.Errorf("Unknown = %v, want the invented URL", answer.Unknown)
+	}
+
+	var linked []string
+	for _, s := range answer.Segments() {
+		if s.Source != nil {
+			linked = append(linked, s.Text)
+		}
+	}
+	if strings.Join(linked, ",") != "Brazil Cerrado,Ethiopia Guji" {
+		t.Errorf("Linked segments = %v", linked)
+	}
+
+	// Without an embedder the filters still pick the context, flagged as unranked
+	answer, err = Ask(ctx, database, gen, nil, searcher.Parse("any washed coffee for a bright espresso?", nil), Options{})
+	if err != nil || len(answer.Sources) != 1 || answer.Sources[0].URL != items[2].URL || !answer.Unranked {
+		t.Errorf("Ask without embedder = %+v (unranked %v), %v; want the Kenya", answer.Sources, answer.Unranked, err)
+	}
+	// ...and with no filters there is nothing to answer from
+	if _, err := Ask(ctx, database, gen, nil, searcher.Parse("something for a bright espresso?", nil), Options{}); !errors.Is(err, searcher.ErrNoEmbedder) {
+		t.Errorf("Ask without embedder or filters = %v, want ErrNoEmbedder", err)
+	}
+}
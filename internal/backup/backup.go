@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// filePrefix is used for generated backup names so Rotate only touches our own files.
+const filePrefix = "brew-buddy-"
+
+// Options control how a backup is written.
+type Options struct {
+	Gzip bool
+	Keep int // Backups to keep when writing into a directory, 0 keeps everything
+}
+
+// Create writes a consistent backup of the live database and returns the file written.
+// If dest is a directory (or ends in a path separator), a timestamped file is created
+// inside it and older backups beyond opts.Keep are removed.
+func Create(ctx context.Context, database *sql.DB, dest string, opts Options) (string, error) {
+	isDir := strings.HasSuffix(dest, string(os.PathSeparator))
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		isDir = true
+	}
+
+	target := dest
+	if isDir {
+		if err := os.MkdirAll(dest, 0o755); err != nil {
+			return "", err
+		}
+		target = filepath.Join(dest, filePrefix+time.Now().Format("20060102-150405")+".db")
+	}
+	if opts.Gzip && !strings.HasSuffix(target, ".gz") {
+		target += ".gz"
+	}
+
+	// VACUUM INTO refuses to overwrite, so write next to the target and rename into place.
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".tmp")
+	os.Remove(tmp)
+	if err := db.VacuumInto(ctx, database, tmp); err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	if opts.Gzip {
+		if err := gzipFile(tmp, tmp+".gz"); err != nil {
+			os.Remove(tmp + ".gz")
+			return "", err
+		}
+		os.Remove(tmp)
+		tmp += ".gz"
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return "", fmt.Errorf("failed to move backup into place: %w", err)
+	}
+
+	if isDir && opts.Keep > 0 {
+		if _, err := Rotate(dest, opts.Keep); err != nil {
+			return target, fmt.Errorf("backup written but rotation failed: %w", err)
+		}
+	}
+	return target, nil
+}
+
+// Rotate deletes all but the newest keep backups in dir and returns the removed paths.
+// Only files created by Create (brew-buddy-<timestamp>.db[.gz]) are considered.
+func Rotate(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, filePrefix) &&
+			(strings.HasSuffix(name, ".db") || strings.HasSuffix(name, ".db.gz")) {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= keep {
+		return nil, nil
+	}
+
+	// Timestamps in the names sort chronologically, newest last
+	sort.Strings(backups)
+	var removed []string
+	for _, name := range backups[:len(backups)-keep] {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// Restore validates the backup at src (integrity and schema version) and then
+// replaces the live database with it. Gzipped backups are decompressed first.
+// It returns the schema version found in the backup.
+func Restore(ctx context.Context, database *sql.DB, src string) (int, error) {
+	path := src
+	if strings.HasSuffix(src, ".gz") {
+		tmp, err := os.CreateTemp("", "brew-buddy-restore-*.db")
+		if err != nil {
+			return 0, err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		if err := gunzipFile(src, tmp.Name()); err != nil {
+			return 0, err
+		}
+		path = tmp.Name()
+	}
+
+	version, err := db.CheckFile(ctx, path)
+	if err != nil {
+		return version, err
+	}
+	return version, db.RestoreFrom(ctx, database, path)
+}
+
+func gzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func gunzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	defer zr.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, zr); err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestBackupRotateAndRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	database, err := db.Connect(ctx, filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	item := models.CoffeeItem{URL: "https://example.com/a", Name: "Original"}
+	if _, err := db.SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	backups := filepath.Join(dir, "backups") + string(os.PathSeparator)
+	path, err := Create(ctx, database, backups, Options{Gzip: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Change the live data, then restore the backup over it
+	item.Name = "Changed"
+	if _, err := db.SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if _, err := Restore(ctx, database, path); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	coffee, err := db.FindCoffee(ctx, database, item.URL)
+	if err != nil || coffee.Name != "Original" {
+		t.Errorf("After restore got %q (%v), want %q", coffee.Name, err, "Original")
+	}
+
+	// Rotation only keeps the newest files with our prefix
+	for _, name := range []string{"brew-buddy-20200101-000000.db", "brew-buddy-20210101-000000.db.gz", "unrelated.db"} {
+		os.WriteFile(filepath.Join(backups, name), []byte("x"), 0o644)
+	}
+	removed, err := Rotate(backups, 1)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Rotate removed %v (%v), want 2 files", removed, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Newest backup was rotated away: %v", err)
+	}
+
+	// Garbage files are rejected before touching the live database
+	bogus := filepath.Join(dir, "bogus.db")
+	os.WriteFile(bogus, []byte("not a database"), 0o644)
+	if _, err := Restore(ctx, database, bogus); err == nil {
+		t.Errorf("Restore accepted a corrupt file")
+	}
+}
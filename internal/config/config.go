@@ -3,14 +3,51 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"mspro-labs/brew-buddy/internal/vector"
 )
 
 // AppConfig holds infrastructure config from standard env vars
 type AppConfig struct {
 	DBPath     string
 	ConfigPath string // Path to the YAML config file
+
+	// Embedding provider (gemini, openai or ollama) and its settings; empty means the provider's default
+	EmbedProvider string
+	EmbedModel    string
+	EmbedURL      string
+	EmbedAPIKey   string
+
+	// How 'embed' sends requests; 0 takes the embedder's defaults (RPM/TPM: unlimited)
+	EmbedBatchSize int
+	EmbedWorkers   int
+	EmbedRPM       int // Requests per minute
+	EmbedTPM       int // Estimated tokens per minute
+
+	VectorEncoding vector.Encoding // How vectors are stored: float32 (default) or int8, a quarter the size
+
+	// Generative model for 'enrich' (gemini, openai or ollama); empty means the provider's default
+	LLMProvider string
+	LLMModel    string
+	LLMURL      string
+	LLMAPIKey   string
+	LLMRPM      int // Requests per minute, 0 = unlimited
+
+	SearchParser   string        // How queries are split into filters and vibe: "rules" (default) or "llm"
+	SearchCacheTTL time.Duration // How long cached query embeddings stay valid, 0 = forever
+	SearchCacheMax int           // Max cached queries before LRU eviction, 0 = unlimited
+
+	// Retention for 'db prune', each measured back from now. 0 keeps data forever.
+	RetainInactive        time.Duration // Delisted coffees with no notes, stock or roasts
+	RetainEmbeddings      time.Duration // Embeddings of delisted coffees
+	RetainSnapshots       time.Duration // Per-scrape listing snapshots (as-of history)
+	RetainChanges         time.Duration // Field-level change log entries
+	RetainInterpretations time.Duration // Cached query interpretations, by last use
+	RetainEnrichments     time.Duration // Cached attribute extractions no coffee's current text uses
 }
 
 // SiteConfig holds all target-site specific settings (from YAML)
@@ -18,6 +55,8 @@ type SiteConfig struct {
 	CategoryURL        string    `yaml:"category_url"`
 	Selectors          Selectors `yaml:"selectors"`
 	DisallowedKeywords []string  `yaml:"disallowed_keywords"`
+	VariantPatterns    []string  `yaml:"variant_patterns"` // Extra size/sample markers (regexps) that split one coffee into listings
+	EmbedTemplate      string    `yaml:"embed_template"`   // text/template over a coffee building the text that gets embedded
 }
 
 type Selectors struct {
@@ -47,10 +86,104 @@ func GetAppConfig() (AppConfig, error) {
 		configPath = "config.yaml"
 	}
 
-	return AppConfig{
-		DBPath:     dbPath,
-		ConfigPath: configPath,
-	}, nil
+	cfg := AppConfig{
+		DBPath:        dbPath,
+		ConfigPath:    configPath,
+		SearchParser:  "rules",
+		EmbedProvider: os.Getenv("EMBED_PROVIDER"),
+		EmbedModel:    os.Getenv("EMBED_MODEL"),
+		EmbedURL:      os.Getenv("EMBED_URL"),
+		EmbedAPIKey:   os.Getenv("EMBED_API_KEY"),
+		EmbedRPM:      60, // Safe for Gemini's free tier
+		LLMProvider:   os.Getenv("LLM_PROVIDER"),
+		LLMModel:      os.Getenv("LLM_MODEL"),
+		LLMURL:        os.Getenv("LLM_URL"),
+		LLMAPIKey:     os.Getenv("LLM_API_KEY"),
+		LLMRPM:        15, // Gemini's free tier for flash models
+	}
+
+	encoding, err := vector.ParseEncoding(os.Getenv("VECTOR_ENCODING"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid VECTOR_ENCODING: %w", err)
+	}
+	cfg.VectorEncoding = encoding
+	if v := os.Getenv("SEARCH_PARSER"); v != "" {
+		switch v = strings.ToLower(v); v {
+		case "rules", "llm":
+			cfg.SearchParser = v
+		default:
+			return cfg, fmt.Errorf("invalid SEARCH_PARSER %q (want rules or llm)", v)
+		}
+	}
+	if v := os.Getenv("SEARCH_CACHE_TTL"); v != "" {
+		ttl, err := parseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SEARCH_CACHE_TTL %q: %w", v, err)
+		}
+		cfg.SearchCacheTTL = ttl
+	}
+	if v := os.Getenv("SEARCH_CACHE_MAX"); v != "" {
+		max, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SEARCH_CACHE_MAX %q: %w", v, err)
+		}
+		cfg.SearchCacheMax = max
+	}
+
+	limits := []struct {
+		env string
+		dst *int
+	}{
+		{"EMBED_BATCH_SIZE", &cfg.EmbedBatchSize},
+		{"EMBED_WORKERS", &cfg.EmbedWorkers},
+		{"EMBED_RPM", &cfg.EmbedRPM},
+		{"EMBED_TPM", &cfg.EmbedTPM},
+		{"LLM_RPM", &cfg.LLMRPM},
+	}
+	for _, l := range limits {
+		if v := os.Getenv(l.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", l.env, v)
+			}
+			*l.dst = n
+		}
+	}
+
+	retention := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"RETAIN_INACTIVE", &cfg.RetainInactive},
+		{"RETAIN_EMBEDDINGS", &cfg.RetainEmbeddings},
+		{"RETAIN_SNAPSHOTS", &cfg.RetainSnapshots},
+		{"RETAIN_CHANGES", &cfg.RetainChanges},
+		{"RETAIN_INTERPRETATIONS", &cfg.RetainInterpretations},
+		{"RETAIN_ENRICHMENTS", &cfg.RetainEnrichments},
+	}
+	for _, r := range retention {
+		if v := os.Getenv(r.env); v != "" {
+			d, err := parseDuration(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %w", r.env, v, err)
+			}
+			*r.dst = d
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseDuration is time.ParseDuration plus a "d" suffix for whole days, e.g. "90d".
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
 }
 
 // LoadSiteConfig reads the YAML file to configure the scraper.
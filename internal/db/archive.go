@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// ExportFilter narrows down which coffees ExportCoffees returns.
+type ExportFilter struct {
+	ActiveOnly bool
+	Origin     string    // Case-insensitive substring match
+	Since      time.Time // Seen on or after this time
+	Until      time.Time // First scraped on or before this time
+}
+
+// ExportCoffees returns the full record of every coffee matching the filter,
+// including its scrape history timestamps and personal notes.
+func ExportCoffees(ctx context.Context, db *sql.DB, f ExportFilter) ([]models.CoffeeRecord, error) {
+	var where []string
+	var args []any
+	if f.ActiveOnly {
+		where = append(where, "c.is_active = 1")
+	}
+	if f.Origin != "" {
+		where = append(where, "LOWER(c.origin) LIKE ?")
+		args = append(args, "%"+strings.ToLower(f.Origin)+"%")
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "c.last_seen_at >= ?")
+		args = append(args, sqliteTime(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "c.first_scraped_at <= ?")
+		args = append(args, sqliteTime(f.Until))
+	}
+
+	// The listing fields as scraped, not inventoryColumns: those fill gaps with
+	// extracted attributes, which would come back as the vendor's on import
+	query := `SELECT c.url, COALESCE(c.name, ''), COALESCE(c.price, 0), COALESCE(c.score, 0),
+		COALESCE(c.origin, ''), COALESCE(c.region, ''), COALESCE(c.tasting_notes, ''), COALESCE(c.processing, ''),
+		COALESCE(c.description, ''), COALESCE(c.stock_status, ''), c.is_active, c.first_scraped_at, c.last_seen_at,
+		COALESCE(c.enriched_processing, ''), COALESCE(c.enriched_region, ''), COALESCE(c.enriched_tasting_notes, ''),
+		COALESCE(c.varietals, ''), COALESCE(c.producer, ''), COALESCE(c.altitude_min_m, 0), COALESCE(c.altitude_max_m, 0),
+		COALESCE(c.enrich_hash, '')
+		FROM coffee c`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY c.id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.CoffeeRecord
+	for rows.Next() {
+		var r models.CoffeeRecord
+		if err := rows.Scan(&r.URL, &r.Name, &r.Price, &r.Score,
+			&r.Origin, &r.Region, &r.TastingNotes, &r.Processing,
+			&r.Description, &r.StockStatus, &r.IsActive, &r.FirstScrapedAt, &r.LastSeenAt,
+			&r.EnrichedProcessing, &r.EnrichedRegion, &r.EnrichedTastingNotes,
+			&r.Varietals, &r.Producer, &r.AltitudeMin, &r.AltitudeMax, &r.EnrichHash); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for idx := range records {
+		notes, err := ListNotes(ctx, db, records[idx].URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load notes for %s: %w", records[idx].URL, err)
+		}
+		records[idx].Notes = notes
+	}
+	return records, nil
+}
+
+// ImportCoffees upserts archived coffee records by URL in a single transaction,
+// recording any listing fields it overwrites in coffee_changes.
+// New coffees keep the imported state as-is. For coffees we already know, the
+// listing fields are overwritten, the history window is widened to cover both
+// copies, and the local active flag is kept since our own scrapes are authoritative.
+// Extracted attributes are only taken from records that have an EnrichHash.
+// Notes are added unless one with the same text, rating and purchase date (to the
+// second) already exists, so importing the same file twice adds nothing.
+func ImportCoffees(ctx context.Context, db *sql.DB, records []models.CoffeeRecord) (inserted, updated int, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	changes, err := newChangeTracker(ctx, tx, time.Now())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer changes.Close()
+
+	for _, r := range records {
+		if r.URL == "" {
+			return 0, 0, fmt.Errorf("record %q has no URL", r.Name)
+		}
+		first, last := r.FirstScrapedAt, r.LastSeenAt
+		if first.IsZero() {
+			first = time.Now()
+		}
+		if last.IsZero() {
+			last = first
+		}
+
+		var exists int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM coffee WHERE url = ?`, r.URL).Scan(&exists); err != nil {
+			return 0, 0, err
+		}
+		if _, err := changes.record(ctx, r.CoffeeItem); err != nil {
+			return 0, 0, fmt.Errorf("failed to record changes for %s: %w", r.URL, err)
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO coffee (
+			  url, name, price, score, origin, region, tasting_notes, processing, description, stock_status,
+			  first_scraped_at, last_scraped_at, last_seen_at, is_active
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(url) DO UPDATE SET
+			  name = excluded.name,
+			  price = excluded.price,
+			  score = excluded.score,
+			  origin = excluded.origin,
+			  region = excluded.region,
+			  tasting_notes = excluded.tasting_notes,
+			  processing = excluded.processing,
+			  description = excluded.description,
+			  stock_status = excluded.stock_status,
+			  first_scraped_at = MIN(first_scraped_at, excluded.first_scraped_at),
+			  last_seen_at = MAX(last_seen_at, excluded.last_seen_at)`,
+			r.URL,
+			r.Name,
+			r.Price,
+			sql.NullFloat64{Float64: r.Score, Valid: r.Score > 0},
+			sql.NullString{String: r.Origin, Valid: r.Origin != ""},
+			sql.NullString{String: r.Region, Valid: r.Region != ""},
+			sql.NullString{String: r.TastingNotes, Valid: r.TastingNotes != ""},
+			sql.NullString{String: r.Processing, Valid: r.Processing != ""},
+			sql.NullString{String: r.Description, Valid: r.Description != ""},
+			sql.NullString{String: r.StockStatus, Valid: r.StockStatus != ""},
+			sqliteTime(first), sqliteTime(last), sqliteTime(last),
+			r.IsActive,
+		)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to import %s: %w", r.URL, err)
+		}
+		if r.EnrichHash != "" {
+			e := Enrichment{
+				Processing: r.EnrichedProcessing, Region: r.EnrichedRegion, TastingNotes: r.EnrichedTastingNotes,
+				Varietals: r.Varietals, Producer: r.Producer, AltitudeMin: r.AltitudeMin, AltitudeMax: r.AltitudeMax,
+			}
+			if err := applyEnrichment(ctx, tx, r.URL, r.EnrichHash, e); err != nil {
+				return 0, 0, fmt.Errorf("failed to import attributes of %s: %w", r.URL, err)
+			}
+		}
+		if exists > 0 {
+			updated++
+		} else {
+			inserted++
+		}
+
+		for _, n := range r.Notes {
+			if err := validateRating(n.Rating); err != nil {
+				return 0, 0, fmt.Errorf("note on %s: %w", r.URL, err)
+			}
+			if n.PurchasedAt.IsZero() {
+				n.PurchasedAt = time.Now()
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO my_notes (coffee_url, rating, notes, purchased_at)
+				SELECT ?, ?, ?, ?
+				WHERE NOT EXISTS (
+				  SELECT 1 FROM my_notes
+				  WHERE coffee_url = ? AND COALESCE(notes, '') = ? AND COALESCE(rating, 0) = ?
+				    AND strftime('%s', purchased_at) = strftime('%s', ?)
+				)`,
+				r.URL, sql.NullInt64{Int64: int64(n.Rating), Valid: n.Rating > 0}, n.Notes, n.PurchasedAt.UTC(),
+				r.URL, n.Notes, n.Rating, n.PurchasedAt.UTC(),
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to import note for %s: %w", r.URL, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return inserted, updated, nil
+}
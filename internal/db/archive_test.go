@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestImportCoffees(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	kenya := models.CoffeeItem{URL: "https://example.com/kenya", Name: "Kenya AA", Price: 9}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{kenya}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	bought := time.Date(2025, 2, 1, 10, 30, 0, 0, time.UTC)
+	if _, err := AddNote(ctx, database, models.Note{CoffeeURL: kenya.URL, Rating: 4, Notes: "Great as espresso", PurchasedAt: bought}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The archive has an older copy of the Kenya, delisted there, with the same
+	// note and a repeat purchase of it, plus a coffee we've never seen
+	renamed := kenya
+	renamed.Name = "Kenya AA Gatomboya"
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	records := []models.CoffeeRecord{
+		{CoffeeItem: renamed, FirstScrapedAt: since, LastSeenAt: since.AddDate(0, 1, 0), Notes: []models.Note{
+			{Rating: 4, Notes: "Great as espresso", PurchasedAt: bought.In(time.FixedZone("CET", 3600))},
+			{Rating: 4, Notes: "Great as espresso", PurchasedAt: bought.AddDate(0, 2, 0)},
+		}},
+		{CoffeeItem: models.CoffeeItem{URL: "https://example.com/yemen", Name: "Yemen Haraz", Price: 20}, FirstScrapedAt: since,
+			Notes: []models.Note{{Rating: 5, Notes: "Wild", PurchasedAt: since}}},
+	}
+
+	for run, want := range []struct{ inserted, updated int }{{1, 1}, {0, 2}} {
+		inserted, updated, err := ImportCoffees(ctx, database, records)
+		if err != nil {
+			t.Fatalf("Import #%d failed: %v", run+1, err)
+		}
+		if inserted != want.inserted || updated != want.updated {
+			t.Errorf("Import #%d = %d inserted, %d updated; want %d and %d", run+1, inserted, updated, want.inserted, want.updated)
+		}
+
+		// Re-importing the same file adds no notes
+		for url, want := range map[string]int{kenya.URL: 2, "https://example.com/yemen": 1} {
+			if notes, err := ListNotes(ctx, database, url); err != nil || len(notes) != want {
+				t.Errorf("Import #%d: %s has %d notes (%v), want %d", run+1, url, len(notes), err, want)
+			}
+		}
+	}
+
+	got, err := FindCoffee(ctx, database, kenya.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Listing fields come from the archive; the local active flag and notes stay
+	if got.Name != renamed.Name || !got.IsActive {
+		t.Errorf("Imported Kenya = %q, active %v; want the archive's name, still active", got.Name, got.IsActive)
+	}
+	exported, err := ExportCoffees(ctx, database, ExportFilter{})
+	if err != nil || len(exported) != 2 {
+		t.Fatalf("ExportCoffees = %d records, %v", len(exported), err)
+	}
+	if !exported[0].FirstScrapedAt.Equal(since) {
+		t.Errorf("First scraped = %s, want the history widened to %s", exported[0].FirstScrapedAt, since)
+	}
+	if exported[1].IsActive {
+		t.Error("A coffee new to us should keep the archive's inactive state")
+	}
+	var changes int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM coffee_changes WHERE coffee_url = ? AND field = 'name'`, kenya.URL).Scan(&changes); err != nil || changes != 1 {
+		t.Errorf("Recorded %d name changes (%v), want 1", changes, err)
+	}
+
+	if _, _, err := ImportCoffees(ctx, database, []models.CoffeeRecord{{CoffeeItem: models.CoffeeItem{Name: "No URL"}}}); err == nil {
+		t.Error("Expected a record without a URL to be rejected")
+	}
+}
+
+func TestExportKeepsExtractedAttributesApart(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	kenya := models.CoffeeItem{URL: "https://example.com/kenya", Name: "Kenya Nyeri", Price: 9, Description: "SL28, washed"}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{kenya}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if err := ApplyEnrichment(ctx, database, kenya.URL, "hash", Enrichment{Region: "Nyeri", Processing: "Washed", Varietals: "SL28"}); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := ExportCoffees(ctx, database, ExportFilter{})
+	if err != nil || len(exported) != 1 {
+		t.Fatalf("ExportCoffees = %d records, %v", len(exported), err)
+	}
+	r := exported[0]
+	if r.Region != "" || r.Processing != "" || r.EnrichedRegion != "Nyeri" || r.EnrichedProcessing != "Washed" || r.EnrichHash != "hash" {
+		t.Errorf("Exported %+v; want the vendor's empty fields and the extracted ones apart", r)
+	}
+
+	// Importing our own export changes nothing the vendor listed
+	if _, _, err := ImportCoffees(ctx, database, exported); err != nil {
+		t.Fatalf("ImportCoffees failed: %v", err)
+	}
+	var changes int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM coffee_changes`).Scan(&changes); err != nil || changes != 0 {
+		t.Errorf("Round trip recorded %d changes (%v), want none", changes, err)
+	}
+
+	// A fresh database gets the attributes as extracted, current for their text
+	other := openTestDB(t)
+	if _, _, err := ImportCoffees(ctx, other, exported); err != nil {
+		t.Fatalf("ImportCoffees into a new database failed: %v", err)
+	}
+	got, err := FindCoffee(ctx, other, kenya.URL)
+	if err != nil || got.Region != "Nyeri" || got.Varietals != "SL28" {
+		t.Errorf("Imported coffee = %+v, %v; want the extracted region and varietals shown", got, err)
+	}
+	if candidates, err := GetEnrichCandidates(ctx, other); err != nil || len(candidates) != 1 || candidates[0].Hash != "hash" {
+		t.Errorf("GetEnrichCandidates = %+v, %v; want the imported hash", candidates, err)
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// VacuumInto writes a consistent, compacted copy of the live database to path.
+// It is safe to run while other connections (e.g. 'serve') are reading and writing.
+func VacuumInto(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to write backup to %s: %w", path, err)
+	}
+	return nil
+}
+
+// CheckFile opens the SQLite file at path read-only, runs an integrity check and
+// returns its schema version. It fails if the file is corrupt, isn't a Brew Buddy
+// database, or was written by a newer version of the schema.
+func CheckFile(ctx context.Context, path string) (int, error) {
+	candidate, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer candidate.Close()
+
+	var result string
+	if err := candidate.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("integrity check failed: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	var tables int
+	if err := candidate.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'coffee'`).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, fmt.Errorf("%s is not a Brew Buddy database (no coffee table)", path)
+	}
+
+	var version int
+	if err := candidate.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return version, fmt.Errorf("backup has schema version %d, newer than this build supports (%d)", version, SchemaVersion)
+	}
+	return version, nil
+}
+
+// RestoreFrom replaces the contents of the live database with the SQLite file at path,
+// using SQLite's online backup API so open connections see a consistent switch.
+// The file should have been validated with CheckFile first. Older schemas are
+// migrated forward afterwards.
+func RestoreFrom(ctx context.Context, db *sql.DB, path string) error {
+	src, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	destConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	err = destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			dest, ok1 := destRaw.(*sqlite3.SQLiteConn)
+			source, ok2 := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("unexpected driver connection type")
+			}
+			b, err := dest.Backup("main", source, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore from %s: %w", path, err)
+	}
+
+	return createSchema(ctx, db)
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// trackedFields are the listing fields whose changes are recorded in coffee_changes.
+// Values are compared as text, the same way they are stored in the audit log.
+var trackedFields = []struct {
+	column string
+	value  func(models.CoffeeItem) string
+}{
+	{"name", func(c models.CoffeeItem) string { return c.Name }},
+	{"price", func(c models.CoffeeItem) string { return formatNumber(c.Price) }},
+	{"score", func(c models.CoffeeItem) string { return formatNumber(c.Score) }},
+	{"origin", func(c models.CoffeeItem) string { return c.Origin }},
+	{"region", func(c models.CoffeeItem) string { return c.Region }},
+	{"tasting_notes", func(c models.CoffeeItem) string { return c.TastingNotes }},
+	{"processing", func(c models.CoffeeItem) string { return c.Processing }},
+	{"description", func(c models.CoffeeItem) string { return c.Description }},
+	{"stock_status", func(c models.CoffeeItem) string { return c.StockStatus }},
+}
+
+func formatNumber(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// changeTracker diffs incoming listings against the stored row inside an upsert transaction.
+type changeTracker struct {
+	lookup *sql.Stmt
+	insert *sql.Stmt
+	at     string
+}
+
+func newChangeTracker(ctx context.Context, tx *sql.Tx, at time.Time) (*changeTracker, error) {
+	lookup, err := tx.PrepareContext(ctx, `
+		SELECT COALESCE(name, ''), COALESCE(price, 0), COALESCE(score, 0), COALESCE(origin, ''),
+		       COALESCE(region, ''), COALESCE(tasting_notes, ''), COALESCE(processing, ''),
+		       COALESCE(description, ''), COALESCE(stock_status, '')
+		FROM coffee WHERE url = ?`)
+	if err != nil {
+		return nil, err
+	}
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO coffee_changes (coffee_url, field, old_value, new_value, changed_at)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		lookup.Close()
+		return nil, err
+	}
+	return &changeTracker{lookup: lookup, insert: insert, at: sqliteTime(at)}, nil
+}
+
+// record stores a row for every tracked field that differs from the stored listing.
+// New coffees have nothing to compare against and record nothing.
+func (t *changeTracker) record(ctx context.Context, item models.CoffeeItem) (int, error) {
+	var old models.CoffeeItem
+	err := t.lookup.QueryRowContext(ctx, item.URL).Scan(&old.Name, &old.Price, &old.Score, &old.Origin,
+		&old.Region, &old.TastingNotes, &old.Processing, &old.Description, &old.StockStatus)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, f := range trackedFields {
+		before, after := f.value(old), f.value(item)
+		if before == after {
+			continue
+		}
+		if _, err := t.insert.ExecContext(ctx, item.URL, f.column, before, after, t.at); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+func (t *changeTracker) Close() {
+	t.lookup.Close()
+	t.insert.Close()
+}
+
+// ListCoffeeChanges returns recorded field changes, newest first.
+// If coffeeURL is empty, changes across all coffees are returned. A limit of 0 means no limit.
+func ListCoffeeChanges(ctx context.Context, db *sql.DB, coffeeURL string, limit int) ([]models.CoffeeChange, error) {
+	query := `
+		SELECT ch.id, ch.coffee_url, COALESCE(c.name, ''), ch.field,
+		       COALESCE(ch.old_value, ''), COALESCE(ch.new_value, ''), ch.changed_at
+		FROM coffee_changes ch LEFT JOIN coffee c ON c.url = ch.coffee_url`
+	var args []any
+	if coffeeURL != "" {
+		query += ` WHERE ch.coffee_url = ?`
+		args = append(args, coffeeURL)
+	}
+	query += ` ORDER BY ch.changed_at DESC, ch.id`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.CoffeeChange
+	for rows.Next() {
+		var ch models.CoffeeChange
+		if err := rows.Scan(&ch.ID, &ch.CoffeeURL, &ch.CoffeeName, &ch.Field,
+			&ch.OldValue, &ch.NewValue, &ch.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestSaveDataRecordsChanges(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	item := models.CoffeeItem{URL: "https://example.com/yirg", Name: "Yirgacheffe", Price: 7, Description: "Jasmine, bergamot", StockStatus: "In Stock"}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	// Re-saving identical data records nothing
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	item.Description = "Lemon"
+	item.Price = 7.5
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	changes, err := ListCoffeeChanges(ctx, database, item.URL, 0)
+	if err != nil {
+		t.Fatalf("ListCoffeeChanges failed: %v", err)
+	}
+	got := map[string][2]string{}
+	for _, ch := range changes {
+		got[ch.Field] = [2]string{ch.OldValue, ch.NewValue}
+	}
+	want := map[string][2]string{
+		"price":       {"7", "7.5"},
+		"description": {"Jasmine, bergamot", "Lemon"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), changes)
+	}
+	for field, w := range want {
+		if got[field] != w {
+			t.Errorf("%s: got %v, want %v", field, got[field], w)
+		}
+	}
+}
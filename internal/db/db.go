@@ -2,31 +2,37 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3" // Import for side-effects only
 
 	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/vector"
 )
 
 // Connect opens a connection to the SQLite database and ensures the schema exists.
 // It automatically applies recommended settings for concurrency (WAL mode).
-func Connect(dbPath string) (*sql.DB, error) {
+func Connect(ctx context.Context, dbPath string) (*sql.DB, error) {
 	// Use robust connection settings to prevent "database locked" errors
 	dsn := fmt.Sprintf("%s?_busy_timeout=5000&_journal_mode=WAL", dbPath)
-	
+
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err = db.Ping(); err != nil {
+	if err = db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	if err = createSchema(db); err != nil {
+	if err = createSchema(ctx, db); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to ensure schema: %w", err)
 	}
@@ -34,18 +40,29 @@ func Connect(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
-// MarkAllAsInactive sets is_active=0 for all coffees.
+// SchemaVersion is stored in PRAGMA user_version by createSchema.
+// Bump it whenever the schema changes so restores can refuse newer backups.
+const SchemaVersion = 13
+
+// MarkAllAsInactive sets is_active=0 for all scraped coffees; manual entries stay active.
 // This is called at the start of a scrape run.
-func MarkAllAsInactive(db *sql.DB) error {
-	_, err := db.Exec(`UPDATE coffee SET is_active = 0 WHERE is_active = 1;`)
+func MarkAllAsInactive(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `UPDATE coffee SET is_active = 0 WHERE is_active = 1 AND url NOT LIKE ?;`,
+		models.ManualURLPrefix+"%")
 	if err != nil {
 		return fmt.Errorf("failed to mark coffees as inactive: %w", err)
 	}
 	return nil
 }
 
-// createSchema is private as it's only called by Connect.
-func createSchema(db *sql.DB) error {
+// createSchema is private as it's only called by Connect (and after a restore).
+// Every step is idempotent so it also migrates older databases forward.
+func createSchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
 	// Main Coffee Table
 	coffeeTable := `
 	CREATE TABLE IF NOT EXISTS coffee (
@@ -63,15 +80,53 @@ func createSchema(db *sql.DB) error {
 	  first_scraped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	  last_scraped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	  last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	  is_active INTEGER DEFAULT 1,
-	  description_embedding BLOB -- Added for AI search
+	  is_active INTEGER DEFAULT 1
 	);
 	CREATE INDEX IF NOT EXISTS idx_url ON coffee(url);
 	CREATE INDEX IF NOT EXISTS idx_is_active ON coffee(is_active);
 	`
-	if _, err := db.Exec(coffeeTable); err != nil {
+	if _, err := db.ExecContext(ctx, coffeeTable); err != nil {
+		return err
+	}
+
+	// Columns added to coffee after the original release
+	coffeeColumns := []struct{ name, decl string }{
+		{"lot_id", "INTEGER"}, // Groups relisted coffees; a coffee on its own is its own lot (lot_id = id)
+		// Attribute extraction: the enriched_* values stand in for empty scraped ones
+		{"enriched_processing", "TEXT"},
+		{"enriched_region", "TEXT"},
+		{"enriched_tasting_notes", "TEXT"},
+		{"varietals", "TEXT"},
+		{"producer", "TEXT"}, // Farm, washing station or cooperative; compared when linking lots
+		{"altitude_min_m", "INTEGER"},
+		{"altitude_max_m", "INTEGER"},
+		{"enrich_hash", "TEXT"}, // ContentHash of the text the attributes came from
+		{"enriched_at", "TIMESTAMP"},
+	}
+	for _, col := range coffeeColumns {
+		if err := addColumnIfMissing(ctx, db, "coffee", col.name, col.decl); err != nil {
+			return err
+		}
+	}
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_lot_id ON coffee(lot_id);`); err != nil {
+		return err
+	}
+	// SQLite can't default a column to another one, so new coffees get their
+	// own lot from a trigger
+	lotTrigger := `
+	CREATE TRIGGER IF NOT EXISTS coffee_own_lot AFTER INSERT ON coffee WHEN NEW.lot_id IS NULL
+	BEGIN
+	  UPDATE coffee SET lot_id = NEW.id WHERE id = NEW.id;
+	END;
+	`
+	if _, err := db.ExecContext(ctx, lotTrigger); err != nil {
 		return err
 	}
+	if version < 13 {
+		if _, err := db.ExecContext(ctx, `UPDATE coffee SET lot_id = id WHERE lot_id IS NULL`); err != nil {
+			return fmt.Errorf("failed to give coffees their own lot: %w", err)
+		}
+	}
 
 	// Search History Table (for local caching of AI queries)
 	historyTable := `
@@ -81,11 +136,47 @@ func createSchema(db *sql.DB) error {
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 	`
-	if _, err := db.Exec(historyTable); err != nil {
+	if _, err := db.ExecContext(ctx, historyTable); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(ctx, db, "search_history", "hit_count", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(ctx, db, "search_history", "last_used_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(ctx, db, "search_history", "model", "TEXT"); err != nil {
+		return err
+	}
+	if version < 4 {
+		if err := normalizeSearchKeys(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	// Embeddings (one vector per coffee and model, so switching models never mixes them)
+	embeddingsTable := `
+	CREATE TABLE IF NOT EXISTS embeddings (
+	  coffee_url TEXT NOT NULL,
+	  model TEXT NOT NULL,
+	  dims INTEGER NOT NULL,
+	  encoding TEXT NOT NULL DEFAULT 'raw', -- vector.Encoding of the blob
+	  vector BLOB NOT NULL,
+	  content_hash TEXT, -- ContentHash of the text the vector was made from
+	  created_at TIMESTAMP NOT NULL,
+	  PRIMARY KEY (coffee_url, model),
+	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
+	);
+	CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model);
+	`
+	if _, err := db.ExecContext(ctx, embeddingsTable); err != nil {
 		return err
 	}
+	if err := moveLegacyEmbeddings(ctx, db); err != nil {
+		return fmt.Errorf("failed to migrate embeddings: %w", err)
+	}
 
-	// (Optional Future Use) My Notes Table
+	// My Notes Table (personal tasting notes, keyed by URL so they outlive delistings)
 	notesTable := `
 	CREATE TABLE IF NOT EXISTS my_notes (
 	  id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -95,17 +186,249 @@ func createSchema(db *sql.DB) error {
 	  purchased_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
 	);
+	CREATE INDEX IF NOT EXISTS idx_notes_coffee_url ON my_notes(coffee_url);
+	`
+	if _, err := db.ExecContext(ctx, notesTable); err != nil {
+		return err
+	}
+
+	// Inventory Ledger (green coffee purchases and withdrawals)
+	ledgerTable := `
+	CREATE TABLE IF NOT EXISTS inventory_ledger (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  coffee_url TEXT NOT NULL,
+	  kind TEXT NOT NULL CHECK (kind IN ('purchase', 'roast', 'sample', 'gift')),
+	  weight_lbs REAL NOT NULL CHECK (weight_lbs > 0),
+	  price_paid REAL,
+	  vendor TEXT,
+	  occurred_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  notes TEXT,
+	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
+	);
+	CREATE INDEX IF NOT EXISTS idx_ledger_coffee_url ON inventory_ledger(coffee_url);
 	`
-	if _, err := db.Exec(notesTable); err != nil {
+	if _, err := db.ExecContext(ctx, ledgerTable); err != nil {
+		return err
+	}
+
+	// Roast Log (one row per roast batch)
+	roastTable := `
+	CREATE TABLE IF NOT EXISTS roasts (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  coffee_url TEXT NOT NULL,
+	  roasted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  charge_grams REAL NOT NULL CHECK (charge_grams > 0),
+	  yield_grams REAL,
+	  first_crack_sec INTEGER,
+	  drop_sec INTEGER,
+	  roast_level TEXT,
+	  notes TEXT,
+	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
+	);
+	CREATE INDEX IF NOT EXISTS idx_roasts_coffee_url ON roasts(coffee_url);
+	`
+	if _, err := db.ExecContext(ctx, roastTable); err != nil {
+		return err
+	}
+
+	// Coffee Changes (field-level audit log of listing edits between scrapes)
+	changesTable := `
+	CREATE TABLE IF NOT EXISTS coffee_changes (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  coffee_url TEXT NOT NULL,
+	  field TEXT NOT NULL,
+	  old_value TEXT,
+	  new_value TEXT,
+	  changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
+	);
+	CREATE INDEX IF NOT EXISTS idx_changes_coffee_url ON coffee_changes(coffee_url, changed_at);
+	`
+	if _, err := db.ExecContext(ctx, changesTable); err != nil {
+		return err
+	}
+
+	// Lot Links (proposed/decided matches between relisted coffees)
+	lotLinksTable := `
+	CREATE TABLE IF NOT EXISTS lot_links (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  new_url TEXT NOT NULL,
+	  old_url TEXT NOT NULL,
+	  score REAL NOT NULL,
+	  reasons TEXT,
+	  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected')),
+	  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  decided_at TIMESTAMP,
+	  UNIQUE (new_url, old_url),
+	  FOREIGN KEY (new_url) REFERENCES coffee (url),
+	  FOREIGN KEY (old_url) REFERENCES coffee (url)
+	);
+	`
+	if _, err := db.ExecContext(ctx, lotLinksTable); err != nil {
+		return err
+	}
+
+	// Scrape Runs (which coffees each scrape saw, at what price and stock, for as-of queries)
+	runsTable := `
+	CREATE TABLE IF NOT EXISTS scrape_runs (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  run_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_scrape_runs_run_at ON scrape_runs(run_at);
+	CREATE TABLE IF NOT EXISTS run_listings (
+	  run_id INTEGER NOT NULL,
+	  coffee_url TEXT NOT NULL,
+	  price REAL,
+	  stock_status TEXT,
+	  PRIMARY KEY (run_id, coffee_url),
+	  FOREIGN KEY (run_id) REFERENCES scrape_runs (id),
+	  FOREIGN KEY (coffee_url) REFERENCES coffee (url)
+	);
+	`
+	if _, err := db.ExecContext(ctx, runsTable); err != nil {
+		return err
+	}
+
+	// Ignore List (coffees and whole vendors hidden from listings and search)
+	ignoreTable := `
+	CREATE TABLE IF NOT EXISTS ignore_list (
+	  id INTEGER PRIMARY KEY AUTOINCREMENT,
+	  kind TEXT NOT NULL CHECK (kind IN ('coffee', 'vendor')),
+	  value TEXT NOT NULL,
+	  reason TEXT,
+	  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	  UNIQUE (kind, value)
+	);
+	`
+	if _, err := db.ExecContext(ctx, ignoreTable); err != nil {
+		return err
+	}
+
+	// Enrichments (attributes extracted by a generative model, cached by input text)
+	enrichmentsTable := `
+	CREATE TABLE IF NOT EXISTS enrichments (
+	  content_hash TEXT NOT NULL,
+	  model TEXT NOT NULL,
+	  attributes TEXT NOT NULL,
+	  created_at TIMESTAMP NOT NULL,
+	  PRIMARY KEY (content_hash, model)
+	);
+	`
+	if _, err := db.ExecContext(ctx, enrichmentsTable); err != nil {
+		return err
+	}
+
+	// Query interpretations (how a generative model split a search, cached by query)
+	interpretationsTable := `
+	CREATE TABLE IF NOT EXISTS query_interpretations (
+	  query_hash TEXT NOT NULL,
+	  model TEXT NOT NULL,
+	  reply TEXT NOT NULL,
+	  created_at TIMESTAMP NOT NULL,
+	  last_used_at TIMESTAMP,
+	  PRIMARY KEY (query_hash, model)
+	);
+	`
+	if _, err := db.ExecContext(ctx, interpretationsTable); err != nil {
+		return err
+	}
+
+	// Leases (advisory locks so two scrapes or embeds never interleave)
+	leaseTable := `
+	CREATE TABLE IF NOT EXISTS leases (
+	  name TEXT PRIMARY KEY,
+	  holder TEXT NOT NULL,
+	  purpose TEXT,
+	  acquired_at TIMESTAMP NOT NULL,
+	  heartbeat_at TIMESTAMP NOT NULL,
+	  expires_at TIMESTAMP NOT NULL
+	);
+	`
+	if _, err := db.ExecContext(ctx, leaseTable); err != nil {
+		return err
+	}
+
+	// Vectorizer (the trained model of the built-in local embedder, a single row)
+	vectorizerTable := `
+	CREATE TABLE IF NOT EXISTS vectorizer (
+	  id INTEGER PRIMARY KEY CHECK (id = 1),
+	  model BLOB NOT NULL,
+	  documents INTEGER NOT NULL,
+	  trained_at TIMESTAMP NOT NULL
+	);
+	`
+	if _, err := db.ExecContext(ctx, vectorizerTable); err != nil {
+		return err
+	}
+
+	// Needs coffee_changes, so it runs once every table exists
+	if version < 10 {
+		if err := backfillContentHashes(ctx, db); err != nil {
+			return fmt.Errorf("failed to hash embedded text: %w", err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// addColumnIfMissing adds a column to an existing table, since CREATE TABLE IF NOT EXISTS
+// won't touch tables created by older versions.
+func addColumnIfMissing(ctx context.Context, db *sql.DB, table, column, decl string) error {
+	exists, err := hasColumn(ctx, db, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
+
+// hasColumn reports whether a table has the named column.
+func hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// sqliteTime formats t the way CURRENT_TIMESTAMP does, so imported and scraped
+// timestamps compare correctly as text.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// parseSQLiteTime parses a timestamp read back as text, e.g. from an expression
+// column the driver can't infer a type for. Unparseable values give the zero time.
+func parseSQLiteTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 // SaveData performs a batch UPSERT of coffee items into the database.
 // It marks saved items as active and updates their 'last_seen_at' timestamp.
-func SaveData(db *sql.DB, items []models.CoffeeItem) (int64, error) {
+// Any listing fields that differ from the stored values are recorded in coffee_changes first,
+// and the whole batch is recorded as one scrape run for as-of queries.
+func SaveData(ctx context.Context, db *sql.DB, items []models.CoffeeItem) (int64, error) {
 	upsertSQL := `
 	INSERT INTO coffee (
 	  url, name, price, score, origin, region, tasting_notes, processing, description, stock_status,
@@ -128,7 +451,7 @@ func SaveData(db *sql.DB, items []models.CoffeeItem) (int64, error) {
 	  is_active = 1;
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	tx, err := db.BeginTx(ctx, nil)
@@ -143,8 +466,28 @@ func SaveData(db *sql.DB, items []models.CoffeeItem) (int64, error) {
 	}
 	defer stmt.Close()
 
+	now := time.Now()
+	changes, err := newChangeTracker(ctx, tx, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer changes.Close()
+
+	snapshot, err := newRunRecorder(ctx, tx, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer snapshot.Close()
+
 	var totalAffected int64 = 0
 	for _, item := range items {
+		if _, err := changes.record(ctx, item); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to record changes for %s: %w", item.URL, err)
+		}
+
 		res, err := stmt.ExecContext(ctx,
 			item.URL,
 			item.Name,
@@ -163,6 +506,11 @@ func SaveData(db *sql.DB, items []models.CoffeeItem) (int64, error) {
 		}
 		rows, _ := res.RowsAffected()
 		totalAffected += rows
+
+		if err := snapshot.record(ctx, item); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to snapshot %s: %w", item.URL, err)
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -172,69 +520,195 @@ func SaveData(db *sql.DB, items []models.CoffeeItem) (int64, error) {
 	return totalAffected, nil
 }
 
+// inventoryColumns selects everything scanInventoryItem expects from inventoryFrom.
+// Extracted attributes fill in where the vendor gave no region, notes or process.
+const inventoryColumns = `
+	c.id, c.url, COALESCE(c.name, ''), COALESCE(c.price, 0), COALESCE(c.score, 0),
+	COALESCE(c.origin, ''), COALESCE(NULLIF(c.region, ''), c.enriched_region, ''),
+	COALESCE(NULLIF(c.tasting_notes, ''), c.enriched_tasting_notes, ''),
+	COALESCE(NULLIF(c.processing, ''), c.enriched_processing, ''), COALESCE(c.description, ''), COALESCE(c.stock_status, ''),
+	COALESCE(c.varietals, ''), COALESCE(c.altitude_min_m, 0), COALESCE(c.altitude_max_m, 0),
+	c.is_active, c.lot_id, COALESCE(lr.rating, 0),
+	` + hiddenExpr
+
+// inventoryFrom is coffee "c" joined to "lr", the most recent rating the user
+// recorded for any listing of the same lot. Notes are few, so ranking them all
+// once is cheaper than looking up each coffee's lot.
+const inventoryFrom = `coffee c LEFT JOIN (
+	  SELECT lot_id, rating FROM (
+	    SELECT l.lot_id, n.rating,
+	           ROW_NUMBER() OVER (PARTITION BY l.lot_id ORDER BY n.purchased_at DESC, n.id DESC) AS pos
+	    FROM my_notes n JOIN coffee l ON l.url = n.coffee_url
+	    WHERE n.rating IS NOT NULL)
+	  WHERE pos = 1) lr ON lr.lot_id = c.lot_id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, for writes that may run
+// alone or as part of a larger transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanInventoryItem scans the inventoryColumns, followed by any extra columns the query selected.
+func scanInventoryItem(row rowScanner, extra ...any) (models.InventoryItem, error) {
+	var i models.InventoryItem
+	dest := []any{&i.ID, &i.URL, &i.Name, &i.Price, &i.Score,
+		&i.Origin, &i.Region, &i.TastingNotes,
+		&i.Processing, &i.Description, &i.StockStatus,
+		&i.Varietals, &i.AltitudeMin, &i.AltitudeMax,
+		&i.IsActive, &i.LotID, &i.Rating, &i.Hidden}
+	err := row.Scan(append(dest, extra...)...)
+	return i, err
+}
+
 // GetActiveCoffees returns all currently available coffees for the web UI.
-func GetActiveCoffees(db *sql.DB) ([]models.CoffeeItem, error) {
-	// We only need basic info for the main list
-	rows, err := db.Query(`
-		SELECT name, url, origin, price, stock_status
-		FROM coffee
-		WHERE is_active = 1
-		ORDER BY id DESC
+// Coffees on the ignore list are left out unless includeHidden is set.
+func GetActiveCoffees(ctx context.Context, db *sql.DB, includeHidden bool) ([]models.InventoryItem, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+inventoryColumns+`
+		FROM `+inventoryFrom+`
+		WHERE c.is_active = 1`+visibleUnless(includeHidden)+`
+		ORDER BY c.id DESC
 	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var items []models.CoffeeItem
+	var items []models.InventoryItem
 	for rows.Next() {
-		var i models.CoffeeItem
-		if err := rows.Scan(&i.Name, &i.URL, &i.Origin, &i.Price, &i.StockStatus); err == nil {
-			items = append(items, i)
+		i, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, err
 		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// GetCoffee returns a single coffee by ID, whether or not it is still active.
+func GetCoffee(ctx context.Context, db *sql.DB, id int64) (models.InventoryItem, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+inventoryColumns+` FROM `+inventoryFrom+` WHERE c.id = ?`, id)
+	item, err := scanInventoryItem(row)
+	return item, notFound(err, "coffee", id)
+}
+
+// FindCoffee resolves a user-supplied reference to a coffee.
+// Numeric references are treated as IDs, anything else as the product URL.
+func FindCoffee(ctx context.Context, db *sql.DB, ref string) (models.InventoryItem, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return GetCoffee(ctx, db, id)
 	}
-	return items, nil
+	row := db.QueryRowContext(ctx, `SELECT `+inventoryColumns+` FROM `+inventoryFrom+` WHERE c.url = ?`, ref)
+	item, err := scanInventoryItem(row)
+	return item, notFound(err, "coffee", ref)
 }
 
 // --- Embedding & Search Helpers ---
 
-// GetUnembeddedCoffees returns a map of URL -> Description for active items missing embeddings.
-func GetUnembeddedCoffees(db *sql.DB) (map[string]string, error) {
-	rows, err := db.Query(`SELECT url, name, description FROM coffee WHERE is_active = 1 AND description_embedding IS NULL`)
+// EmbeddingCandidate is a coffee that may need embedding, with the
+// ContentHash stored with its current vector ("" if it has none).
+type EmbeddingCandidate struct {
+	models.CoffeeItem
+	Hash string
+}
+
+// GetEmbeddingCandidates returns the active coffees, in URL order, with the
+// hash of the text behind their vector from model. The caller renders each
+// coffee's text and embeds those whose hash differs. With reembed, delisted
+// coffees that have a vector from another model are included too, i.e.
+// everything a move to this model has to redo.
+func GetEmbeddingCandidates(ctx context.Context, db *sql.DB, model string, reembed bool) ([]EmbeddingCandidate, error) {
+	where := `c.is_active = 1`
+	args := []any{model}
+	if reembed {
+		where += ` OR EXISTS (SELECT 1 FROM embeddings o WHERE o.coffee_url = c.url AND o.model != ?)`
+		args = append(args, model)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+inventoryColumns+`, COALESCE(e.content_hash, '')
+		FROM `+inventoryFrom+`
+		LEFT JOIN embeddings e ON e.coffee_url = c.url AND e.model = ?
+		WHERE `+where+`
+		ORDER BY c.url`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	results := make(map[string]string)
+	var candidates []EmbeddingCandidate
 	for rows.Next() {
-		var url, name, desc string
-		if err := rows.Scan(&url, &name, &desc); err == nil {
-			// Combine name and description for a richer embedding
-			results[url] = fmt.Sprintf("Coffee Name: %s\nDescription: %s", name, desc)
+		var c EmbeddingCandidate
+		item, err := scanInventoryItem(rows, &c.Hash)
+		if err != nil {
+			return nil, err
 		}
+		c.CoffeeItem = item.CoffeeItem
+		candidates = append(candidates, c)
 	}
-	return results, nil
+	return candidates, rows.Err()
+}
+
+// ContentHash identifies the exact text a vector was made from.
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
 
-// UpdateEmbedding saves the generated vector blob for a specific coffee URL.
-func UpdateEmbedding(db *sql.DB, url string, embedding []byte) error {
-	_, err := db.Exec("UPDATE coffee SET description_embedding = ? WHERE url = ?", embedding, url)
+// UpdateEmbedding saves the generated vector blob for a specific coffee URL and
+// model, with the ContentHash of the text it was made from, replacing any
+// earlier vector from the same model. The blob's dimensions and encoding are
+// read from the blob itself.
+func UpdateEmbedding(ctx context.Context, db *sql.DB, url, model, hash string, embedding []byte) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO embeddings (coffee_url, model, dims, encoding, vector, content_hash, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(coffee_url, model) DO UPDATE SET
+		  dims = excluded.dims, encoding = excluded.encoding, vector = excluded.vector,
+		  content_hash = excluded.content_hash, created_at = excluded.created_at`,
+		url, model, vector.Dims(embedding), vector.EncodingOf(embedding), embedding, hash, sqliteTime(time.Now()))
 	return err
 }
 
 // GetCoffeeVectors returns all active coffees that have embeddings.
 // Returns a slice of struct for easy iteration during search.
 type CoffeeVector struct {
-	URL			string
+	URL         string
 	Name        string
 	Origin      string
 	Description string
-	Vector      []byte
+	Price       float64 // As listed at the time searched
+	StockStatus string
+	Vector      []byte // nil when searching without a model
 }
 
-func GetCoffeeVectors(db *sql.DB) ([]CoffeeVector, error) {
-	rows, err := db.Query(`SELECT url, name, origin, description, description_embedding FROM coffee WHERE is_active = 1 AND description_embedding IS NOT NULL`)
+// Only vectors from the given model are returned, and coffees on the ignore
+// list are left out unless includeHidden is set.
+func GetCoffeeVectors(ctx context.Context, db *sql.DB, model string, includeHidden bool) ([]CoffeeVector, error) {
+	return queryCoffeeVectors(ctx, db, model, 0, `c.is_active = 1`+visibleUnless(includeHidden))
+}
+
+// queryCoffeeVectors loads the coffees matching a condition on coffee "c" that
+// have a vector from model, or all of them without vectors if model is empty.
+// Price and stock come from scrape run runID where it listed the coffee, so
+// the condition can filter on them through "r".
+func queryCoffeeVectors(ctx context.Context, db *sql.DB, model string, runID int64, where string, args ...any) ([]CoffeeVector, error) {
+	vectors := `JOIN embeddings e ON e.coffee_url = c.url AND e.model = ?`
+	params := []any{model, runID}
+	if model == "" {
+		vectors = `LEFT JOIN embeddings e ON 0`
+		params = params[1:]
+	}
+	rows, err := db.QueryContext(ctx, `SELECT c.url, COALESCE(c.name, ''), COALESCE(c.origin, ''), COALESCE(c.description, ''),
+		       COALESCE(r.price, c.price, 0), COALESCE(r.stock_status, c.stock_status, ''), e.vector
+		FROM coffee c
+		`+vectors+`
+		LEFT JOIN run_listings r ON r.coffee_url = c.url AND r.run_id = ?
+		WHERE `+where+`
+		ORDER BY c.id`, append(params, args...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -243,36 +717,168 @@ func GetCoffeeVectors(db *sql.DB) ([]CoffeeVector, error) {
 	var results []CoffeeVector
 	for rows.Next() {
 		var cv CoffeeVector
-		if err := rows.Scan(&cv.URL, &cv.Name, &cv.Origin, &cv.Description, &cv.Vector); err == nil {
-			results = append(results, cv)
+		if err := rows.Scan(&cv.URL, &cv.Name, &cv.Origin, &cv.Description, &cv.Price, &cv.StockStatus, &cv.Vector); err != nil {
+			return nil, err
 		}
+		results = append(results, cv)
 	}
-	return results, nil
+	return results, rows.Err()
+}
+
+// CachePolicy bounds the search query cache.
+type CachePolicy struct {
+	TTL        time.Duration // Entries older than this are ignored and evicted, 0 = forever
+	MaxEntries int           // Least recently used entries beyond this are evicted, 0 = unlimited
+}
+
+var reSpaces = regexp.MustCompile(`\s+`)
+
+// NormalizeQuery is the cache key for a search: lowercased, trimmed, single-spaced.
+// Every search_history read and write goes through it so casing and spacing don't
+// create duplicate entries or make clears miss.
+func NormalizeQuery(text string) string {
+	return reSpaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(text)), " ")
 }
 
-// GetCachedQuery tries to find a previously searched query vector.
-func GetCachedQuery(db *sql.DB, text string) ([]byte, error) {
-	var blob []byte
-	err := db.QueryRow("SELECT embedding FROM search_history WHERE query_text = ?", text).Scan(&blob)
-	return blob, err
+// CachedQuery is a query vector from the search cache.
+type CachedQuery struct {
+	Model  string
+	Vector []byte
 }
 
-// SaveCachedQuery saves a new query and its vector to the history table.
-func SaveCachedQuery(db *sql.DB, text string, blob []byte) error {
-	_, err := db.Exec("INSERT OR IGNORE INTO search_history (query_text, embedding) VALUES (?, ?)", text, blob)
+// GetCachedQuery tries to find a previously searched query vector. With a
+// model, only a vector from that model counts; an empty model takes whatever
+// is cached, for searching without an embedder.
+// Entries older than the policy's TTL are treated as misses.
+// A hit bumps the entry's hit count and last-used time.
+func GetCachedQuery(ctx context.Context, db *sql.DB, text, model string, policy CachePolicy) (CachedQuery, error) {
+	key := NormalizeQuery(text)
+	query := "SELECT COALESCE(model, ''), embedding FROM search_history WHERE query_text = ?"
+	args := []any{key}
+	if model != "" {
+		query += " AND model = ?"
+		args = append(args, model)
+	}
+	if policy.TTL > 0 {
+		query += " AND created_at >= ?"
+		args = append(args, sqliteTime(time.Now().Add(-policy.TTL)))
+	}
+
+	var cached CachedQuery
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&cached.Model, &cached.Vector); err != nil {
+		return cached, notFound(err, "cached query", key)
+	}
+	_, err := db.ExecContext(ctx, `UPDATE search_history SET hit_count = hit_count + 1, last_used_at = ? WHERE query_text = ?`,
+		sqliteTime(time.Now()), key)
+	return cached, err
+}
+
+// SaveCachedQuery saves a new query and its vector to the history table,
+// replacing a vector from any other model, then evicts anything the policy no
+// longer allows.
+func SaveCachedQuery(ctx context.Context, db *sql.DB, text, model string, blob []byte, policy CachePolicy) error {
+	now := sqliteTime(time.Now())
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO search_history (query_text, model, embedding, created_at, last_used_at, hit_count)
+		VALUES (?, ?, ?, ?, ?, 0)
+		ON CONFLICT(query_text) DO UPDATE SET
+		  model = excluded.model, embedding = excluded.embedding, created_at = excluded.created_at, last_used_at = excluded.last_used_at`,
+		NormalizeQuery(text), model, blob, now, now)
+	if err != nil {
+		return err
+	}
+	_, err = EvictSearchCache(ctx, db, policy)
 	return err
 }
 
+// EvictSearchCache deletes expired entries and, beyond MaxEntries, the least recently used ones.
+func EvictSearchCache(ctx context.Context, db *sql.DB, policy CachePolicy) (int64, error) {
+	var removed int64
+	if policy.TTL > 0 {
+		res, err := db.ExecContext(ctx, `DELETE FROM search_history WHERE created_at < ?`, sqliteTime(time.Now().Add(-policy.TTL)))
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+	if policy.MaxEntries > 0 {
+		res, err := db.ExecContext(ctx, `
+			DELETE FROM search_history WHERE query_text NOT IN (
+			  SELECT query_text FROM search_history
+			  ORDER BY COALESCE(last_used_at, created_at) DESC LIMIT ?
+			)`, policy.MaxEntries)
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+	return removed, nil
+}
+
+// normalizeSearchKeys rewrites cache keys saved before NormalizeQuery existed.
+// When several old keys collapse to one, the most used entry wins and the hit counts are summed.
+func normalizeSearchKeys(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT query_text FROM search_history`)
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+		if key != NormalizeQuery(key) {
+			stale = append(stale, key)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		norm := NormalizeQuery(key)
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO search_history (query_text, model, embedding, created_at, last_used_at, hit_count)
+			SELECT ?, model, embedding, created_at, last_used_at, hit_count FROM search_history WHERE query_text = ?
+			ON CONFLICT(query_text) DO UPDATE SET hit_count = hit_count + excluded.hit_count`, norm, key); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM search_history WHERE query_text = ?`, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --- History Management for search ---
 
 type HistoryEntry struct {
-	QueryText string
-	CreatedAt time.Time
+	QueryText  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	HitCount   int
 }
 
-// ListSearchHistory returns all cached queries, newest first.
-func ListSearchHistory(db *sql.DB) ([]HistoryEntry, error) {
-	rows, err := db.Query("SELECT query_text, created_at FROM search_history ORDER BY created_at DESC")
+// History sort orders.
+const (
+	SortPopular = "popular"
+	SortRecent  = "recent"
+)
+
+// ListSearchHistory returns all cached queries, most popular (or most recently used) first.
+func ListSearchHistory(ctx context.Context, db *sql.DB, sortBy string) ([]HistoryEntry, error) {
+	order := "hit_count DESC, last_used DESC"
+	if sortBy == SortRecent {
+		order = "last_used DESC"
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT query_text, created_at, COALESCE(last_used_at, created_at) AS last_used, COALESCE(hit_count, 0)
+		FROM search_history ORDER BY `+order)
 	if err != nil {
 		return nil, err
 	}
@@ -281,16 +887,19 @@ func ListSearchHistory(db *sql.DB) ([]HistoryEntry, error) {
 	var entries []HistoryEntry
 	for rows.Next() {
 		var e HistoryEntry
-		if err := rows.Scan(&e.QueryText, &e.CreatedAt); err == nil {
-			entries = append(entries, e)
+		var lastUsed string
+		if err := rows.Scan(&e.QueryText, &e.CreatedAt, &lastUsed, &e.HitCount); err != nil {
+			return nil, err
 		}
+		e.LastUsedAt = parseSQLiteTime(lastUsed)
+		entries = append(entries, e)
 	}
-	return entries, nil
+	return entries, rows.Err()
 }
 
 // ClearSearchHistory removes a specific query from the cache.
-func ClearSearchHistory(db *sql.DB, queryText string) (int64, error) {
-	res, err := db.Exec("DELETE FROM search_history WHERE query_text = ?", queryText)
+func ClearSearchHistory(ctx context.Context, db *sql.DB, queryText string) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM search_history WHERE query_text = ?", NormalizeQuery(queryText))
 	if err != nil {
 		return 0, err
 	}
@@ -298,8 +907,8 @@ func ClearSearchHistory(db *sql.DB, queryText string) (int64, error) {
 }
 
 // ClearAllSearchHistory wipes the entire cache.
-func ClearAllSearchHistory(db *sql.DB) (int64, error) {
-	res, err := db.Exec("DELETE FROM search_history")
+func ClearAllSearchHistory(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM search_history")
 	if err != nil {
 		return 0, err
 	}
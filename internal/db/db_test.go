@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// TestDatabaseUPSERT tests the insert, update, and is_active logic.
+func TestDatabaseUPSERT(t *testing.T) {
+	ctx := context.Background()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory db: %v", err)
+	}
+	defer database.Close()
+
+	if err := createSchema(ctx, database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	// 1. Test INSERT
+	item1 := models.CoffeeItem{
+		URL:   "https://example.com/coffee1",
+		Name:  "Test Coffee",
+		Price: 10.00,
+	}
+	items := []models.CoffeeItem{item1}
+
+	count, err := SaveData(ctx, database, items)
+	if err != nil {
+		t.Fatalf("SaveData (insert) failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 row affected for insert, got %d", count)
+	}
+
+	// Verify insert and default is_active status
+	var name string
+	var isActive int
+	err = database.QueryRow("SELECT name, is_active FROM coffee WHERE url = ?", item1.URL).Scan(&name, &isActive)
+	if err != nil {
+		t.Fatalf("Failed to query inserted data: %v", err)
+	}
+	if name != "Test Coffee" {
+		t.Errorf("Inserted name mismatch. Got '%s'", name)
+	}
+	if isActive != 1 {
+		t.Errorf("New item should be active (1), got %d", isActive)
+	}
+
+	// 2. Test UPDATE (ON CONFLICT)
+	// We also test that it stays active
+	item2 := models.CoffeeItem{
+		URL:   "https://example.com/coffee1", // Same URL
+		Name:  "Test Coffee Updated",
+		Price: 12.50,
+	}
+	items = []models.CoffeeItem{item2}
+
+	_, err = SaveData(ctx, database, items)
+	if err != nil {
+		t.Fatalf("SaveData (update) failed: %v", err)
+	}
+
+	// Verify update
+	err = database.QueryRow("SELECT name, price, is_active FROM coffee WHERE url = ?", item2.URL).Scan(&name, &item2.Price, &isActive)
+	if err != nil {
+		t.Fatalf("Failed to query updated data: %v", err)
+	}
+	if name != "Test Coffee Updated" {
+		t.Errorf("Updated name mismatch. Got '%s'", name)
+	}
+	if isActive != 1 {
+		t.Errorf("Updated item should remain active (1), got %d", isActive)
+	}
+}
+
+// openTestDB returns a fresh in-memory database with the schema applied.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory db: %v", err)
+	}
+	// Each connection to ":memory:" is a separate database, so pin the pool to one.
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+
+	if err := createSchema(context.Background(), database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return database
+}
+
+// TestReadErrors checks that bad rows and missing records are reported
+// instead of being silently dropped.
+func TestReadErrors(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	if _, err := GetCoffee(ctx, database, 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCoffee on a missing ID = %v, want ErrNotFound", err)
+	}
+	var nf *NotFoundError
+	if _, err := FindCoffee(ctx, database, "https://example.com/none"); !errors.As(err, &nf) || nf.What != "coffee" {
+		t.Errorf("FindCoffee on a missing URL = %v, want a coffee NotFoundError", err)
+	}
+
+	// A price the driver can't scan into a float must surface as an error
+	if _, err := database.Exec(`INSERT INTO coffee (url, name, price, is_active) VALUES ('https://example.com/bad', 'Bad', 'n/a', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if items, err := GetActiveCoffees(ctx, database, false); err == nil {
+		t.Errorf("GetActiveCoffees returned %d items and no error for a corrupt row", len(items))
+	}
+
+	// Cancelled contexts reach SQLite
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := GetActiveCoffees(cancelled, database, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetActiveCoffees with a cancelled context = %v, want context.Canceled", err)
+	}
+}
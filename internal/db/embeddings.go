@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// DropOtherEmbeddings deletes vectors and cached queries from models other
+// than the given one. A coffee keeps its old vector until it has one from the
+// new model, so an interrupted re-embed loses nothing.
+func DropOtherEmbeddings(ctx context.Context, db *sql.DB, model string) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM embeddings WHERE model != ? AND coffee_url IN (
+		  SELECT coffee_url FROM embeddings WHERE model = ?)`, model, model)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM search_history WHERE model IS NOT ?`, model); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// EmbeddingModelCount is how many vectors a model has made.
+type EmbeddingModelCount struct {
+	Model   string
+	Dims    int
+	Vectors int
+	Latest  time.Time
+}
+
+// ListEmbeddingModels summarises the stored vectors by model, most used first.
+func ListEmbeddingModels(ctx context.Context, db *sql.DB) ([]EmbeddingModelCount, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT model, dims, COUNT(*), MAX(created_at) FROM embeddings
+		GROUP BY model, dims ORDER BY COUNT(*) DESC, model`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []EmbeddingModelCount
+	for rows.Next() {
+		var c EmbeddingModelCount
+		var latest string
+		if err := rows.Scan(&c.Model, &c.Dims, &c.Vectors, &latest); err != nil {
+			return nil, err
+		}
+		c.Latest = parseSQLiteTime(latest)
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// CoffeeEmbedding describes one stored vector of a coffee.
+type CoffeeEmbedding struct {
+	Model     string
+	Dims      int
+	Encoding  vector.Encoding
+	Hash      string // ContentHash of the text it was made from, "" if unknown
+	CreatedAt time.Time
+}
+
+// GetCoffeeEmbeddings lists the vectors stored for a coffee, newest first.
+func GetCoffeeEmbeddings(ctx context.Context, db *sql.DB, url string) ([]CoffeeEmbedding, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT model, dims, encoding, COALESCE(content_hash, ''), created_at FROM embeddings
+		WHERE coffee_url = ? ORDER BY created_at DESC, model`, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeddings []CoffeeEmbedding
+	for rows.Next() {
+		var e CoffeeEmbedding
+		if err := rows.Scan(&e.Model, &e.Dims, &e.Encoding, &e.Hash, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, e)
+	}
+	return embeddings, rows.Err()
+}
+
+// ReencodeEmbeddings rewrites the vectors from model that aren't stored in
+// the given encoding, keeping their hashes and timestamps, so a change of
+// encoding needs no new embeddings. Going from int8 back to float32 keeps the
+// int8 precision. It returns how many vectors were rewritten.
+func ReencodeEmbeddings(ctx context.Context, db *sql.DB, model string, enc vector.Encoding) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT coffee_url, vector FROM embeddings WHERE model = ? AND encoding != ?`, model, enc)
+	if err != nil {
+		return 0, err
+	}
+	blobs := make(map[string][]byte)
+	for rows.Next() {
+		var url string
+		var blob []byte
+		if err := rows.Scan(&url, &blob); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		blobs[url] = blob
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(blobs) == 0 {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for url, blob := range blobs {
+		v, err := vector.Decode(blob)
+		if err != nil {
+			return 0, fmt.Errorf("vector of %s: %w", url, err)
+		}
+		if blob, err = vector.Encode(v.Floats(), enc); err != nil {
+			return 0, fmt.Errorf("vector of %s: %w", url, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE embeddings SET vector = ?, encoding = ?, dims = ? WHERE coffee_url = ? AND model = ?`,
+			blob, enc, vector.Dims(blob), url, model); err != nil {
+			return 0, err
+		}
+	}
+	return len(blobs), tx.Commit()
+}
+
+// legacyEmbeddingModel is what made the vectors stored before they were
+// labelled: Gemini's text-embedding-004 was the only embedder, at 768 dimensions.
+const (
+	legacyEmbeddingModel = "gemini/text-embedding-004"
+	legacyEmbeddingDims  = 768
+)
+
+// moveLegacyEmbeddings copies vectors out of coffee.description_embedding into
+// the embeddings table and drops the column. Vectors of any other size can't
+// have come from the legacy model and are labelled "unknown", so they are
+// never compared with anything and get redone by the next embed. Vectors were
+// made once, right after a coffee was first seen, so that is their timestamp.
+func moveLegacyEmbeddings(ctx context.Context, db *sql.DB) error {
+	legacy, err := hasColumn(ctx, db, "coffee", "description_embedding")
+	if err != nil || !legacy {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	label := `CASE WHEN length(%[1]s) = ? THEN ? ELSE 'unknown' END`
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT OR IGNORE INTO embeddings (coffee_url, model, dims, vector, created_at)
+		SELECT url, `+label+`, length(%[1]s) / 4, %[1]s, COALESCE(first_scraped_at, CURRENT_TIMESTAMP)
+		FROM coffee WHERE %[1]s IS NOT NULL`, "description_embedding"),
+		4*legacyEmbeddingDims, legacyEmbeddingModel); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE search_history SET model = `+label+` WHERE model IS NULL`, "embedding"),
+		4*legacyEmbeddingDims, legacyEmbeddingModel); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE coffee DROP COLUMN description_embedding`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// legacyEmbeddingText is what was embedded for every coffee before the
+// embedding document became a template; the default template renders the same.
+func legacyEmbeddingText(name, desc string) string {
+	return fmt.Sprintf("Coffee Name: %s\nDescription: %s", name, desc)
+}
+
+// backfillContentHashes hashes the text behind vectors stored before hashes
+// were. A vector is assumed to match the current text unless the change log
+// shows the name or description changing after it was made; those keep no
+// hash, so the next embed redoes them.
+func backfillContentHashes(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.coffee_url, e.model, c.name, COALESCE(c.description, '')
+		FROM embeddings e JOIN coffee c ON c.url = e.coffee_url
+		WHERE e.content_hash IS NULL AND NOT EXISTS (
+		  SELECT 1 FROM coffee_changes ch
+		  WHERE ch.coffee_url = e.coffee_url AND ch.field IN ('name', 'description') AND ch.changed_at > e.created_at)`)
+	if err != nil {
+		return err
+	}
+	type key struct{ url, model, hash string }
+	var current []key
+	for rows.Next() {
+		var k key
+		var name, desc string
+		if err := rows.Scan(&k.url, &k.model, &name, &desc); err != nil {
+			rows.Close()
+			return err
+		}
+		k.hash = ContentHash(legacyEmbeddingText(name, desc))
+		current = append(current, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, k := range current {
+		if _, err := db.ExecContext(ctx, `UPDATE embeddings SET content_hash = ? WHERE coffee_url = ? AND model = ?`,
+			k.hash, k.url, k.model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
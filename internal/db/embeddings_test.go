@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestEmbeddingModels(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/a", Name: "Kenya"},
+		{URL: "https://example.com/b", Name: "Brazil"},
+		{URL: "https://example.com/c", Name: "Sumatra"},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	// Sumatra is delisted, but was embedded by the old model
+	if _, err := database.ExecContext(ctx, `UPDATE coffee SET is_active = 0 WHERE url = ?`, items[2].URL); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if err := UpdateEmbedding(ctx, database, item.URL, "old/v1", textHash(item), make([]byte, 8)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := UpdateEmbedding(ctx, database, items[0].URL, "new/v2", textHash(items[0]), make([]byte, 12)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Search only sees vectors from its own model
+	if vectors, _ := GetCoffeeVectors(ctx, database, "new/v2", false); len(vectors) != 1 || len(vectors[0].Vector) != 12 {
+		t.Errorf("GetCoffeeVectors(new) = %+v; want Kenya's 3-dim vector alone", vectors)
+	}
+	if vectors, _ := GetCoffeeVectors(ctx, database, "old/v1", false); len(vectors) != 2 {
+		t.Errorf("GetCoffeeVectors(old) = %d vectors, want the 2 active coffees", len(vectors))
+	}
+	counts, err := ListEmbeddingModels(ctx, database)
+	if err != nil || len(counts) != 2 || counts[0].Model != "old/v1" || counts[0].Vectors != 3 || counts[1].Dims != 3 {
+		t.Errorf("ListEmbeddingModels = %+v, %v", counts, err)
+	}
+
+	// A plain embed redoes active coffees only; a re-embed takes the delisted one too
+	if pending, _ := staleCoffees(ctx, database, "new/v2", false); len(pending) != 1 {
+		t.Errorf("staleCoffees = %v, want Brazil alone", pending)
+	}
+	pending, err := staleCoffees(ctx, database, "new/v2", true)
+	if err != nil || len(pending) != 2 || pending[items[2].URL] == "" {
+		t.Errorf("staleCoffees(reembed) = %v, %v; want Brazil and Sumatra", pending, err)
+	}
+
+	// Old vectors go only once their coffee has a new one
+	if err := SaveCachedQuery(ctx, database, "fruity", "old/v1", []byte{1}, CachePolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := DropOtherEmbeddings(ctx, database, "new/v2"); err != nil || n != 1 {
+		t.Errorf("DropOtherEmbeddings = %d, %v; want Kenya's old vector gone", n, err)
+	}
+	if vectors, _ := GetCoffeeVectors(ctx, database, "old/v1", false); len(vectors) != 1 {
+		t.Errorf("Expected Brazil to keep its old vector, got %d", len(vectors))
+	}
+	if _, err := GetCachedQuery(ctx, database, "fruity", "", CachePolicy{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the old model's cached query to be dropped, got %v", err)
+	}
+}
+
+// textHash is the ContentHash of the default embedding document for item.
+func textHash(item models.CoffeeItem) string {
+	return ContentHash(legacyEmbeddingText(item.Name, item.Description))
+}
+
+// staleCoffees does what embedder.Pending does with the default template:
+// it returns URL -> text for the candidates whose vector needs redoing.
+func staleCoffees(ctx context.Context, database *sql.DB, model string, reembed bool) (map[string]string, error) {
+	candidates, err := GetEmbeddingCandidates(ctx, database, model, reembed)
+	if err != nil {
+		return nil, err
+	}
+	stale := make(map[string]string)
+	for _, c := range candidates {
+		if text := legacyEmbeddingText(c.Name, c.Description); c.Hash != ContentHash(text) {
+			stale[c.URL] = text
+		}
+	}
+	return stale, nil
+}
+
+func TestChangedTextIsReembedded(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/a", Name: "Kenya", Description: "Blackcurrant"},
+		{URL: "https://example.com/b", Name: "Brazil", Description: "Chocolate"},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if err := UpdateEmbedding(ctx, database, item.URL, "test/model", textHash(item), []byte{1, 2, 3, 4}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pending, _ := staleCoffees(ctx, database, "test/model", false); len(pending) != 0 {
+		t.Fatalf("Expected nothing to embed, got %v", pending)
+	}
+
+	// The vendor rewrites Kenya's description; a price change alone doesn't matter
+	items[0].Description = "Blackcurrant and tomato, now juicier"
+	items[1].Price = 9.5
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := staleCoffees(ctx, database, "test/model", false)
+	if err != nil || len(pending) != 1 || !strings.Contains(pending[items[0].URL], "juicier") {
+		t.Errorf("staleCoffees = %v, %v; want Kenya's new text", pending, err)
+	}
+}
+
+func TestLegacyEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{{URL: "https://example.com/a", Name: "Kenya"}, {URL: "https://example.com/b", Name: "Brazil"}}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	// Recreate a database from before vectors were labelled
+	legacy := []string{
+		`ALTER TABLE coffee ADD COLUMN description_embedding BLOB`,
+		`UPDATE coffee SET description_embedding = zeroblob(3072) WHERE url = 'https://example.com/a'`,
+		`UPDATE coffee SET description_embedding = zeroblob(8) WHERE url = 'https://example.com/b'`,
+		`INSERT INTO search_history (query_text, embedding) VALUES ('fruity', zeroblob(3072))`,
+	}
+	for _, stmt := range legacy {
+		if _, err := database.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Brazil's description changed after its vector was made
+	if _, err := database.ExecContext(ctx, `
+		INSERT INTO coffee_changes (coffee_url, field, old_value, new_value, changed_at)
+		VALUES ('https://example.com/b', 'description', '', 'Nutty', '2999-01-01 00:00:00')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecContext(ctx, `PRAGMA user_version = 8`); err != nil {
+		t.Fatal(err)
+	}
+	if err := createSchema(ctx, database); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if still, _ := hasColumn(ctx, database, "coffee", "description_embedding"); still {
+		t.Error("Expected the legacy column to be dropped")
+	}
+	if vectors, _ := GetCoffeeVectors(ctx, database, legacyEmbeddingModel, false); len(vectors) != 1 || vectors[0].URL != items[0].URL {
+		t.Errorf("Expected Kenya's 768-dim vector under %s, got %+v", legacyEmbeddingModel, vectors)
+	}
+	if vectors, _ := GetCoffeeVectors(ctx, database, "unknown", false); len(vectors) != 1 {
+		t.Errorf("Expected Brazil's odd-sized vector as unknown, got %d", len(vectors))
+	}
+	if cached, err := GetCachedQuery(ctx, database, "fruity", legacyEmbeddingModel, CachePolicy{}); err != nil || cached.Model != legacyEmbeddingModel {
+		t.Errorf("GetCachedQuery = %+v, %v", cached, err)
+	}
+
+	// Kenya's vector is kept, but Brazil's text changed since its vector was made
+	if pending, _ := staleCoffees(ctx, database, legacyEmbeddingModel, false); len(pending) != 1 || pending[items[0].URL] != "" {
+		t.Errorf("Expected Kenya's vector to be kept, got %v", pending)
+	}
+	var hash sql.NullString
+	if err := database.QueryRowContext(ctx, `SELECT content_hash FROM embeddings WHERE coffee_url = ?`, items[1].URL).Scan(&hash); err != nil || hash.Valid {
+		t.Errorf("Expected Brazil's vector to be left unhashed, got %v, %v", hash, err)
+	}
+}
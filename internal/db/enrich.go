@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// EnrichCandidate is an active coffee as the vendor listed it, with the
+// enrich_hash of the text its extracted attributes came from ("" if none).
+type EnrichCandidate struct {
+	URL         string
+	Name        string
+	Origin      string
+	Description string
+	Hash        string
+}
+
+// GetEnrichCandidates returns the active coffees in URL order. The caller
+// builds the extraction input and compares its hash with Hash to decide what
+// needs (re-)extracting.
+func GetEnrichCandidates(ctx context.Context, db *sql.DB) ([]EnrichCandidate, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT url, COALESCE(name, ''), COALESCE(origin, ''), COALESCE(description, ''), COALESCE(enrich_hash, '')
+		FROM coffee WHERE is_active = 1 ORDER BY url`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []EnrichCandidate
+	for rows.Next() {
+		var c EnrichCandidate
+		if err := rows.Scan(&c.URL, &c.Name, &c.Origin, &c.Description, &c.Hash); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// GetEnrichment returns the cached attributes (JSON) a model extracted from
+// the text with the given hash.
+func GetEnrichment(ctx context.Context, db *sql.DB, hash, model string) (string, error) {
+	var attrs string
+	err := db.QueryRowContext(ctx, `SELECT attributes FROM enrichments WHERE content_hash = ? AND model = ?`,
+		hash, model).Scan(&attrs)
+	if err != nil {
+		return "", notFound(err, "enrichment", hash)
+	}
+	return attrs, nil
+}
+
+// SaveEnrichment caches the attributes (JSON) a model extracted from the text
+// with the given hash, so identical descriptions are only sent once.
+func SaveEnrichment(ctx context.Context, db *sql.DB, hash, model, attrs string) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO enrichments (content_hash, model, attributes, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(content_hash, model) DO UPDATE SET attributes = excluded.attributes, created_at = excluded.created_at`,
+		hash, model, attrs, sqliteTime(time.Now()))
+	return err
+}
+
+// Enrichment is the set of attributes stored on a coffee by extraction.
+// Processing, Region and TastingNotes only show where the vendor left the
+// scraped field empty.
+type Enrichment struct {
+	Processing   string
+	Region       string
+	TastingNotes string
+	Varietals    string
+	Producer     string
+	AltitudeMin  int
+	AltitudeMax  int
+}
+
+// ApplyEnrichment stores extracted attributes on a coffee along with the hash
+// of the text they came from.
+func ApplyEnrichment(ctx context.Context, db *sql.DB, url, hash string, e Enrichment) error {
+	return applyEnrichment(ctx, db, url, hash, e)
+}
+
+func applyEnrichment(ctx context.Context, db querier, url, hash string, e Enrichment) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE coffee SET
+		  enriched_processing = NULLIF(?, ''), enriched_region = NULLIF(?, ''), enriched_tasting_notes = NULLIF(?, ''),
+		  varietals = NULLIF(?, ''), producer = NULLIF(?, ''), altitude_min_m = NULLIF(?, 0), altitude_max_m = NULLIF(?, 0),
+		  enrich_hash = ?, enriched_at = ?
+		WHERE url = ?`,
+		e.Processing, e.Region, e.TastingNotes, e.Varietals, e.Producer, e.AltitudeMin, e.AltitudeMax,
+		hash, sqliteTime(time.Now()), url)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound(sql.ErrNoRows, "coffee", url)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is matched (via errors.Is) by every lookup that finds no row.
+// Callers should test for it rather than for sql.ErrNoRows.
+var ErrNotFound = errors.New("not found")
+
+// NotFoundError says which record a lookup was looking for.
+type NotFoundError struct {
+	What string // e.g. "coffee", "note"
+	Key  any    // the ID, URL or query that was looked up
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %v not found", e.What, e.Key)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// notFound translates sql.ErrNoRows into a *NotFoundError and passes any
+// other error through unchanged.
+func notFound(err error, what string, key any) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{What: what, Key: key}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// SearchFilter holds the hard constraints of a search. Zero fields don't filter.
+type SearchFilter struct {
+	Origins  []string // Any of these, found in the origin or name
+	Process  string   // Found in the process, or in the description when the process is unknown
+	MaxPrice float64  // Highest listed price
+	InStock  bool
+	Vendor   string // Vendor host, subdomains included (see models.CoffeeItem.Vendor)
+}
+
+// IsZero reports whether the filter lets every coffee through.
+func (f SearchFilter) IsZero() bool {
+	return len(f.Origins) == 0 && f.Process == "" && f.MaxPrice == 0 && !f.InStock && f.Vendor == ""
+}
+
+// processExpr is the process of coffee "c", scraped or extracted.
+const processExpr = `LOWER(COALESCE(NULLIF(c.processing, ''), c.enriched_processing, ''))`
+
+// where returns the filter as AND clauses on coffee "c" and its listing "r".
+func (f SearchFilter) where() (string, []any) {
+	var b strings.Builder
+	var args []any
+	if len(f.Origins) > 0 {
+		var alternatives []string
+		for _, o := range f.Origins {
+			alternatives = append(alternatives, `LOWER(COALESCE(c.origin, '')) LIKE ? OR LOWER(COALESCE(c.name, '')) LIKE ?`)
+			pattern := "%" + strings.ToLower(o) + "%"
+			args = append(args, pattern, pattern)
+		}
+		b.WriteString(` AND (` + strings.Join(alternatives, ` OR `) + `)`)
+	}
+	if f.Process != "" {
+		pattern := "%" + strings.ToLower(f.Process) + "%"
+		b.WriteString(` AND (` + processExpr + ` LIKE ? OR (` + processExpr + ` = '' AND LOWER(COALESCE(c.description, '')) LIKE ?))`)
+		args = append(args, pattern, pattern)
+	}
+	if f.MaxPrice > 0 {
+		b.WriteString(` AND COALESCE(r.price, c.price, 0) BETWEEN 0.01 AND ?`)
+		args = append(args, f.MaxPrice)
+	}
+	if f.InStock {
+		b.WriteString(` AND COALESCE(r.stock_status, c.stock_status, '') = 'In Stock'`)
+	}
+	if f.Vendor != "" {
+		vendor := strings.ToLower(f.Vendor)
+		b.WriteString(` AND (` + vendorExpr + ` = ? OR ` + vendorExpr + ` LIKE ?)`)
+		args = append(args, vendor, "%."+vendor)
+	}
+	return b.String(), args
+}
+
+// SearchCoffeeVectors loads the coffees a search may return: the current
+// catalogue, or the one as of at when it is set, narrowed by f. With a model
+// only the coffees it embedded come back; without one every match does,
+// with no vector.
+func SearchCoffeeVectors(ctx context.Context, db *sql.DB, model string, at time.Time, f SearchFilter, includeHidden bool) ([]CoffeeVector, error) {
+	filter, filterArgs := f.where()
+	if at.IsZero() {
+		return queryCoffeeVectors(ctx, db, model, 0, `c.is_active = 1`+visibleUnless(includeHidden)+filter, filterArgs...)
+	}
+	run, where, args, err := asOfFilter(ctx, db, at)
+	if err != nil {
+		return nil, err
+	}
+	return queryCoffeeVectors(ctx, db, model, run.ID, where+visibleUnless(includeHidden)+filter, append(args, filterArgs...)...)
+}
+
+// ListVendors returns the vendors of the active coffees, without "www.", in
+// alphabetical order.
+func ListVendors(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT `+vendorExpr+` AS vendor FROM coffee c WHERE c.is_active = 1 ORDER BY vendor`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var vendors []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		v = strings.TrimPrefix(v, "www.")
+		if v != "" && !seen[v] {
+			seen[v] = true
+			vendors = append(vendors, v)
+		}
+	}
+	return vendors, rows.Err()
+}
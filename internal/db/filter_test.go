@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestSearchFilter(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://www.shop.test/kenya", Name: "Kenya AA", Origin: "Kenya", Processing: "Washed", Price: 8.5, StockStatus: "In Stock"},
+		{URL: "https://www.shop.test/nyeri", Name: "Nyeri Peaberry", Origin: "Kenya", Price: 9.5, StockStatus: "In Stock",
+			Description: "A fully washed peaberry"},
+		{URL: "https://other.test/guji", Name: "Ethiopia Guji", Origin: "Ethiopia", Price: 7, StockStatus: "Out of Stock",
+			Description: "A natural that tastes washed"},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if err := ApplyEnrichment(ctx, database, items[2].URL, "h", Enrichment{Processing: "Natural"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateEmbedding(ctx, database, items[0].URL, "test/model", "", []byte{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	urls := func(f SearchFilter, model string, at time.Time) []string {
+		t.Helper()
+		vectors, err := SearchCoffeeVectors(ctx, database, model, at, f, false)
+		if err != nil {
+			t.Fatalf("SearchCoffeeVectors(%+v) failed: %v", f, err)
+		}
+		var out []string
+		for _, v := range vectors {
+			out = append(out, v.URL)
+		}
+		return out
+	}
+	tests := []struct {
+		filter SearchFilter
+		want   int
+	}{
+		{SearchFilter{}, 3},
+		{SearchFilter{Origins: []string{"kenya", "Colombia"}}, 2},
+		{SearchFilter{Process: "Washed"}, 2}, // The Guji's extracted process wins over its description
+		{SearchFilter{MaxPrice: 9}, 2},
+		{SearchFilter{InStock: true, MaxPrice: 9}, 1},
+		{SearchFilter{Vendor: "shop.test"}, 2},
+	}
+	for _, tt := range tests {
+		if got := urls(tt.filter, "", time.Time{}); len(got) != tt.want {
+			t.Errorf("Filter %+v matched %v, want %d coffees", tt.filter, got, tt.want)
+		}
+	}
+	if got := urls(SearchFilter{Origins: []string{"Kenya"}}, "test/model", time.Time{}); len(got) != 1 {
+		t.Errorf("With a model got %v, want only the embedded Kenya", got)
+	}
+
+	// As of the first scrape, prices are the ones listed then
+	if _, err := database.Exec(`UPDATE scrape_runs SET run_at = '2025-03-10 12:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+	items[1].Price = 12
+	if _, err := SaveData(ctx, database, items[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	march := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	if got := urls(SearchFilter{MaxPrice: 10, InStock: true}, "", march); len(got) != 2 {
+		t.Errorf("As of March got %v, want both Kenyas at their March prices", got)
+	}
+	if got := urls(SearchFilter{MaxPrice: 10, InStock: true}, "", time.Time{}); len(got) != 1 {
+		t.Errorf("Today got %v, want the repriced peaberry left out", got)
+	}
+
+	if vendors, err := ListVendors(ctx, database); err != nil || len(vendors) != 2 || vendors[0] != "other.test" || vendors[1] != "shop.test" {
+		t.Errorf("ListVendors = %v, %v", vendors, err)
+	}
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// vendorExpr extracts the lowercased host of coffee "c"'s URL in SQL.
+const vendorExpr = `LOWER(substr(c.url, instr(c.url, '://') + 3,
+	instr(substr(c.url, instr(c.url, '://') + 3) || '/', '/') - 1))`
+
+// hiddenExpr is true when coffee "c" is on the ignore list, either itself or
+// through its vendor. Vendor entries also match subdomains such as www.
+const hiddenExpr = `EXISTS (SELECT 1 FROM ignore_list i
+	WHERE (i.kind = 'coffee' AND i.value = c.url)
+	   OR (i.kind = 'vendor' AND (` + vendorExpr + ` = i.value OR ` + vendorExpr + ` LIKE '%.' || i.value)))`
+
+// visibleUnless returns an AND clause leaving out hidden coffees, or nothing
+// when hidden ones are wanted too.
+func visibleUnless(includeHidden bool) string {
+	if includeHidden {
+		return ""
+	}
+	return ` AND NOT ` + hiddenExpr
+}
+
+// HideCoffee puts a single coffee on the ignore list.
+func HideCoffee(ctx context.Context, db *sql.DB, coffeeURL, reason string) (int64, error) {
+	return addIgnoreEntry(ctx, db, models.IgnoreCoffee, coffeeURL, reason)
+}
+
+// HideVendor puts every coffee from a vendor on the ignore list. The vendor is a
+// host name such as "example.com"; a leading "www." is dropped.
+func HideVendor(ctx context.Context, db *sql.DB, host, reason string) (int64, error) {
+	host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "www.")
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return 0, fmt.Errorf("invalid vendor %q, expected a host name like example.com", host)
+	}
+	return addIgnoreEntry(ctx, db, models.IgnoreVendor, host, reason)
+}
+
+func addIgnoreEntry(ctx context.Context, db *sql.DB, kind, value, reason string) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO ignore_list (kind, value, reason, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, value) DO UPDATE SET reason = COALESCE(excluded.reason, reason)
+		RETURNING id`,
+		kind, value, sql.NullString{String: reason, Valid: reason != ""}, sqliteTime(time.Now())).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hide %s %s: %w", kind, value, err)
+	}
+	return id, nil
+}
+
+// UnhideCoffee takes a coffee off the ignore list. Hiding through its vendor is unaffected.
+func UnhideCoffee(ctx context.Context, db *sql.DB, coffeeURL string) (int64, error) {
+	return removeIgnoreEntries(ctx, db, `kind = 'coffee' AND value = ?`, coffeeURL)
+}
+
+// UnhideVendor takes a vendor off the ignore list.
+func UnhideVendor(ctx context.Context, db *sql.DB, host string) (int64, error) {
+	host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "www.")
+	return removeIgnoreEntries(ctx, db, `kind = 'vendor' AND value = ?`, host)
+}
+
+// DeleteIgnoreEntry removes an ignore list entry by ID.
+func DeleteIgnoreEntry(ctx context.Context, db *sql.DB, id int64) (int64, error) {
+	return removeIgnoreEntries(ctx, db, `id = ?`, id)
+}
+
+func removeIgnoreEntries(ctx context.Context, db *sql.DB, where string, args ...any) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM ignore_list WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListIgnoreEntries returns the whole ignore list, vendors first.
+func ListIgnoreEntries(ctx context.Context, db *sql.DB) ([]models.IgnoreEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT i.id, i.kind, i.value, COALESCE(c.name, ''), COALESCE(i.reason, ''), i.created_at
+		FROM ignore_list i
+		LEFT JOIN coffee c ON i.kind = 'coffee' AND c.url = i.value
+		ORDER BY i.kind DESC, i.value`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.IgnoreEntry
+	for rows.Next() {
+		var e models.IgnoreEntry
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Value, &e.Name, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
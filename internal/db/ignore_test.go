@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestIgnoreList(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://www.example.com/kenya", Name: "Kenya AA"},
+		{URL: "https://www.example.com/sampler", Name: "Sample Pack"},
+		{URL: "https://decaf.test/swiss-water", Name: "Swiss Water Decaf"},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if err := UpdateEmbedding(ctx, database, items[2].URL, "test/model", "", []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := HideCoffee(ctx, database, items[1].URL, "sample pack"); err != nil {
+		t.Fatalf("HideCoffee failed: %v", err)
+	}
+	if _, err := HideVendor(ctx, database, "www.Decaf.test", ""); err != nil {
+		t.Fatalf("HideVendor failed: %v", err)
+	}
+
+	visible, err := GetActiveCoffees(ctx, database, false)
+	if err != nil || len(visible) != 1 || visible[0].URL != items[0].URL {
+		t.Errorf("GetActiveCoffees = %+v, %v; want only the Kenya", visible, err)
+	}
+	all, err := GetActiveCoffees(ctx, database, true)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("GetActiveCoffees(includeHidden) = %d items, %v; want 3", len(all), err)
+	}
+	for _, c := range all {
+		if c.Hidden != (c.URL != items[0].URL) {
+			t.Errorf("%s: Hidden = %v", c.Name, c.Hidden)
+		}
+	}
+	if vectors, err := GetCoffeeVectors(ctx, database, "test/model", false); err != nil || len(vectors) != 0 {
+		t.Errorf("GetCoffeeVectors = %d vectors, %v; want the decaf left out", len(vectors), err)
+	}
+
+	// Unhiding works for both kinds
+	if n, err := UnhideVendor(ctx, database, "decaf.test"); err != nil || n != 1 {
+		t.Errorf("UnhideVendor = %d, %v; want 1", n, err)
+	}
+	if n, err := UnhideCoffee(ctx, database, items[1].URL); err != nil || n != 1 {
+		t.Errorf("UnhideCoffee = %d, %v; want 1", n, err)
+	}
+	if visible, _ := GetActiveCoffees(ctx, database, false); len(visible) != 3 {
+		t.Errorf("Expected everything visible again, got %d", len(visible))
+	}
+}
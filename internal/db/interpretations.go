@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// GetInterpretation returns the cached reply a model gave when asked to split
+// the query with the given hash, and marks it used.
+func GetInterpretation(ctx context.Context, db *sql.DB, hash, model string) (string, error) {
+	var reply string
+	err := db.QueryRowContext(ctx, `SELECT reply FROM query_interpretations WHERE query_hash = ? AND model = ?`,
+		hash, model).Scan(&reply)
+	if err != nil {
+		return "", notFound(err, "query interpretation", hash)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE query_interpretations SET last_used_at = ? WHERE query_hash = ? AND model = ?`,
+		sqliteTime(time.Now()), hash, model); err != nil {
+		return "", err
+	}
+	return reply, nil
+}
+
+// SaveInterpretation caches a model's reply for the query with the given hash.
+func SaveInterpretation(ctx context.Context, db *sql.DB, hash, model, reply string) error {
+	now := sqliteTime(time.Now())
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO query_interpretations (query_hash, model, reply, created_at, last_used_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(query_hash, model) DO UPDATE SET reply = excluded.reply, created_at = excluded.created_at,
+		  last_used_at = excluded.last_used_at`,
+		hash, model, reply, now, now)
+	return err
+}
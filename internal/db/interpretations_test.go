@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInterpretations(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	if err := SaveInterpretation(ctx, database, "h1", "fake/chat", `{"vibe": "juicy"}`); err != nil {
+		t.Fatalf("SaveInterpretation failed: %v", err)
+	}
+	if reply, err := GetInterpretation(ctx, database, "h1", "fake/chat"); err != nil || reply != `{"vibe": "juicy"}` {
+		t.Errorf("GetInterpretation = %q, %v", reply, err)
+	}
+	// Interpretations and extracted attributes don't share keys
+	if _, err := GetEnrichment(ctx, database, "h1", "fake/chat"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEnrichment found an interpretation: %v", err)
+	}
+	if _, err := GetInterpretation(ctx, database, "h1", "other/chat"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Another model's interpretation = %v, want ErrNotFound", err)
+	}
+
+	if err := SaveInterpretation(ctx, database, "h2", "fake/chat", `{"vibe": "floral"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Prune goes by last use: h1 was used a year ago, h2 just now
+	old := sqliteTime(time.Now().AddDate(-1, 0, 0))
+	if _, err := database.ExecContext(ctx, `UPDATE query_interpretations SET created_at = ?, last_used_at = ?`, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetInterpretation(ctx, database, "h2", "fake/chat"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Prune(ctx, database, RetentionPolicy{Interpretations: 30 * 24 * time.Hour})
+	if err != nil || result.Interpretations != 1 {
+		t.Errorf("Prune = %+v, %v; want 1 interpretation dropped", result, err)
+	}
+	if _, err := GetInterpretation(ctx, database, "h2", "fake/chat"); err != nil {
+		t.Errorf("Recently used interpretation was pruned: %v", err)
+	}
+}
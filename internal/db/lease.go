@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CatalogueLease is taken by anything that rewrites the catalogue (scrape, embed),
+// so a manual run can't interleave with the scheduled one.
+const CatalogueLease = "catalogue"
+
+// Lease is an advisory lock held by one process. It lapses at ExpiresAt unless
+// the holder keeps renewing it.
+type Lease struct {
+	Name        string
+	Holder      string // Identifies the process, e.g. "host:pid"
+	Purpose     string // What it's doing, e.g. "scrape"
+	AcquiredAt  time.Time
+	HeartbeatAt time.Time
+	ExpiresAt   time.Time
+}
+
+// Stale reports whether the holder stopped renewing the lease, so anyone may take it.
+func (l Lease) Stale(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// ErrLeaseHeld is matched (via errors.Is) when a lease belongs to someone else.
+var ErrLeaseHeld = errors.New("lease held")
+
+// LeaseHeldError says who holds the lease that couldn't be taken or renewed.
+type LeaseHeldError struct {
+	Lease Lease
+}
+
+func (e *LeaseHeldError) Error() string {
+	return fmt.Sprintf("%s lock is held by %s (%s) until %s", e.Lease.Name, e.Lease.Holder, e.Lease.Purpose,
+		e.Lease.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+func (e *LeaseHeldError) Is(target error) bool {
+	return target == ErrLeaseHeld
+}
+
+// AcquireLease takes the named lease for ttl. It succeeds if the lease is free,
+// stale or already ours; with force it also takes it from a live holder.
+// Otherwise it returns a *LeaseHeldError.
+func AcquireLease(ctx context.Context, db *sql.DB, name, holder, purpose string, ttl time.Duration, force bool) (Lease, error) {
+	now := time.Now()
+	lease := Lease{Name: name, Holder: holder, Purpose: purpose, AcquiredAt: now, HeartbeatAt: now, ExpiresAt: now.Add(ttl)}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO leases (name, holder, purpose, acquired_at, heartbeat_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+		  holder = excluded.holder, purpose = excluded.purpose, acquired_at = excluded.acquired_at,
+		  heartbeat_at = excluded.heartbeat_at, expires_at = excluded.expires_at
+		WHERE leases.expires_at <= ? OR leases.holder = excluded.holder OR ?`,
+		name, holder, purpose, sqliteTime(now), sqliteTime(now), sqliteTime(lease.ExpiresAt), sqliteTime(now), force)
+	if err != nil {
+		return lease, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return lease, nil
+	}
+	return lease, heldBy(ctx, db, name)
+}
+
+// RenewLease pushes the expiry of a lease we hold ttl past now. If someone else
+// has taken it (because it went stale or was forced) it returns a *LeaseHeldError,
+// and if it was broken, an error matching ErrNotFound.
+func RenewLease(ctx context.Context, db *sql.DB, name, holder string, ttl time.Duration) error {
+	now := time.Now()
+	res, err := db.ExecContext(ctx, `UPDATE leases SET heartbeat_at = ?, expires_at = ? WHERE name = ? AND holder = ?`,
+		sqliteTime(now), sqliteTime(now.Add(ttl)), name, holder)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	return heldBy(ctx, db, name)
+}
+
+// heldBy builds the error for a lease we failed to take or keep.
+func heldBy(ctx context.Context, db *sql.DB, name string) error {
+	lease, err := GetLease(ctx, db, name)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s lock was released by someone else: %w", name, err)
+	} else if err != nil {
+		return err
+	}
+	return &LeaseHeldError{Lease: lease}
+}
+
+// ReleaseLease gives up a lease if we still hold it.
+func ReleaseLease(ctx context.Context, db *sql.DB, name, holder string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM leases WHERE name = ? AND holder = ?`, name, holder)
+	return err
+}
+
+// BreakLease removes a lease whoever holds it. With staleOnly it only removes
+// one that has expired. It returns the number of leases removed.
+func BreakLease(ctx context.Context, db *sql.DB, name string, staleOnly bool) (int64, error) {
+	query := `DELETE FROM leases WHERE name = ?`
+	args := []any{name}
+	if staleOnly {
+		query += ` AND expires_at <= ?`
+		args = append(args, sqliteTime(time.Now()))
+	}
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const leaseColumns = `name, holder, COALESCE(purpose, ''), acquired_at, heartbeat_at, expires_at`
+
+func scanLease(row rowScanner) (Lease, error) {
+	var l Lease
+	err := row.Scan(&l.Name, &l.Holder, &l.Purpose, &l.AcquiredAt, &l.HeartbeatAt, &l.ExpiresAt)
+	return l, err
+}
+
+// GetLease reads one lease.
+func GetLease(ctx context.Context, db *sql.DB, name string) (Lease, error) {
+	l, err := scanLease(db.QueryRowContext(ctx, `SELECT `+leaseColumns+` FROM leases WHERE name = ?`, name))
+	return l, notFound(err, "lease", name)
+}
+
+// ListLeases returns every lease, held or stale.
+func ListLeases(ctx context.Context, db *sql.DB) ([]Lease, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+leaseColumns+` FROM leases ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []Lease
+	for rows.Next() {
+		l, err := scanLease(rows)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, l)
+	}
+	return leases, rows.Err()
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeases(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	if _, err := AcquireLease(ctx, database, CatalogueLease, "cron:1", "scrape", time.Minute, false); err != nil {
+		t.Fatalf("AcquireLease failed: %v", err)
+	}
+	// Taking it again is fine for the holder, not for anyone else
+	if _, err := AcquireLease(ctx, database, CatalogueLease, "cron:1", "scrape", time.Minute, false); err != nil {
+		t.Errorf("Re-acquiring our own lease failed: %v", err)
+	}
+	_, err := AcquireLease(ctx, database, CatalogueLease, "laptop:2", "embed", time.Minute, false)
+	var held *LeaseHeldError
+	if !errors.As(err, &held) || held.Lease.Holder != "cron:1" || held.Lease.Purpose != "scrape" {
+		t.Fatalf("Expected a LeaseHeldError naming cron:1, got %v", err)
+	}
+	if !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("Expected errors.Is(err, ErrLeaseHeld)")
+	}
+	if n, err := BreakLease(ctx, database, CatalogueLease, true); err != nil || n != 0 {
+		t.Errorf("BreakLease(staleOnly) on a live lease = %d, %v; want 0", n, err)
+	}
+
+	// Once the holder stops renewing, the lease can be taken over
+	if _, err := database.Exec(`UPDATE leases SET expires_at = ?`, sqliteTime(time.Now().Add(-time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	leases, err := ListLeases(ctx, database)
+	if err != nil || len(leases) != 1 || !leases[0].Stale(time.Now()) {
+		t.Fatalf("ListLeases = %+v, %v; want one stale lease", leases, err)
+	}
+	if _, err := AcquireLease(ctx, database, CatalogueLease, "laptop:2", "embed", time.Minute, false); err != nil {
+		t.Fatalf("Taking over a stale lease failed: %v", err)
+	}
+	if err := RenewLease(ctx, database, CatalogueLease, "cron:1", time.Minute); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("Renewing a lost lease = %v, want ErrLeaseHeld", err)
+	}
+	if err := RenewLease(ctx, database, CatalogueLease, "laptop:2", time.Minute); err != nil {
+		t.Errorf("RenewLease failed: %v", err)
+	}
+
+	// Force takes a live lease; release only works for the holder
+	if _, err := AcquireLease(ctx, database, CatalogueLease, "cron:3", "scrape", time.Minute, true); err != nil {
+		t.Fatalf("Forced AcquireLease failed: %v", err)
+	}
+	if err := ReleaseLease(ctx, database, CatalogueLease, "laptop:2"); err != nil {
+		t.Fatal(err)
+	}
+	if lease, err := GetLease(ctx, database, CatalogueLease); err != nil || lease.Holder != "cron:3" {
+		t.Errorf("GetLease = %+v, %v; want it still held by cron:3", lease, err)
+	}
+	if err := ReleaseLease(ctx, database, CatalogueLease, "cron:3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetLease(ctx, database, CatalogueLease); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the lease to be gone, got %v", err)
+	}
+}
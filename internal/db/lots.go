@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// LotCandidate is the subset of a coffee the matcher needs to compare listings.
+type LotCandidate struct {
+	ID       int64
+	URL      string
+	Name     string
+	Origin   string
+	Region   string
+	Producer string
+	LotID    int64
+	Linked   bool // Shares its lot with another listing, i.e. already linked
+	IsActive bool
+	Vector   []byte
+	Model    string // Embedding model of Vector; vectors of different models don't compare
+}
+
+// GetLotCandidates returns every coffee with the fields used for entity resolution.
+// A coffee embedded by several models comes with its newest vector.
+func GetLotCandidates(ctx context.Context, db *sql.DB) ([]LotCandidate, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.id, c.url, COALESCE(c.name, ''), COALESCE(c.origin, ''), COALESCE(c.region, ''), COALESCE(c.producer, ''),
+		       c.lot_id, EXISTS (SELECT 1 FROM coffee o WHERE o.lot_id = c.lot_id AND o.id != c.id),
+		       c.is_active, e.vector, COALESCE(e.model, '')
+		FROM coffee c
+		LEFT JOIN embeddings e ON e.rowid = (
+		  SELECT rowid FROM embeddings WHERE coffee_url = c.url ORDER BY created_at DESC LIMIT 1)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []LotCandidate
+	for rows.Next() {
+		var c LotCandidate
+		if err := rows.Scan(&c.ID, &c.URL, &c.Name, &c.Origin, &c.Region, &c.Producer,
+			&c.LotID, &c.Linked, &c.IsActive, &c.Vector, &c.Model); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// SaveLotProposals stores new pending links and returns how many were added.
+// Pairs that were already proposed (including rejected ones) are left untouched.
+func SaveLotProposals(ctx context.Context, db *sql.DB, links []models.LotLink) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO lot_links (new_url, old_url, score, reasons) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	added := 0
+	for _, l := range links {
+		res, err := stmt.ExecContext(ctx, l.NewURL, l.OldURL, l.Score, l.Reasons)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save link %s -> %s: %w", l.NewURL, l.OldURL, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, tx.Commit()
+}
+
+const lotLinkColumns = `
+	k.id, k.new_url, COALESCE(n.id, 0), COALESCE(n.name, ''),
+	k.old_url, COALESCE(o.id, 0), COALESCE(o.name, ''),
+	k.score, COALESCE(k.reasons, ''), k.status, k.created_at`
+
+const lotLinkFrom = `
+	FROM lot_links k
+	LEFT JOIN coffee n ON n.url = k.new_url
+	LEFT JOIN coffee o ON o.url = k.old_url`
+
+func scanLotLink(row rowScanner) (models.LotLink, error) {
+	var l models.LotLink
+	err := row.Scan(&l.ID, &l.NewURL, &l.NewID, &l.NewName,
+		&l.OldURL, &l.OldID, &l.OldName,
+		&l.Score, &l.Reasons, &l.Status, &l.CreatedAt)
+	return l, err
+}
+
+// ListLotLinks returns links with the given status (all if empty), best matches first.
+func ListLotLinks(ctx context.Context, db *sql.DB, status string) ([]models.LotLink, error) {
+	query := `SELECT ` + lotLinkColumns + lotLinkFrom
+	var args []any
+	if status != "" {
+		query += ` WHERE k.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY k.score DESC, k.id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.LotLink
+	for rows.Next() {
+		l, err := scanLotLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// ListPendingLinksFor returns pending links where the coffee is either side.
+func ListPendingLinksFor(ctx context.Context, db *sql.DB, coffeeURL string) ([]models.LotLink, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+lotLinkColumns+lotLinkFrom+`
+		WHERE k.status = 'pending' AND (k.new_url = ? OR k.old_url = ?)
+		ORDER BY k.score DESC`, coffeeURL, coffeeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.LotLink
+	for rows.Next() {
+		l, err := scanLotLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// GetLotLink returns a single link by ID.
+func GetLotLink(ctx context.Context, db *sql.DB, id int64) (models.LotLink, error) {
+	l, err := scanLotLink(db.QueryRowContext(ctx, `SELECT `+lotLinkColumns+lotLinkFrom+` WHERE k.id = ?`, id))
+	return l, notFound(err, "link", id)
+}
+
+// AcceptLotLink joins the new listing (and anything already grouped with it) to
+// the old listing's lot. Other pending proposals for the same new listing are rejected.
+func AcceptLotLink(ctx context.Context, db *sql.DB, id int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var newURL, oldURL, status string
+	if err := tx.QueryRowContext(ctx, `SELECT new_url, old_url, status FROM lot_links WHERE id = ?`, id).Scan(&newURL, &oldURL, &status); err != nil {
+		return notFound(err, "link", id)
+	}
+	if status != models.LinkPending {
+		return fmt.Errorf("link #%d is already %s", id, status)
+	}
+
+	var oldLot, newLot int64
+	if err := tx.QueryRowContext(ctx, `SELECT lot_id FROM coffee WHERE url = ?`, oldURL).Scan(&oldLot); err != nil {
+		return fmt.Errorf("old listing %s: %w", oldURL, err)
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT lot_id FROM coffee WHERE url = ?`, newURL).Scan(&newLot); err != nil {
+		return fmt.Errorf("new listing %s: %w", newURL, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE coffee SET lot_id = ? WHERE lot_id = ?`, oldLot, newLot); err != nil {
+		return err
+	}
+
+	now := sqliteTime(time.Now())
+	if _, err := tx.ExecContext(ctx, `UPDATE lot_links SET status = 'accepted', decided_at = ? WHERE id = ?`, now, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE lot_links SET status = 'rejected', decided_at = ?
+		WHERE new_url = ? AND status = 'pending'`, now, newURL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RejectLotLink marks a proposal as wrong so it is never proposed again.
+func RejectLotLink(ctx context.Context, db *sql.DB, id int64) error {
+	res, err := db.ExecContext(ctx, `UPDATE lot_links SET status = 'rejected', decided_at = ? WHERE id = ? AND status = 'pending'`,
+		sqliteTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("link #%d not found or already decided", id)
+	}
+	return nil
+}
+
+// GetLotMembers returns every listing in a lot, oldest first.
+func GetLotMembers(ctx context.Context, db *sql.DB, lotID int64) ([]models.InventoryItem, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+inventoryColumns+`
+		FROM `+inventoryFrom+` WHERE c.lot_id = ?
+		ORDER BY c.first_scraped_at, c.id`, lotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.InventoryItem
+	for rows.Next() {
+		i, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
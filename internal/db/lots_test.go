@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestAcceptLotLink(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/kenya-2024", Name: "Kenya Gatomboya 2024"},
+		{URL: "https://example.com/kenya-2025", Name: "Kenya Gatomboya 2025"},
+		{URL: "https://example.com/brazil", Name: "Brazil Cerrado"},
+		{URL: "https://example.com/kenya-2025-sample", Name: "Kenya Gatomboya 2025 Sample"},
+		{URL: "https://example.com/colombia", Name: "Colombia Huila"},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	// Every coffee starts as its own lot, including ones from before lots existed
+	if _, err := database.Exec(`UPDATE coffee SET lot_id = NULL WHERE id = 5; PRAGMA user_version = 12`); err != nil {
+		t.Fatal(err)
+	}
+	if err := createSchema(ctx, database); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	for _, id := range []int64{1, 5} {
+		if c, err := GetCoffee(ctx, database, id); err != nil || c.LotID != id {
+			t.Errorf("Coffee #%d is in lot %d (%v), want its own", id, c.LotID, err)
+		}
+	}
+
+	// The sample was already grouped with the 2025 listing
+	if _, err := database.Exec(`UPDATE coffee SET lot_id = 2 WHERE id = 4`); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyEnrichment(ctx, database, items[0].URL, "hash", Enrichment{Producer: "Gatomboya Washing Station"}); err != nil {
+		t.Fatal(err)
+	}
+
+	links := []models.LotLink{
+		{NewURL: items[1].URL, OldURL: items[0].URL, Score: 0.9, Reasons: "name 60%, same producer"},
+		{NewURL: items[1].URL, OldURL: items[2].URL, Score: 0.6, Reasons: "name 0%"},
+		{NewURL: items[4].URL, OldURL: items[0].URL, Score: 0.6, Reasons: "description 90%"},
+	}
+	if n, err := SaveLotProposals(ctx, database, links); err != nil || n != 3 {
+		t.Fatalf("SaveLotProposals = %d, %v; want 3", n, err)
+	}
+
+	if err := AcceptLotLink(ctx, database, 1); err != nil {
+		t.Fatalf("AcceptLotLink failed: %v", err)
+	}
+	members, err := GetLotMembers(ctx, database, 1)
+	if err != nil || len(members) != 3 {
+		t.Fatalf("GetLotMembers = %d members, %v; want the 2024 listing, the 2025 listing and its sample", len(members), err)
+	}
+	for i, id := range []int64{1, 2, 4} {
+		if members[i].ID != id {
+			t.Errorf("Member %d is #%d, want #%d", i, members[i].ID, id)
+		}
+	}
+
+	// The other proposal for the new listing is rejected; unrelated ones stay pending
+	wantStatus := []string{models.LinkAccepted, models.LinkRejected, models.LinkPending}
+	for i, want := range wantStatus {
+		if l, err := GetLotLink(ctx, database, int64(i+1)); err != nil || l.Status != want {
+			t.Errorf("Link #%d is %q (%v), want %q", i+1, l.Status, err, want)
+		}
+	}
+	if err := AcceptLotLink(ctx, database, 2); err == nil {
+		t.Error("Expected accepting a rejected link to fail")
+	}
+	if err := AcceptLotLink(ctx, database, 99); err == nil {
+		t.Error("Expected accepting a missing link to fail")
+	}
+
+	candidates, err := GetLotCandidates(ctx, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range candidates {
+		if want := c.ID <= 2 || c.ID == 4; c.Linked != want {
+			t.Errorf("Candidate #%d linked = %v, want %v", c.ID, c.Linked, want)
+		}
+		if c.ID == 1 && c.Producer != "Gatomboya Washing Station" {
+			t.Errorf("Candidate producer = %q", c.Producer)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// AddManualCoffee stores a coffee that has no web page to scrape, such as one
+// bought from a local importer. It is given a synthetic URL under
+// models.ManualURLPrefix built from the vendor and name, is active straight away
+// and is never deactivated by scrapes. Returns the new coffee's ID.
+func AddManualCoffee(ctx context.Context, db *sql.DB, vendor string, item models.CoffeeItem) (int64, error) {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		return 0, fmt.Errorf("a name is required")
+	}
+	if item.Price < 0 {
+		return 0, fmt.Errorf("price can't be negative")
+	}
+	url, err := manualURL(vendor, item.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO coffee (url, name, price, score, origin, region, tasting_notes, processing, description, stock_status, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		url,
+		item.Name,
+		item.Price,
+		sql.NullFloat64{Float64: item.Score, Valid: item.Score > 0},
+		sql.NullString{String: item.Origin, Valid: item.Origin != ""},
+		sql.NullString{String: item.Region, Valid: item.Region != ""},
+		sql.NullString{String: item.TastingNotes, Valid: item.TastingNotes != ""},
+		sql.NullString{String: item.Processing, Valid: item.Processing != ""},
+		sql.NullString{String: item.Description, Valid: item.Description != ""},
+		sql.NullString{String: item.StockStatus, Valid: item.StockStatus != ""},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add %s: %w", item.Name, err)
+	}
+	return res.LastInsertId()
+}
+
+// manualURL builds a synthetic, unique URL like "manual://local-co-op/kenya-aa-1a2b3c".
+func manualURL(vendor, name string) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	host := slugify(vendor)
+	if host == "" {
+		host = "local"
+	}
+	return fmt.Sprintf("%s%s/%s-%s", models.ManualURLPrefix, host, slugify(name), hex.EncodeToString(suffix)), nil
+}
+
+// slugify lowercases s and joins its letters and digits with dashes.
+func slugify(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), "-")
+}
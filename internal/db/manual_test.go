@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestManualCoffee(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	id, err := AddManualCoffee(ctx, database, "Local Co-op", models.CoffeeItem{
+		Name: "Kenya AA Kiambu", Origin: "Kenya", Price: 7.5, Description: "Blackcurrant and grapefruit",
+	})
+	if err != nil {
+		t.Fatalf("AddManualCoffee failed: %v", err)
+	}
+	coffee, err := GetCoffee(ctx, database, id)
+	if err != nil {
+		t.Fatalf("GetCoffee failed: %v", err)
+	}
+	if !coffee.IsManual() || !strings.HasPrefix(coffee.URL, "manual://local-co-op/kenya-aa-kiambu-") {
+		t.Errorf("Unexpected synthetic URL %q", coffee.URL)
+	}
+	if _, err := AddManualCoffee(ctx, database, "", models.CoffeeItem{Name: "  "}); err == nil {
+		t.Errorf("Expected an error for a nameless coffee")
+	}
+
+	// A scrape run must not deactivate it
+	if err := MarkAllAsInactive(ctx, database); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{{URL: "https://example.com/a", Name: "Scraped"}}); err != nil {
+		t.Fatal(err)
+	}
+	active, err := GetActiveCoffees(ctx, database, false)
+	if err != nil || len(active) != 2 {
+		t.Fatalf("Expected the manual and scraped coffees to be active, got %d (%v)", len(active), err)
+	}
+	// The run didn't list it, but it's in the catalogue as of then all the same
+	if asOf, _, err := GetCoffeesAsOf(ctx, database, time.Now().Add(time.Minute), false); err != nil || len(asOf) != 2 {
+		t.Errorf("Expected the manual and scraped coffees as of the run, got %d (%v)", len(asOf), err)
+	}
+
+	// ...and it is queued for embedding like any other coffee
+	pending, err := staleCoffees(ctx, database, "test/model", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pending[coffee.URL]; !ok {
+		t.Errorf("Manual coffee is not queued for embedding")
+	}
+}
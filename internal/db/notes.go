@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+const noteColumns = `
+	n.id, n.coffee_url, COALESCE(c.name, ''), COALESCE(n.rating, 0),
+	COALESCE(n.notes, ''), n.purchased_at`
+
+func scanNote(row rowScanner) (models.Note, error) {
+	var n models.Note
+	err := row.Scan(&n.ID, &n.CoffeeURL, &n.CoffeeName, &n.Rating, &n.Notes, &n.PurchasedAt)
+	return n, err
+}
+
+func validateRating(rating int) error {
+	if rating < 0 || rating > models.MaxRating {
+		return fmt.Errorf("rating must be between 1 and %d (or 0 for none), got %d", models.MaxRating, rating)
+	}
+	return nil
+}
+
+// ListNotes returns tasting notes, newest first.
+// If coffeeURL is empty, notes for every coffee are returned.
+func ListNotes(ctx context.Context, db *sql.DB, coffeeURL string) ([]models.Note, error) {
+	query := `SELECT ` + noteColumns + `
+		FROM my_notes n LEFT JOIN coffee c ON c.url = n.coffee_url`
+	var args []any
+	if coffeeURL != "" {
+		query += ` WHERE n.coffee_url = ?`
+		args = append(args, coffeeURL)
+	}
+	query += ` ORDER BY n.purchased_at DESC, n.id DESC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// ListLotNotes returns the tasting notes of every listing in a lot, newest first,
+// so notes follow a coffee when the vendor relists it under a new URL.
+func ListLotNotes(ctx context.Context, db *sql.DB, lotID int64) ([]models.Note, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+noteColumns+`
+		FROM my_notes n LEFT JOIN coffee c ON c.url = n.coffee_url
+		WHERE n.coffee_url IN (SELECT url FROM coffee WHERE lot_id = ?)
+		ORDER BY n.purchased_at DESC, n.id DESC`, lotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// GetNote returns a single tasting note by ID.
+func GetNote(ctx context.Context, db *sql.DB, id int64) (models.Note, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+noteColumns+`
+		FROM my_notes n LEFT JOIN coffee c ON c.url = n.coffee_url
+		WHERE n.id = ?`, id)
+	n, err := scanNote(row)
+	return n, notFound(err, "note", id)
+}
+
+// AddNote stores a new tasting note and returns its ID.
+// A zero PurchasedAt defaults to now.
+func AddNote(ctx context.Context, db *sql.DB, n models.Note) (int64, error) {
+	if err := validateRating(n.Rating); err != nil {
+		return 0, err
+	}
+	if n.PurchasedAt.IsZero() {
+		n.PurchasedAt = time.Now()
+	}
+	res, err := db.ExecContext(ctx, `INSERT INTO my_notes (coffee_url, rating, notes, purchased_at) VALUES (?, ?, ?, ?)`,
+		n.CoffeeURL,
+		sql.NullInt64{Int64: int64(n.Rating), Valid: n.Rating > 0},
+		n.Notes,
+		n.PurchasedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add note: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// UpdateNote overwrites the rating, text and purchase date of an existing note.
+// A zero PurchasedAt defaults to now.
+func UpdateNote(ctx context.Context, db *sql.DB, n models.Note) error {
+	if err := validateRating(n.Rating); err != nil {
+		return err
+	}
+	if n.PurchasedAt.IsZero() {
+		n.PurchasedAt = time.Now()
+	}
+	res, err := db.ExecContext(ctx, `UPDATE my_notes SET rating = ?, notes = ?, purchased_at = ? WHERE id = ?`,
+		sql.NullInt64{Int64: int64(n.Rating), Valid: n.Rating > 0},
+		n.Notes,
+		n.PurchasedAt.UTC(),
+		n.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update note %d: %w", n.ID, err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return &NotFoundError{What: "note", Key: n.ID}
+	}
+	return nil
+}
+
+// DeleteNote removes a tasting note.
+func DeleteNote(ctx context.Context, db *sql.DB, id int64) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM my_notes WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// TestNotesSurviveDeactivation checks note CRUD and that ratings stay attached to inactive coffees.
+func TestNotesSurviveDeactivation(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	item := models.CoffeeItem{URL: "https://example.com/kenya", Name: "Kenya AA", Price: 8.5}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{item}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	coffee, err := FindCoffee(ctx, database, item.URL)
+	if err != nil {
+		t.Fatalf("FindCoffee by URL failed: %v", err)
+	}
+
+	id, err := AddNote(ctx, database, models.Note{CoffeeURL: item.URL, Rating: 4, Notes: "Blackcurrant"})
+	if err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	if _, err := AddNote(ctx, database, models.Note{CoffeeURL: item.URL, Rating: models.MaxRating + 1}); err == nil {
+		t.Errorf("AddNote accepted out-of-range rating")
+	}
+
+	note, err := GetNote(ctx, database, id)
+	if err != nil {
+		t.Fatalf("GetNote failed: %v", err)
+	}
+	note.Rating = 5
+	if err := UpdateNote(ctx, database, note); err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+
+	if err := MarkAllAsInactive(ctx, database); err != nil {
+		t.Fatalf("MarkAllAsInactive failed: %v", err)
+	}
+	coffee, err = GetCoffee(ctx, database, coffee.ID)
+	if err != nil {
+		t.Fatalf("GetCoffee failed: %v", err)
+	}
+	if coffee.IsActive || coffee.Rating != 5 {
+		t.Errorf("Expected inactive coffee with rating 5, got active=%v rating=%d", coffee.IsActive, coffee.Rating)
+	}
+
+	notes, err := ListNotes(ctx, database, item.URL)
+	if err != nil || len(notes) != 1 || notes[0].CoffeeName != "Kenya AA" {
+		t.Fatalf("ListNotes = %+v, %v; want one note for Kenya AA", notes, err)
+	}
+
+	if n, err := DeleteNote(ctx, database, id); err != nil || n != 1 {
+		t.Errorf("DeleteNote = %d, %v; want 1, nil", n, err)
+	}
+}
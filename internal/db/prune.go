@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// RetentionPolicy says how long Prune keeps each kind of data, measured back
+// from now. A zero duration keeps that data forever.
+type RetentionPolicy struct {
+	InactiveCoffees    time.Duration // Delisted coffees nothing of ours refers to
+	InactiveEmbeddings time.Duration // Vectors of delisted coffees
+	Snapshots          time.Duration // Scrape runs and their listings
+	Changes            time.Duration // coffee_changes audit entries
+	Interpretations    time.Duration // Cached query interpretations, by when they were last used
+	Enrichments        time.Duration // Cached attribute extractions no coffee's current text came from
+	Cache              CachePolicy   // Cached query embeddings
+}
+
+// PruneResult counts what Prune removed.
+type PruneResult struct {
+	Coffees         int64
+	Embeddings      int64
+	Snapshots       int64
+	Changes         int64
+	Interpretations int64
+	Enrichments     int64
+	CachedQueries   int64
+}
+
+// prunableCoffees selects delisted coffees last seen before the cutoff that are safe
+// to forget: not entered by hand, with no notes, stock, roasts or ignore entry, and
+// not sharing a lot with another listing.
+const prunableCoffees = `
+	SELECT c.url FROM coffee c
+	WHERE c.is_active = 0 AND c.last_seen_at < ? AND c.url NOT LIKE ?
+	  AND NOT EXISTS (SELECT 1 FROM my_notes n WHERE n.coffee_url = c.url)
+	  AND NOT EXISTS (SELECT 1 FROM inventory_ledger l WHERE l.coffee_url = c.url)
+	  AND NOT EXISTS (SELECT 1 FROM roasts r WHERE r.coffee_url = c.url)
+	  AND NOT EXISTS (SELECT 1 FROM ignore_list i WHERE i.kind = 'coffee' AND i.value = c.url)
+	  AND NOT EXISTS (SELECT 1 FROM coffee o WHERE o.lot_id = c.lot_id AND o.id != c.id)`
+
+// Prune deletes data older than the policy allows. Everything except the search
+// cache is removed in one transaction. It does not shrink the file; see Checkpoint
+// and Vacuum.
+func Prune(ctx context.Context, db *sql.DB, policy RetentionPolicy) (PruneResult, error) {
+	var result PruneResult
+	now := time.Now()
+	cutoff := func(d time.Duration) string { return sqliteTime(now.Add(-d)) }
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	exec := func(n *int64, query string, args ...any) error {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if n != nil {
+			affected, _ := res.RowsAffected()
+			*n += affected
+		}
+		return nil
+	}
+
+	if policy.InactiveCoffees > 0 {
+		args := []any{cutoff(policy.InactiveCoffees), models.ManualURLPrefix + "%"}
+		// Rows keyed by URL go first, then the coffees themselves; nothing
+		// enforces the foreign keys, so anything left behind would be orphaned
+		for _, t := range []struct {
+			table string
+			n     *int64
+		}{{"coffee_changes", nil}, {"run_listings", nil}, {"embeddings", &result.Embeddings}} {
+			if err := exec(t.n, `DELETE FROM `+t.table+` WHERE coffee_url IN (`+prunableCoffees+`)`, args...); err != nil {
+				return result, err
+			}
+		}
+		if err := exec(nil, `DELETE FROM lot_links WHERE new_url IN (`+prunableCoffees+`) OR old_url IN (`+prunableCoffees+`)`,
+			append(args, args...)...); err != nil {
+			return result, err
+		}
+		if err := exec(&result.Coffees, `DELETE FROM coffee WHERE url IN (`+prunableCoffees+`)`, args...); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.InactiveEmbeddings > 0 {
+		if err := exec(&result.Embeddings, `
+			DELETE FROM embeddings WHERE coffee_url IN (
+			  SELECT url FROM coffee WHERE is_active = 0 AND last_seen_at < ?)`,
+			cutoff(policy.InactiveEmbeddings)); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.Snapshots > 0 {
+		old := `SELECT id FROM scrape_runs WHERE run_at < ?`
+		if err := exec(nil, `DELETE FROM run_listings WHERE run_id IN (`+old+`)`, cutoff(policy.Snapshots)); err != nil {
+			return result, err
+		}
+		if err := exec(&result.Snapshots, `DELETE FROM scrape_runs WHERE id IN (`+old+`)`, cutoff(policy.Snapshots)); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.Changes > 0 {
+		if err := exec(&result.Changes, `DELETE FROM coffee_changes WHERE changed_at < ?`, cutoff(policy.Changes)); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.Interpretations > 0 {
+		if err := exec(&result.Interpretations, `DELETE FROM query_interpretations WHERE COALESCE(last_used_at, created_at) < ?`,
+			cutoff(policy.Interpretations)); err != nil {
+			return result, err
+		}
+	}
+
+	if policy.Enrichments > 0 {
+		// Answers a coffee's stored attributes came from are kept, however old
+		if err := exec(&result.Enrichments, `
+			DELETE FROM enrichments WHERE created_at < ?
+			  AND content_hash NOT IN (SELECT enrich_hash FROM coffee WHERE enrich_hash IS NOT NULL)`,
+			cutoff(policy.Enrichments)); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	result.CachedQueries, err = EvictSearchCache(ctx, db, policy.Cache)
+	return result, err
+}
+
+// SpaceUsage describes how much of the database file is in use.
+type SpaceUsage struct {
+	PageSize  int64
+	Pages     int64
+	FreePages int64 // Pages freed by deletes, reused before the file grows
+}
+
+// Bytes is the size of the main database file.
+func (u SpaceUsage) Bytes() int64 { return u.PageSize * u.Pages }
+
+// FreeBytes is the space inside the file that a vacuum would give back.
+func (u SpaceUsage) FreeBytes() int64 { return u.PageSize * u.FreePages }
+
+// GetSpaceUsage reads the page counts of the main database.
+func GetSpaceUsage(ctx context.Context, db *sql.DB) (SpaceUsage, error) {
+	var u SpaceUsage
+	for _, p := range []struct {
+		pragma string
+		dst    *int64
+	}{{"page_size", &u.PageSize}, {"page_count", &u.Pages}, {"freelist_count", &u.FreePages}} {
+		if err := db.QueryRowContext(ctx, `PRAGMA `+p.pragma).Scan(p.dst); err != nil {
+			return u, err
+		}
+	}
+	return u, nil
+}
+
+// Checkpoint copies the WAL into the main database and truncates it, so the
+// -wal file stops growing. It can't finish while readers hold old snapshots,
+// in which case busy is true and the WAL is only partly reset.
+func Checkpoint(ctx context.Context, db *sql.DB) (busy bool, err error) {
+	var blocked, logFrames, checkpointed int
+	err = db.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&blocked, &logFrames, &checkpointed)
+	return blocked != 0, err
+}
+
+// Vacuum rebuilds the database file without its free pages. It needs a moment
+// of exclusive access, so it may wait on (or fail against) a busy writer.
+func Vacuum(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `VACUUM`)
+	return err
+}
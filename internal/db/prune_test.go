@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/gone", Name: "Forgotten", Price: 5},
+		{URL: "https://example.com/noted", Name: "Loved", Price: 6},
+		{URL: "https://example.com/current", Name: "Current", Price: 7},
+	}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	items[2].Price = 7.5 // leaves a change log entry
+	if _, err := SaveData(ctx, database, items[2:]); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if _, err := AddNote(ctx, database, models.Note{CoffeeURL: items[1].URL, Notes: "Keep me"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{items[0].URL, items[1].URL} {
+		if err := UpdateEmbedding(ctx, database, url, "test/model", "", []byte{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One cached extraction is what the current listing's attributes came from
+	if err := ApplyEnrichment(ctx, database, items[2].URL, "current-text", Enrichment{Varietals: "SL28"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"current-text", "old-text"} {
+		if err := SaveEnrichment(ctx, database, hash, "fake/chat", `{}`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Age everything but the current listing by a year
+	old := sqliteTime(time.Now().AddDate(-1, 0, 0))
+	for _, q := range []string{
+		`UPDATE coffee SET is_active = 0, last_seen_at = ? WHERE url != 'https://example.com/current'`,
+		`UPDATE scrape_runs SET run_at = ? WHERE id = 1`,
+		`UPDATE coffee_changes SET changed_at = ?`,
+		`UPDATE enrichments SET created_at = ?`,
+	} {
+		if _, err := database.Exec(q, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	month := 30 * 24 * time.Hour
+	result, err := Prune(ctx, database, RetentionPolicy{
+		InactiveCoffees: month, InactiveEmbeddings: month, Snapshots: month, Changes: month, Enrichments: month,
+	})
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	// Both vectors go: the forgotten coffee's with it, the loved one's by age
+	want := PruneResult{Coffees: 1, Embeddings: 2, Snapshots: 1, Changes: 1, Enrichments: 1}
+	if result != want {
+		t.Errorf("Prune = %+v, want %+v", result, want)
+	}
+
+	if _, err := FindCoffee(ctx, database, items[0].URL); err == nil {
+		t.Errorf("Unreferenced delisted coffee was kept")
+	}
+	if _, err := FindCoffee(ctx, database, items[1].URL); err != nil {
+		t.Errorf("Coffee with notes was pruned: %v", err)
+	}
+	for _, table := range []string{"embeddings", "coffee_changes", "run_listings"} {
+		var orphans int
+		if err := database.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE coffee_url NOT IN (SELECT url FROM coffee)`).Scan(&orphans); err != nil || orphans != 0 {
+			t.Errorf("%d orphaned %s rows left (%v)", orphans, table, err)
+		}
+	}
+	if _, err := GetEnrichment(ctx, database, "current-text", "fake/chat"); err != nil {
+		t.Errorf("The extraction a coffee's attributes came from was pruned: %v", err)
+	}
+	if run, err := FindScrapeRun(ctx, database, time.Now()); err != nil || run.ID != 2 {
+		t.Errorf("Expected the recent scrape run to survive, got %+v, %v", run, err)
+	}
+
+	// A zero policy keeps everything
+	if result, err := Prune(ctx, database, RetentionPolicy{}); err != nil || result != (PruneResult{}) {
+		t.Errorf("Prune with an empty policy = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+const roastColumns = `
+	r.id, COALESCE(c.id, 0), r.coffee_url, COALESCE(c.name, ''), r.roasted_at, r.charge_grams,
+	COALESCE(r.yield_grams, 0), COALESCE(r.first_crack_sec, 0), COALESCE(r.drop_sec, 0),
+	COALESCE(r.roast_level, ''), COALESCE(r.notes, '')`
+
+func scanRoast(row rowScanner) (models.Roast, error) {
+	var r models.Roast
+	err := row.Scan(&r.ID, &r.CoffeeID, &r.CoffeeURL, &r.CoffeeName, &r.RoastedAt, &r.ChargeGrams,
+		&r.YieldGrams, &r.FirstCrackSec, &r.DropSec, &r.RoastLevel, &r.Notes)
+	return r, err
+}
+
+// AddRoast stores a roast batch and returns its ID.
+// A zero RoastedAt defaults to now.
+func AddRoast(ctx context.Context, db *sql.DB, r models.Roast) (int64, error) {
+	return addRoast(ctx, db, r)
+}
+
+// AddRoastWithWithdrawal stores a roast batch and withdraws its charge weight
+// from the stock ledger in one transaction, so a roast is never logged without
+// its withdrawal (or the other way round). It returns the roast's ID.
+func AddRoastWithWithdrawal(ctx context.Context, db *sql.DB, r models.Roast) (int64, error) {
+	if r.RoastedAt.IsZero() {
+		r.RoastedAt = time.Now()
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := addRoast(ctx, tx, r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := addLedgerEntry(ctx, tx, models.LedgerEntry{
+		CoffeeURL:  r.CoffeeURL,
+		Kind:       models.LedgerRoast,
+		WeightLbs:  r.ChargeGrams / models.GramsPerLb,
+		OccurredAt: r.RoastedAt,
+		Notes:      fmt.Sprintf("Roast #%d", id),
+	}); err != nil {
+		return 0, fmt.Errorf("stock withdrawal failed: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+func addRoast(ctx context.Context, db querier, r models.Roast) (int64, error) {
+	if r.ChargeGrams <= 0 {
+		return 0, fmt.Errorf("charge weight must be positive")
+	}
+	if r.YieldGrams < 0 || r.YieldGrams > r.ChargeGrams {
+		return 0, fmt.Errorf("yield weight must be between 0 and the charge weight")
+	}
+	if r.DropSec > 0 && r.FirstCrackSec > r.DropSec {
+		return 0, fmt.Errorf("first crack cannot come after drop")
+	}
+	if r.RoastedAt.IsZero() {
+		r.RoastedAt = time.Now()
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO roasts (coffee_url, roasted_at, charge_grams, yield_grams, first_crack_sec, drop_sec, roast_level, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.CoffeeURL,
+		r.RoastedAt.UTC(),
+		r.ChargeGrams,
+		sql.NullFloat64{Float64: r.YieldGrams, Valid: r.YieldGrams > 0},
+		sql.NullInt64{Int64: int64(r.FirstCrackSec), Valid: r.FirstCrackSec > 0},
+		sql.NullInt64{Int64: int64(r.DropSec), Valid: r.DropSec > 0},
+		sql.NullString{String: r.RoastLevel, Valid: r.RoastLevel != ""},
+		sql.NullString{String: r.Notes, Valid: r.Notes != ""},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add roast: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListRoasts returns roast batches, newest first.
+// If coffeeURL is empty, roasts of every coffee are returned.
+func ListRoasts(ctx context.Context, db *sql.DB, coffeeURL string) ([]models.Roast, error) {
+	query := `SELECT ` + roastColumns + `
+		FROM roasts r LEFT JOIN coffee c ON c.url = r.coffee_url`
+	var args []any
+	if coffeeURL != "" {
+		query += ` WHERE r.coffee_url = ?`
+		args = append(args, coffeeURL)
+	}
+	query += ` ORDER BY r.roasted_at DESC, r.id DESC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roasts []models.Roast
+	for rows.Next() {
+		r, err := scanRoast(rows)
+		if err != nil {
+			return nil, err
+		}
+		roasts = append(roasts, r)
+	}
+	return roasts, rows.Err()
+}
+
+// DeleteRoast removes a roast batch. Any ledger withdrawal made for it is left alone.
+func DeleteRoast(ctx context.Context, db *sql.DB, id int64) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM roasts WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
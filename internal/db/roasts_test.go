@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestRoasts(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	urls := []string{"https://example.com/kenya", "https://example.com/brazil"}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{{URL: urls[0], Name: "Kenya Nyeri"}, {URL: urls[1], Name: "Brazil Cerrado"}}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	invalid := []models.Roast{
+		{CoffeeURL: urls[0]},
+		{CoffeeURL: urls[0], ChargeGrams: 250, YieldGrams: 260},
+		{CoffeeURL: urls[0], ChargeGrams: 250, YieldGrams: -1},
+		{CoffeeURL: urls[0], ChargeGrams: 250, FirstCrackSec: 600, DropSec: 540},
+	}
+	for _, r := range invalid {
+		if _, err := AddRoast(ctx, database, r); err == nil {
+			t.Errorf("Expected AddRoast(%+v) to fail", r)
+		}
+	}
+
+	day := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	roasts := []models.Roast{
+		{CoffeeURL: urls[0], RoastedAt: day, ChargeGrams: 250, YieldGrams: 212, FirstCrackSec: 510, DropSec: 615, RoastLevel: "City+"},
+		{CoffeeURL: urls[0], RoastedAt: day.AddDate(0, 0, 7), ChargeGrams: 250},
+		{CoffeeURL: urls[1], RoastedAt: day.AddDate(0, 0, 3), ChargeGrams: 300, YieldGrams: 255, Notes: "Chocolatey"},
+	}
+	for i, r := range roasts {
+		id, err := AddRoast(ctx, database, r)
+		if err != nil || id != int64(i+1) {
+			t.Fatalf("AddRoast #%d = %d, %v", i+1, id, err)
+		}
+	}
+
+	all, err := ListRoasts(ctx, database, "")
+	if err != nil || len(all) != 3 {
+		t.Fatalf("ListRoasts = %d roasts, %v; want 3", len(all), err)
+	}
+	if all[0].ID != 2 || all[1].ID != 3 || all[2].ID != 1 {
+		t.Errorf("ListRoasts order = %d, %d, %d; want newest first", all[0].ID, all[1].ID, all[2].ID)
+	}
+	first := all[2]
+	if first.CoffeeName != "Kenya Nyeri" || first.RoastLevel != "City+" || !first.RoastedAt.Equal(day) || first.DropSec != 615 {
+		t.Errorf("Stored roast = %+v", first)
+	}
+	if got := first.WeightLossPct(); math.Abs(got-15.2) > 1e-9 {
+		t.Errorf("WeightLossPct = %f, want 15.2", got)
+	}
+	if got := first.DTRPct(); math.Abs(got-105.0/615*100) > 1e-9 {
+		t.Errorf("DTRPct = %f, want %f", got, 105.0/615*100)
+	}
+	if unmeasured := all[0]; unmeasured.WeightLossPct() != 0 || unmeasured.DTRPct() != 0 {
+		t.Errorf("A roast without yield or times should report 0, got %f and %f", unmeasured.WeightLossPct(), unmeasured.DTRPct())
+	}
+
+	if kenya, err := ListRoasts(ctx, database, urls[0]); err != nil || len(kenya) != 2 {
+		t.Errorf("ListRoasts(%s) = %d roasts, %v; want 2", urls[0], len(kenya), err)
+	}
+
+	if n, err := DeleteRoast(ctx, database, 2); err != nil || n != 1 {
+		t.Errorf("DeleteRoast = %d, %v", n, err)
+	}
+	if n, err := DeleteRoast(ctx, database, 2); err != nil || n != 0 {
+		t.Errorf("Deleting a missing roast = %d, %v; want 0", n, err)
+	}
+	if kenya, _ := ListRoasts(ctx, database, urls[0]); len(kenya) != 1 {
+		t.Errorf("Expected one Kenya roast left, got %d", len(kenya))
+	}
+}
+
+func TestParseMinSec(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"", 0, true},
+		{" 8:30 ", 510, true},
+		{"10:05", 605, true},
+		{"0:59", 59, true},
+		{"615", 615, true},
+		{"8:60", 0, false},
+		{"8:", 0, false},
+		{"-1:30", 0, false},
+		{"8:-5", 0, false},
+		{"-90", 0, false},
+		{"ten", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := models.ParseMinSec(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseMinSec(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+	if got := models.FormatMinSec(510); got != "8:30" {
+		t.Errorf("FormatMinSec(510) = %q", got)
+	}
+}
+
+func TestAddRoastWithWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	url := "https://example.com/kenya"
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{{URL: url, Name: "Kenya Nyeri", Price: 10}}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	if _, err := AddLedgerEntry(ctx, database, models.LedgerEntry{CoffeeURL: url, Kind: models.LedgerPurchase, WeightLbs: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := AddRoastWithWithdrawal(ctx, database, models.Roast{CoffeeURL: url, ChargeGrams: models.GramsPerLb / 2})
+	if err != nil {
+		t.Fatalf("AddRoastWithWithdrawal failed: %v", err)
+	}
+	entries, err := ListLedgerEntries(ctx, database, url)
+	if err != nil || len(entries) != 2 || entries[1].Kind != models.LedgerRoast || entries[1].Notes != "Roast #1" || id != 1 {
+		t.Fatalf("Ledger after roast #%d = %+v, %v", id, entries, err)
+	}
+	if remaining, _ := RemainingStock(ctx, database, url); math.Abs(remaining-0.5) > 1e-9 {
+		t.Errorf("Remaining stock = %.3f lb, want 0.5", remaining)
+	}
+
+	// Overdrawing the ledger saves neither the roast nor the withdrawal
+	if _, err := AddRoastWithWithdrawal(ctx, database, models.Roast{CoffeeURL: url, ChargeGrams: models.GramsPerLb}); err == nil {
+		t.Fatal("Expected a roast larger than the stock to fail")
+	}
+	if roasts, err := ListRoasts(ctx, database, url); err != nil || len(roasts) != 1 {
+		t.Errorf("ListRoasts = %d roasts, %v; want only the first", len(roasts), err)
+	}
+	if entries, _ := ListLedgerEntries(ctx, database, url); len(entries) != 2 {
+		t.Errorf("Ledger has %d entries after a failed roast, want 2", len(entries))
+	}
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchCache(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+	policy := CachePolicy{MaxEntries: 2}
+
+	if err := SaveCachedQuery(ctx, database, "  Funky   and FRUITY ", "test/model", []byte{1}, policy); err != nil {
+		t.Fatalf("SaveCachedQuery failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := GetCachedQuery(ctx, database, "funky and fruity", "", policy); err != nil {
+			t.Fatalf("GetCachedQuery missed a normalized key: %v", err)
+		}
+	}
+	if _, err := GetCachedQuery(ctx, database, "funky and fruity", "other/model", policy); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a vector from another model to miss, got %v", err)
+	}
+	if err := SaveCachedQuery(ctx, database, "chocolate", "test/model", []byte{2}, policy); err != nil {
+		t.Fatalf("SaveCachedQuery failed: %v", err)
+	}
+
+	entries, err := ListSearchHistory(ctx, database, SortPopular)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ListSearchHistory = %+v, %v; want 2 entries", entries, err)
+	}
+	if entries[0].QueryText != "funky and fruity" || entries[0].HitCount != 3 {
+		t.Errorf("Most popular entry = %+v, want 'funky and fruity' with 3 hits", entries[0])
+	}
+
+	// A third query pushes out the least recently used one
+	if _, err := database.Exec(`UPDATE search_history SET created_at = ?, last_used_at = ? WHERE query_text = 'chocolate'`,
+		sqliteTime(time.Now().Add(-time.Hour)), sqliteTime(time.Now().Add(-time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetCachedQuery(ctx, database, "FUNKY AND FRUITY", "", policy); err != nil {
+		t.Fatalf("GetCachedQuery failed: %v", err)
+	}
+	if err := SaveCachedQuery(ctx, database, "floral", "test/model", []byte{3}, policy); err != nil {
+		t.Fatalf("SaveCachedQuery failed: %v", err)
+	}
+	if _, err := GetCachedQuery(ctx, database, "chocolate", "", policy); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected 'chocolate' to be evicted")
+	}
+
+	// Clearing is case-insensitive too
+	if n, err := ClearSearchHistory(ctx, database, "Funky and Fruity"); err != nil || n != 1 {
+		t.Errorf("ClearSearchHistory = %d, %v; want 1", n, err)
+	}
+
+	// Expired entries are ignored
+	if _, err := database.Exec(`UPDATE search_history SET created_at = '2000-01-01 00:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetCachedQuery(ctx, database, "floral", "", CachePolicy{TTL: time.Hour}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected expired entry to miss")
+	}
+}
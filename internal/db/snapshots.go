@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// ScrapeRun is one recorded scrape, the unit of as-of queries.
+// A zero ID means no run was recorded that early and the catalogue was
+// approximated from each coffee's first/last seen times instead.
+type ScrapeRun struct {
+	ID       int64
+	RunAt    time.Time
+	Listings int
+}
+
+// runRecorder writes a scrape run and its listings inside an upsert transaction.
+type runRecorder struct {
+	insert *sql.Stmt
+	runID  int64
+}
+
+func newRunRecorder(ctx context.Context, tx *sql.Tx, at time.Time) (*runRecorder, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO scrape_runs (run_at) VALUES (?)`, sqliteTime(at))
+	if err != nil {
+		return nil, err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO run_listings (run_id, coffee_url, price, stock_status)
+		VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+	return &runRecorder{insert: insert, runID: runID}, nil
+}
+
+func (r *runRecorder) record(ctx context.Context, item models.CoffeeItem) error {
+	_, err := r.insert.ExecContext(ctx, r.runID, item.URL, item.Price,
+		sql.NullString{String: item.StockStatus, Valid: item.StockStatus != ""})
+	return err
+}
+
+func (r *runRecorder) Close() {
+	r.insert.Close()
+}
+
+// FindScrapeRun returns the last scrape run at or before the given time.
+func FindScrapeRun(ctx context.Context, db *sql.DB, at time.Time) (ScrapeRun, error) {
+	var run ScrapeRun
+	var runAt string
+	err := db.QueryRowContext(ctx, `
+		SELECT r.id, r.run_at, (SELECT COUNT(*) FROM run_listings l WHERE l.run_id = r.id)
+		FROM scrape_runs r
+		WHERE r.run_at <= ?
+		ORDER BY r.run_at DESC, r.id DESC LIMIT 1`, sqliteTime(at)).Scan(&run.ID, &runAt, &run.Listings)
+	if err != nil {
+		return ScrapeRun{}, notFound(err, "scrape run before", at.Format("2006-01-02"))
+	}
+	run.RunAt = parseSQLiteTime(runAt)
+	return run, nil
+}
+
+// asOfFilter returns the run a catalogue as of the given time is built from, and a
+// WHERE condition on coffee "c" selecting the coffees listed then. Before the first
+// recorded run it falls back to coffees whose first/last seen window covers the time.
+// Manual entries are included from the moment they were added.
+func asOfFilter(ctx context.Context, db *sql.DB, at time.Time) (ScrapeRun, string, []any, error) {
+	ts := sqliteTime(at)
+	manual := ` OR (c.url LIKE ? AND c.first_scraped_at <= ?)`
+	run, err := FindScrapeRun(ctx, db, at)
+	switch {
+	case err == nil:
+		// One IN over both lets SQLite start from the run's listings rather than
+		// scan the whole archive; the prefix range keeps to the url index
+		return run, `c.url IN (SELECT coffee_url FROM run_listings WHERE run_id = ?
+			UNION ALL SELECT url FROM coffee WHERE url >= ? AND url < ? AND first_scraped_at <= ?)`,
+			[]any{run.ID, models.ManualURLPrefix, prefixEnd(models.ManualURLPrefix), ts}, nil
+	case errors.Is(err, ErrNotFound):
+		return ScrapeRun{RunAt: at}, `((c.first_scraped_at <= ? AND c.last_seen_at >= ?)` + manual + `)`,
+			[]any{ts, ts, models.ManualURLPrefix + "%", ts}, nil
+	default:
+		return ScrapeRun{}, "", nil, err
+	}
+}
+
+// prefixEnd is the smallest string above every string starting with prefix,
+// for range scans: prefix with its last byte incremented.
+func prefixEnd(prefix string) string {
+	return prefix[:len(prefix)-1] + string(prefix[len(prefix)-1]+1)
+}
+
+// GetCoffeesAsOf rebuilds the catalogue as it looked at the given time, with the price
+// and stock status each coffee was listed at. Other fields are the current values.
+// Coffees on the ignore list are left out unless includeHidden is set.
+func GetCoffeesAsOf(ctx context.Context, db *sql.DB, at time.Time, includeHidden bool) ([]models.InventoryItem, ScrapeRun, error) {
+	run, where, args, err := asOfFilter(ctx, db, at)
+	if err != nil {
+		return nil, run, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+inventoryColumns+`,
+		       COALESCE(r.price, c.price, 0), COALESCE(r.stock_status, c.stock_status, '')
+		FROM `+inventoryFrom+`
+		LEFT JOIN run_listings r ON r.coffee_url = c.url AND r.run_id = ?
+		WHERE `+where+visibleUnless(includeHidden)+`
+		ORDER BY c.id DESC`, append([]any{run.ID}, args...)...)
+	if err != nil {
+		return nil, run, err
+	}
+	defer rows.Close()
+
+	var items []models.InventoryItem
+	for rows.Next() {
+		var price float64
+		var stock string
+		i, err := scanInventoryItem(rows, &price, &stock)
+		if err != nil {
+			return nil, run, err
+		}
+		i.Price, i.StockStatus = price, stock
+		items = append(items, i)
+	}
+	return items, run, rows.Err()
+}
+
+// GetCoffeeVectorsAsOf is GetCoffeeVectors for the catalogue as of the given time,
+// including coffees that have since gone inactive.
+func GetCoffeeVectorsAsOf(ctx context.Context, db *sql.DB, at time.Time, model string, includeHidden bool) ([]CoffeeVector, error) {
+	return SearchCoffeeVectors(ctx, db, model, at, SearchFilter{}, includeHidden)
+}
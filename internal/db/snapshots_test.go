@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestCoffeesAsOf(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	kenya := models.CoffeeItem{URL: "https://example.com/kenya", Name: "Kenya AA", Origin: "Kenya", Price: 8, StockStatus: "In Stock"}
+	guji := models.CoffeeItem{URL: "https://example.com/guji", Name: "Ethiopia Guji", Origin: "Ethiopia", Price: 7, StockStatus: "In Stock"}
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{kenya, guji}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+	// Pretend that run happened in March, then a later scrape dropped the Guji
+	// and raised the Kenya's price.
+	if _, err := database.Exec(`UPDATE scrape_runs SET run_at = '2025-03-10 12:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkAllAsInactive(ctx, database); err != nil {
+		t.Fatal(err)
+	}
+	kenya.Price, kenya.StockStatus = 9.5, "Low Stock"
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{kenya}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	march := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	items, run, err := GetCoffeesAsOf(ctx, database, march, false)
+	if err != nil {
+		t.Fatalf("GetCoffeesAsOf failed: %v", err)
+	}
+	if run.ID == 0 || run.Listings != 2 {
+		t.Errorf("Expected the March run with 2 listings, got %+v", run)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 coffees in March, got %d", len(items))
+	}
+	for _, i := range items {
+		if i.URL == kenya.URL && (i.Price != 8 || i.StockStatus != "In Stock") {
+			t.Errorf("Kenya as of March = $%.2f %q, want the March price and stock", i.Price, i.StockStatus)
+		}
+	}
+
+	// Today only the Kenya is listed
+	items, _, err = GetCoffeesAsOf(ctx, database, time.Now(), false)
+	if err != nil || len(items) != 1 || items[0].Price != 9.5 {
+		t.Errorf("GetCoffeesAsOf(now) = %+v, %v; want just the repriced Kenya", items, err)
+	}
+
+	// Before any recorded run nothing was listed
+	items, run, err = GetCoffeesAsOf(ctx, database, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false)
+	if err != nil || run.ID != 0 || len(items) != 0 {
+		t.Errorf("GetCoffeesAsOf(2024) = %d items, run %+v, %v; want none", len(items), run, err)
+	}
+}
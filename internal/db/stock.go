@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/variants"
+)
+
+const ledgerColumns = `
+	l.id, l.coffee_url, COALESCE(c.name, ''), l.kind, l.weight_lbs,
+	COALESCE(l.price_paid, 0), COALESCE(l.vendor, ''), l.occurred_at, COALESCE(l.notes, '')`
+
+func scanLedgerEntry(row rowScanner) (models.LedgerEntry, error) {
+	var e models.LedgerEntry
+	err := row.Scan(&e.ID, &e.CoffeeURL, &e.CoffeeName, &e.Kind, &e.WeightLbs,
+		&e.PricePaid, &e.Vendor, &e.OccurredAt, &e.Notes)
+	return e, err
+}
+
+// AddLedgerEntry records a purchase or withdrawal and returns its ID.
+// A zero OccurredAt defaults to now.
+func AddLedgerEntry(ctx context.Context, db *sql.DB, e models.LedgerEntry) (int64, error) {
+	return addLedgerEntry(ctx, db, e)
+}
+
+func addLedgerEntry(ctx context.Context, db querier, e models.LedgerEntry) (int64, error) {
+	switch e.Kind {
+	case models.LedgerPurchase, models.LedgerRoast, models.LedgerSample, models.LedgerGift:
+	default:
+		return 0, fmt.Errorf("unknown ledger entry kind %q", e.Kind)
+	}
+	if e.WeightLbs <= 0 {
+		return 0, fmt.Errorf("weight must be positive, got %.2f", e.WeightLbs)
+	}
+	if e.PricePaid < 0 {
+		return 0, fmt.Errorf("price paid cannot be negative")
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+
+	// Don't let withdrawals take a coffee below zero
+	if e.Kind != models.LedgerPurchase {
+		remaining, err := remainingStock(ctx, db, e.CoffeeURL)
+		if err != nil {
+			return 0, err
+		}
+		if e.WeightLbs > remaining+0.0001 {
+			return 0, fmt.Errorf("cannot withdraw %.2f lb, only %.2f lb left", e.WeightLbs, remaining)
+		}
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO inventory_ledger (coffee_url, kind, weight_lbs, price_paid, vendor, occurred_at, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.CoffeeURL,
+		e.Kind,
+		e.WeightLbs,
+		sql.NullFloat64{Float64: e.PricePaid, Valid: e.Kind == models.LedgerPurchase},
+		sql.NullString{String: e.Vendor, Valid: e.Vendor != ""},
+		e.OccurredAt.UTC(),
+		sql.NullString{String: e.Notes, Valid: e.Notes != ""},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add ledger entry: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// RemainingStock returns the current ledger balance for one coffee, in pounds.
+func RemainingStock(ctx context.Context, db *sql.DB, coffeeURL string) (float64, error) {
+	return remainingStock(ctx, db, coffeeURL)
+}
+
+func remainingStock(ctx context.Context, db querier, coffeeURL string) (float64, error) {
+	var remaining float64
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN kind = 'purchase' THEN weight_lbs ELSE -weight_lbs END), 0)
+		FROM inventory_ledger WHERE coffee_url = ?`, coffeeURL).Scan(&remaining)
+	return remaining, err
+}
+
+// ListLedgerEntries returns ledger entries in the order they happened.
+// If coffeeURL is empty, entries for every coffee are returned.
+func ListLedgerEntries(ctx context.Context, db *sql.DB, coffeeURL string) ([]models.LedgerEntry, error) {
+	query := `SELECT ` + ledgerColumns + `
+		FROM inventory_ledger l LEFT JOIN coffee c ON c.url = l.coffee_url`
+	var args []any
+	if coffeeURL != "" {
+		query += ` WHERE l.coffee_url = ?`
+		args = append(args, coffeeURL)
+	}
+	query += ` ORDER BY l.occurred_at, l.id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LedgerEntry
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteLedgerEntry removes a ledger entry, e.g. to correct a typo.
+func DeleteLedgerEntry(ctx context.Context, db *sql.DB, id int64) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM inventory_ledger WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetStockSummaries returns the ledger balance of every coffee that has entries,
+// ordered by name. Coffees that have been fully used up are only included if
+// includeEmpty is set.
+func GetStockSummaries(ctx context.Context, db *sql.DB, includeEmpty bool) ([]models.StockSummary, error) {
+	query := `
+		SELECT COALESCE(c.id, 0), l.coffee_url, COALESCE(c.name, l.coffee_url),
+		       SUM(CASE WHEN l.kind = 'purchase' THEN l.weight_lbs ELSE 0 END),
+		       SUM(CASE WHEN l.kind = 'purchase' THEN 0 ELSE l.weight_lbs END),
+		       SUM(COALESCE(l.price_paid, 0)),
+		       COALESCE(c.price, 0)
+		FROM inventory_ledger l LEFT JOIN coffee c ON c.url = l.coffee_url
+		GROUP BY l.coffee_url`
+	if !includeEmpty {
+		query += `
+		HAVING SUM(CASE WHEN l.kind = 'purchase' THEN l.weight_lbs ELSE -l.weight_lbs END) > 0.0001`
+	}
+	query += `
+		ORDER BY 3`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []models.StockSummary
+	for rows.Next() {
+		var s models.StockSummary
+		if err := rows.Scan(&s.CoffeeID, &s.CoffeeURL, &s.CoffeeName,
+			&s.PurchasedLbs, &s.WithdrawnLbs, &s.TotalPaid, &s.ListingPrice); err != nil {
+			return nil, err
+		}
+		if models.IsManualURL(s.CoffeeURL) {
+			s.PackLbs = 1 // Manual entries are priced per pound
+		} else {
+			s.PackLbs = variants.PackLbs(s.CoffeeName)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
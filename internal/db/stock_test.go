@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestStockLedger(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	url := "https://example.com/ethiopia"
+	if _, err := SaveData(ctx, database, []models.CoffeeItem{{URL: url, Name: "Ethiopia Guji – 5 lb", Price: 45}}); err != nil {
+		t.Fatalf("SaveData failed: %v", err)
+	}
+
+	entries := []models.LedgerEntry{
+		{CoffeeURL: url, Kind: models.LedgerPurchase, WeightLbs: 5, PricePaid: 35},
+		{CoffeeURL: url, Kind: models.LedgerPurchase, WeightLbs: 5, PricePaid: 45},
+		{CoffeeURL: url, Kind: models.LedgerRoast, WeightLbs: 1.5},
+		{CoffeeURL: url, Kind: models.LedgerSample, WeightLbs: 0.5},
+	}
+	for _, e := range entries {
+		if _, err := AddLedgerEntry(ctx, database, e); err != nil {
+			t.Fatalf("AddLedgerEntry(ctx, %s) failed: %v", e.Kind, err)
+		}
+	}
+	if _, err := AddLedgerEntry(ctx, database, models.LedgerEntry{CoffeeURL: url, Kind: models.LedgerGift, WeightLbs: 20}); err == nil {
+		t.Errorf("Expected overdrawing the ledger to fail")
+	}
+
+	summaries, err := GetStockSummaries(ctx, database, false)
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("GetStockSummaries = %+v, %v; want one summary", summaries, err)
+	}
+	s := summaries[0]
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"remaining", s.RemainingLbs(), 8},
+		{"cost per lb", s.CostPerLb(), 8},
+		{"cost basis", s.CostBasis(), 64},
+		{"estimated value", s.EstimatedValue(), 72},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s: got %.2f, want %.2f", c.name, c.got, c.want)
+		}
+	}
+
+	// Without a pack size in the name the listing price can't be turned into a value
+	s.PackLbs = 0
+	if v := s.EstimatedValue(); v != 0 {
+		t.Errorf("Estimated value without a pack size = %.2f, want 0", v)
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// GetVectorizer loads the stored model of the local embedder.
+func GetVectorizer(ctx context.Context, db *sql.DB) ([]byte, error) {
+	var model []byte
+	err := db.QueryRowContext(ctx, `SELECT model FROM vectorizer WHERE id = 1`).Scan(&model)
+	return model, notFound(err, "vectorizer", 1)
+}
+
+// SaveVectorizer stores a newly trained local embedder model, replacing any
+// previous one.
+func SaveVectorizer(ctx context.Context, db *sql.DB, model []byte, documents int) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO vectorizer (id, model, documents, trained_at) VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET model = excluded.model, documents = excluded.documents, trained_at = excluded.trained_at`,
+		model, documents, sqliteTime(time.Now()))
+	return err
+}
+
+// GetEmbeddingCorpus returns every coffee, active or not, to train the local
+// embedder on.
+func GetEmbeddingCorpus(ctx context.Context, db *sql.DB) ([]models.CoffeeItem, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+inventoryColumns+` FROM `+inventoryFrom+` ORDER BY c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var corpus []models.CoffeeItem
+	for rows.Next() {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		corpus = append(corpus, item.CoffeeItem)
+	}
+	return corpus, rows.Err()
+}
+
+// ClearEmbeddings drops every coffee vector and cached query made by a model,
+// for when the model is retrained and its old vectors no longer compare with
+// new ones. It returns the number of coffees that need embedding again.
+func ClearEmbeddings(ctx context.Context, db *sql.DB, model string) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM embeddings WHERE model = ?`, model)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM search_history WHERE model = ?`, model); err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
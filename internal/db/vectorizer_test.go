@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestVectorizer(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDB(t)
+
+	if _, err := GetVectorizer(ctx, database); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected no vectorizer yet, got %v", err)
+	}
+	if err := SaveVectorizer(ctx, database, []byte{1, 2}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveVectorizer(ctx, database, []byte{3}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if model, err := GetVectorizer(ctx, database); err != nil || len(model) != 1 || model[0] != 3 {
+		t.Errorf("GetVectorizer = %v, %v; want the latest model", model, err)
+	}
+
+	items := []models.CoffeeItem{{URL: "https://example.com/a", Name: "Kenya"}, {URL: "https://example.com/b", Name: "Brazil"}}
+	if _, err := SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	if corpus, err := GetEmbeddingCorpus(ctx, database); err != nil || len(corpus) != 2 {
+		t.Errorf("GetEmbeddingCorpus = %+v, %v", corpus, err)
+	}
+	if err := UpdateEmbedding(ctx, database, items[0].URL, "test/model", "", []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ClearEmbeddings(ctx, database, "test/model"); err != nil || n != 1 {
+		t.Errorf("ClearEmbeddings = %d, %v; want 1", n, err)
+	}
+	if pending, _ := staleCoffees(ctx, database, "test/model", false); len(pending) != 2 {
+		t.Errorf("Expected both coffees to need embedding, got %d", len(pending))
+	}
+}
@@ -0,0 +1,98 @@
+package embedder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// DefaultTemplate is the embedding document used when none is configured.
+const DefaultTemplate = "Coffee Name: {{.Name}}\nDescription: {{.Description}}"
+
+// Document builds the text embedded for a coffee from a text/template over
+// models.CoffeeItem. The rendered text is hashed with each vector, so editing
+// the template re-embeds exactly the coffees whose text it changes.
+// A nil Document uses DefaultTemplate.
+type Document struct {
+	tmpl *template.Template
+}
+
+var defaultDocument = mustDocument(DefaultTemplate)
+
+// NewDocument parses an embedding template; empty text gives the default. The
+// template is tried on an empty coffee so a misspelt field fails here rather
+// than halfway through an embed.
+func NewDocument(text string) (*Document, error) {
+	if strings.TrimSpace(text) == "" {
+		return defaultDocument, nil
+	}
+	tmpl, err := template.New("embed_template").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embed_template: %w", err)
+	}
+	d := &Document{tmpl: tmpl}
+	if _, err := d.Render(models.CoffeeItem{}); err != nil {
+		return nil, fmt.Errorf("invalid embed_template: %w", err)
+	}
+	return d, nil
+}
+
+func mustDocument(text string) *Document {
+	return &Document{tmpl: template.Must(template.New("embed_template").Parse(text))}
+}
+
+// Render returns the text to embed for a coffee.
+func (d *Document) Render(item models.CoffeeItem) (string, error) {
+	if d == nil {
+		d = defaultDocument
+	}
+	var b strings.Builder
+	if err := d.tmpl.Execute(&b, item); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Pending returns URL -> text for the coffees whose vector from model is
+// missing or was made from different text: new coffees, rewritten listings,
+// and everything the template now renders differently. With reembed it also
+// covers delisted coffees embedded by another model.
+func Pending(ctx context.Context, database *sql.DB, model string, doc *Document, reembed bool) (map[string]string, error) {
+	candidates, err := db.GetEmbeddingCandidates(ctx, database, model, reembed)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]string)
+	for _, c := range candidates {
+		text, err := doc.Render(c.CoffeeItem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", c.URL, err)
+		}
+		if c.Hash != db.ContentHash(text) {
+			pending[c.URL] = text
+		}
+	}
+	return pending, nil
+}
+
+// corpus renders every coffee, active or not, for training the local embedder.
+func corpus(ctx context.Context, database *sql.DB, doc *Document) ([]string, error) {
+	items, err := db.GetEmbeddingCorpus(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		text, err := doc.Render(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", item.URL, err)
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
@@ -0,0 +1,70 @@
+package embedder
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+func TestDocument(t *testing.T) {
+	kenya := models.CoffeeItem{URL: "https://example.com/kenya", Name: "Kenya AA", Origin: "Kenya", Price: 8.5, Description: "Blackcurrant"}
+
+	def, err := NewDocument("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := def.Render(kenya); text != "Coffee Name: Kenya AA\nDescription: Blackcurrant" {
+		t.Errorf("Default document = %q", text)
+	}
+
+	doc, err := NewDocument("{{.Name}} from {{.Origin}}, ${{printf \"%.2f\" .Price}}/lb. {{.Description}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := doc.Render(kenya); text != "Kenya AA from Kenya, $8.50/lb. Blackcurrant" {
+		t.Errorf("Render = %q", text)
+	}
+
+	for _, bad := range []string{"{{.Name", "{{.Altitude}}"} {
+		if _, err := NewDocument(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestTemplateChangeMarksStale(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/a", Name: "Kenya", Origin: "Kenya"},
+		{URL: "https://example.com/b", Name: "House Blend"},
+	}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeEmbedder{calls: 1} // Skip the rate-limited first call
+	if _, err := Run(ctx, database, fake, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := Pending(ctx, database, fake.Model(), nil, false); len(pending) != 0 {
+		t.Fatalf("Expected everything embedded, got %v", pending)
+	}
+
+	// Adding the origin only changes the text of coffees that have one
+	doc, err := NewDocument(DefaultTemplate + "{{if .Origin}}\nOrigin: {{.Origin}}{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := Pending(ctx, database, fake.Model(), doc, false)
+	if err != nil || len(pending) != 1 || pending[items[0].URL] == "" {
+		t.Errorf("Pending = %v, %v; want only Kenya", pending, err)
+	}
+}
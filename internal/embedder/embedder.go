@@ -2,57 +2,289 @@ package embedder
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"mspro-labs/brew-buddy/internal/ai"
 	"mspro-labs/brew-buddy/internal/db"
-	"database/sql"
+	"mspro-labs/brew-buddy/internal/vector"
 )
 
-// Run finds all coffees missing embeddings and processes them.
-func Run(ctx context.Context, database *sql.DB, aiClient *ai.Client) error {
+// Defaults for a zero Options.
+const (
+	DefaultBatchSize  = 32
+	DefaultWorkers    = 4
+	DefaultMaxRetries = 5
+)
+
+// Options tune how embedding requests are sent. Zero fields take the defaults.
+type Options struct {
+	BatchSize  int  // Texts per request
+	Workers    int  // Requests in flight at once
+	RPM        int  // Requests per minute, 0 = unlimited
+	TPM        int  // Estimated tokens per minute, 0 = unlimited
+	MaxRetries int  // Retries per batch after rate limits or server errors, -1 for none
+	Reembed    bool // Also redo delisted coffees embedded by another model, then drop the old vectors
+
+	Document *Document       // Builds the text embedded for each coffee; nil uses DefaultTemplate
+	Encoding vector.Encoding // How vectors are stored, default vector.Float32
+}
+
+// Failure is a coffee that couldn't be embedded.
+type Failure struct {
+	URL string
+	Err error
+}
+
+// Summary reports what a Run did.
+type Summary struct {
+	Pending   int // Coffees that needed embedding
+	Embedded  int
+	Reencoded int   // Stored vectors converted to Options.Encoding without embedding again
+	Dropped   int64 // Old vectors from other models removed by a re-embed
+	Requests  int   // Embedding requests sent, including retries
+	Retries   int
+	Failures  []Failure
+}
+
+// batch is a slice of the work, sent as one request.
+type batch struct {
+	urls  []string
+	texts []string
+}
+
+// Run finds all coffees missing embeddings from the client's model, or whose
+// text changed since they were embedded, and processes them in batches, spreading requests over a few workers within the
+// rate limits.
+func Run(ctx context.Context, database *sql.DB, aiClient ai.Embedder, opts Options) (Summary, error) {
+	opts = withDefaults(opts)
+	var summary Summary
+	model := aiClient.Model()
+
+	// 0. Bring stored vectors to the configured encoding
+	reencoded, err := db.ReencodeEmbeddings(ctx, database, model, opts.Encoding)
+	if err != nil {
+		return summary, fmt.Errorf("failed to re-encode vectors: %w", err)
+	}
+	summary.Reencoded = reencoded
+	if reencoded > 0 {
+		log.Printf("🗜️ Re-encoded %d stored vector(s) as %s.", reencoded, opts.Encoding)
+	}
+
 	// 1. Find work to do
-	targets, err := db.GetUnembeddedCoffees(database)
+	targets, err := Pending(ctx, database, model, opts.Document, opts.Reembed)
 	if err != nil {
-		return err
+		return summary, err
 	}
+	summary.Pending = len(targets)
 
 	if len(targets) == 0 {
-		log.Println("✨ All active coffees are already embedded.")
-		return nil
+		log.Printf("✨ All coffees are already embedded with %s.", model)
+		return summary, dropOthers(ctx, database, model, opts, &summary)
 	}
-	log.Printf("Found %d new coffees to embed...", len(targets))
+	log.Printf("Found %d coffees to embed with %s...", len(targets), model)
 
-	// 2. Process loop
-	count := 0
-	for url, textToEmbed := range targets {
-		// Just show the start of the name for cleaner logs
-		shortName := textToEmbed
-		if len(shortName) > 30 {
-			shortName = shortName[:30] + "..."
+	// 2. Cut it into batches (in URL order, so reruns behave the same)
+	urls := make([]string, 0, len(targets))
+	for url := range targets {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	var batches []batch
+	for start := 0; start < len(urls); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(urls))
+		b := batch{urls: urls[start:end]}
+		for _, url := range b.urls {
+			b.texts = append(b.texts, targets[url])
 		}
-		log.Printf("Embedding: %s", shortName)
+		batches = append(batches, b)
+	}
 
-		// Generate vector
-		blob, _, err := aiClient.EmbedString(ctx, textToEmbed)
+	// 3. Process them with a bounded pool of workers
+	w := &worker{
+		database: database,
+		client:   aiClient,
+		model:    model,
+		opts:     opts,
+		requests: newTokenBucket(opts.RPM),
+		tokens:   newTokenBucket(opts.TPM),
+		summary:  &summary,
+	}
+	queue := make(chan batch)
+	var wg sync.WaitGroup
+	for range min(opts.Workers, len(batches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range queue {
+				w.process(ctx, b)
+			}
+		}()
+	}
+feed:
+	for _, b := range batches {
+		select {
+		case queue <- b:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	// 4. Report
+	log.Printf("🎉 Embedded %d of %d coffees in %d request(s), %d retried.",
+		summary.Embedded, summary.Pending, summary.Requests, summary.Retries)
+	if len(summary.Failures) > 0 {
+		log.Printf("⚠️ %d coffee(s) failed:", len(summary.Failures))
+		for i, f := range summary.Failures {
+			if i == 10 {
+				log.Printf("   ...and %d more", len(summary.Failures)-i)
+				break
+			}
+			log.Printf("   %s: %v", f.URL, f.Err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	return summary, dropOthers(ctx, database, model, opts, &summary)
+}
+
+// dropOthers finishes a re-embed by deleting the vectors the new model replaced.
+func dropOthers(ctx context.Context, database *sql.DB, model string, opts Options, summary *Summary) error {
+	if !opts.Reembed {
+		return nil
+	}
+	dropped, err := db.DropOtherEmbeddings(ctx, database, model)
+	if err != nil {
+		return fmt.Errorf("failed to drop old vectors: %w", err)
+	}
+	summary.Dropped = dropped
+	if dropped > 0 {
+		log.Printf("🧹 Dropped %d vector(s) from other models.", dropped)
+	}
+	return nil
+}
+
+func withDefaults(opts Options) Options {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Encoding == "" {
+		opts.Encoding = vector.Float32
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	return opts
+}
+
+// worker holds what the goroutines of one Run share.
+type worker struct {
+	database *sql.DB
+	client   ai.Embedder
+	model    string
+	opts     Options
+	requests *tokenBucket
+	tokens   *tokenBucket
+
+	mu      sync.Mutex
+	summary *Summary
+}
+
+// estimateTokens is a rough count for the TPM limit: about four characters a token.
+func estimateTokens(texts []string) float64 {
+	n := 0
+	for _, t := range texts {
+		n += len(t)/4 + 1
+	}
+	return float64(n)
+}
+
+// process embeds and saves one batch, retrying what's worth retrying.
+func (w *worker) process(ctx context.Context, b batch) {
+	vectors, err := w.embed(ctx, b)
+	if err != nil {
+		w.fail(b.urls, err)
+		return
+	}
+
+	embedded := 0
+	for i, url := range b.urls {
+		blob, err := encodeVector(vectors[i], w.opts.Encoding)
+		if err == nil {
+			err = db.UpdateEmbedding(ctx, w.database, url, w.model, db.ContentHash(b.texts[i]), blob)
+		}
 		if err != nil {
-			log.Printf("⚠️ Error embedding item: %v", err)
-			time.Sleep(1 * time.Second) // Backoff on error
+			w.fail([]string{url}, err)
 			continue
 		}
+		embedded++
+	}
+	w.mu.Lock()
+	w.summary.Embedded += embedded
+	done := w.summary.Embedded
+	w.mu.Unlock()
+	log.Printf("Embedded %d/%d", done, w.summary.Pending)
+}
 
-		// Save vector
-		if err := db.UpdateEmbedding(database, url, blob); err != nil {
-			log.Printf("⚠️ Error saving to DB: %v", err)
-			continue
+// embed sends one batch, waiting for the rate limits and backing off on
+// retryable errors.
+func (w *worker) embed(ctx context.Context, b batch) ([][]float32, error) {
+	cost := estimateTokens(b.texts)
+	for attempt := 0; ; attempt++ {
+		if err := w.requests.Wait(ctx, 1); err != nil {
+			return nil, err
+		}
+		if err := w.tokens.Wait(ctx, cost); err != nil {
+			return nil, err
+		}
+
+		w.mu.Lock()
+		w.summary.Requests++
+		w.mu.Unlock()
+		vectors, err := ai.EmbedBatch(ctx, w.client, b.texts)
+		if err == nil {
+			return vectors, nil
 		}
 
-		count++
-		// Rate limit for free tier safety (approx 60 RPM max)
-		time.Sleep(1 * time.Second)
+		retry, after := ai.RetryHint(err)
+		if !retry || attempt >= w.opts.MaxRetries {
+			return nil, err
+		}
+		delay := ai.Backoff(attempt, after)
+		log.Printf("⏳ Embedding request failed (%v); retrying in %s", err, delay.Round(100*time.Millisecond))
+		w.mu.Lock()
+		w.summary.Retries++
+		w.mu.Unlock()
+		if err := ai.Sleep(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	log.Printf("🎉 Successfully embedded %d items.", count)
-	return nil
+func (w *worker) fail(urls []string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, url := range urls {
+		w.summary.Failures = append(w.summary.Failures, Failure{URL: url, Err: err})
+	}
+}
+
+// encodeVector checks and packs a vector for the database.
+func encodeVector(v []float32, enc vector.Encoding) ([]byte, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("AI returned empty embedding")
+	}
+	return vector.Encode(v, enc)
 }
@@ -0,0 +1,191 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// fakeEmbedder rate-limits its first request and refuses texts containing "poison".
+type fakeEmbedder struct {
+	model    string
+	mu       sync.Mutex
+	calls    int
+	maxBatch int
+}
+
+func (f *fakeEmbedder) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	return nil, nil, errors.New("expected batches")
+}
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	f.mu.Lock()
+	f.calls++
+	first := f.calls == 1
+	f.maxBatch = max(f.maxBatch, len(texts))
+	f.mu.Unlock()
+
+	if first {
+		return nil, &ai.APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down", RetryAfter: time.Millisecond}
+	}
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		if strings.Contains(t, "poison") {
+			return nil, &ai.APIError{StatusCode: http.StatusBadRequest, Message: "bad input"}
+		}
+		vectors[i] = []float32{float32(len(t)), 1}
+	}
+	return vectors, nil
+}
+
+func (f *fakeEmbedder) Model() string {
+	if f.model == "" {
+		return "fake/v1"
+	}
+	return f.model
+}
+
+func (f *fakeEmbedder) Close() {}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	var items []models.CoffeeItem
+	for i := range 5 {
+		items = append(items, models.CoffeeItem{URL: fmt.Sprintf("https://example.com/%d", i), Name: fmt.Sprintf("Coffee %d", i)})
+	}
+	items[4].Name = "poison"
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeEmbedder{}
+	summary, err := Run(ctx, database, fake, Options{BatchSize: 2, Workers: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// 3 batches plus one retry; the last batch, with the bad text, fails for good
+	if summary.Pending != 5 || summary.Embedded != 4 || summary.Requests != 4 || summary.Retries != 1 {
+		t.Errorf("Summary = %+v", summary)
+	}
+	if len(summary.Failures) != 1 || summary.Failures[0].URL != items[4].URL || fake.maxBatch != 2 {
+		t.Errorf("Expected only the bad text to fail, got %+v (max batch %d)", summary.Failures, fake.maxBatch)
+	}
+	if pending, _ := Pending(ctx, database, fake.Model(), nil, false); len(pending) != 1 {
+		t.Errorf("Expected 1 coffee still unembedded, got %d", len(pending))
+	}
+}
+
+func TestReembed(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	items := []models.CoffeeItem{{URL: "https://example.com/a", Name: "Kenya"}, {URL: "https://example.com/b", Name: "Brazil"}}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(ctx, database, &fakeEmbedder{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	// Once Brazil is delisted a plain embed would never move it to the new model
+	if _, err := database.ExecContext(ctx, `UPDATE coffee SET is_active = 0 WHERE url = ?`, items[1].URL); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := Run(ctx, database, &fakeEmbedder{model: "fake/v2"}, Options{Reembed: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if summary.Embedded != 2 || summary.Dropped != 2 {
+		t.Errorf("Summary = %+v; want both coffees moved and their old vectors dropped", summary)
+	}
+	if counts, _ := db.ListEmbeddingModels(ctx, database); len(counts) != 1 || counts[0].Model != "fake/v2" {
+		t.Errorf("Expected only fake/v2 vectors left, got %+v", counts)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(60) // One a second, a minute's worth to start
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	if wait := b.reserve(60); wait != 0 {
+		t.Errorf("A full bucket made us wait %s", wait)
+	}
+	if wait := b.reserve(2); wait != 2*time.Second {
+		t.Errorf("An empty bucket asked for %s, want 2s", wait)
+	}
+	now = now.Add(3 * time.Second)
+	if wait := b.reserve(2); wait != 0 {
+		t.Errorf("A refilled bucket made us wait %s", wait)
+	}
+	// More than a minute's worth is capped rather than waiting forever
+	now = now.Add(time.Hour)
+	if wait := b.reserve(1000); wait != 0 {
+		t.Errorf("An oversized request waited %s", wait)
+	}
+	if newTokenBucket(0).Wait(context.Background(), 1e9) != nil {
+		t.Error("An unlimited bucket should never wait")
+	}
+}
+
+func TestReencode(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	items := []models.CoffeeItem{{URL: "https://example.com/a", Name: "Kenya"}, {URL: "https://example.com/b", Name: "Brazil"}}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+	// One vector stored raw, the way they were before encodings
+	if err := db.UpdateEmbedding(ctx, database, items[1].URL, "fake/v1", "", []byte{0, 0, 0x40, 0x40, 0, 0, 0x80, 0x40}); err != nil {
+		t.Fatal(err)
+	}
+	if summary, err := Run(ctx, database, &fakeEmbedder{}, Options{}); err != nil || summary.Reencoded != 1 {
+		t.Fatalf("Run = %+v, %v; want the raw vector re-encoded", summary, err)
+	}
+
+	// Switching to int8 converts the stored vectors without asking for new ones
+	fake := &fakeEmbedder{}
+	summary, err := Run(ctx, database, fake, Options{Encoding: vector.Int8})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if summary.Reencoded != 2 || fake.calls != 0 {
+		t.Errorf("Summary = %+v after %d calls; want both vectors re-encoded and nothing embedded", summary, fake.calls)
+	}
+	for _, item := range items {
+		stored, _ := db.GetCoffeeEmbeddings(ctx, database, item.URL)
+		if len(stored) != 1 || stored[0].Encoding != vector.Int8 || stored[0].Dims != 2 {
+			t.Errorf("Vectors of %s = %+v, want one int8 vector of 2 dims", item.Name, stored)
+		}
+	}
+	if summary, _ := Run(ctx, database, fake, Options{Encoding: vector.Int8}); summary.Reencoded != 0 {
+		t.Errorf("Second run re-encoded %d vectors", summary.Reencoded)
+	}
+}
@@ -0,0 +1,60 @@
+package embedder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// LoadLocalModel returns the stored model of the local embedder. If there is
+// none yet it trains one on the catalogue and stores it, so every later vector
+// is made with the same model. An empty catalogue gives an untrained model
+// that isn't stored.
+func LoadLocalModel(ctx context.Context, database *sql.DB, doc *Document) (*ai.LocalModel, error) {
+	blob, err := db.GetVectorizer(ctx, database)
+	if err == nil {
+		model := &ai.LocalModel{}
+		return model, model.UnmarshalBinary(blob)
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+	model, err := trainLocal(ctx, database, doc)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🧮 Trained the local embedder on %d coffees.", model.Documents)
+	return model, nil
+}
+
+// RetrainLocal trains a new local model on the current catalogue and clears
+// the vectors made with the old one, so 'embed' can redo them. It returns the
+// number of coffees cleared.
+func RetrainLocal(ctx context.Context, database *sql.DB, doc *Document) (*ai.LocalModel, int64, error) {
+	model, err := trainLocal(ctx, database, doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	cleared, err := db.ClearEmbeddings(ctx, database, model.Name())
+	return model, cleared, err
+}
+
+func trainLocal(ctx context.Context, database *sql.DB, doc *Document) (*ai.LocalModel, error) {
+	texts, err := corpus(ctx, database, doc)
+	if err != nil {
+		return nil, err
+	}
+	model := ai.TrainLocal(texts, ai.DefaultLocalDims)
+	if len(texts) == 0 {
+		return model, nil
+	}
+	blob, err := model.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return model, db.SaveVectorizer(ctx, database, blob, len(texts))
+}
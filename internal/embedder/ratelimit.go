@@ -0,0 +1,69 @@
+package embedder
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/ai"
+)
+
+// tokenBucket is a rate limiter configured per minute: it starts full, holds
+// at most a minute's worth, and refills continuously. A nil bucket never waits.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	perSec   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// newTokenBucket returns a bucket allowing perMinute units a minute, or nil for no limit.
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// reserve takes n units if they're available and otherwise says how long
+// until they will be. Requests larger than the bucket are capped to its size
+// so they can't wait forever.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+
+	n = min(n, b.capacity)
+	if b.tokens >= n {
+		b.tokens -= n
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.perSec * float64(time.Second))
+}
+
+// Wait blocks until n units are available and takes them.
+func (b *tokenBucket) Wait(ctx context.Context, n float64) error {
+	if b == nil {
+		return nil
+	}
+	for {
+		wait := b.reserve(n)
+		if wait <= 0 {
+			return nil
+		}
+		if err := ai.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// Processes are the values the model may give for "process", with how each
+// is stored. "unknown" stores nothing.
+var Processes = map[string]string{
+	"washed":       "Washed",
+	"natural":      "Natural",
+	"honey":        "Honey",
+	"anaerobic":    "Anaerobic",
+	"wet-hulled":   "Wet Hulled",
+	"experimental": "Experimental",
+	"unknown":      "",
+}
+
+// Limits on a reply, generous enough for any real listing.
+const (
+	maxVarietals = 6
+	maxNotes     = 8
+	maxItemLen   = 40 // Per varietal or tasting note
+	maxRegionLen = 80 // Also the limit for the producer
+	minAltitude  = 300
+	maxAltitude  = 3000
+)
+
+// Attributes are the fields extracted from a listing, exactly as the model
+// must return them.
+type Attributes struct {
+	Process      string   `json:"process"`
+	Varietals    []string `json:"varietals"`
+	Region       string   `json:"region"`
+	Producer     string   `json:"producer"`
+	AltitudeMin  int      `json:"altitude_min_m"`
+	AltitudeMax  int      `json:"altitude_max_m"`
+	TastingNotes []string `json:"tasting_notes"`
+}
+
+// Schema is the JSON Schema replies must follow. It is sent with the prompt
+// and enforced by Parse.
+const Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["process", "varietals", "region", "producer", "altitude_min_m", "altitude_max_m", "tasting_notes"],
+  "properties": {
+    "process": {"enum": ["washed", "natural", "honey", "anaerobic", "wet-hulled", "experimental", "unknown"]},
+    "varietals": {"type": "array", "maxItems": 6, "items": {"type": "string", "maxLength": 40}},
+    "region": {"type": "string", "maxLength": 80},
+    "producer": {"type": "string", "maxLength": 80},
+    "altitude_min_m": {"type": "integer", "description": "0 if not stated, otherwise 300-3000"},
+    "altitude_max_m": {"type": "integer", "description": "0 if not stated, otherwise 300-3000"},
+    "tasting_notes": {"type": "array", "maxItems": 8, "items": {"type": "string", "maxLength": 40}}
+  }
+}`
+
+// Parse decodes and validates a model reply. Anything that isn't a single
+// object with exactly the schema's fields and sensible values is rejected,
+// so a confused model can't write junk into the catalogue.
+func Parse(reply string) (Attributes, error) {
+	data := []byte(stripFences(reply))
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return Attributes{}, fmt.Errorf("reply is not a JSON object: %w", err)
+	}
+	for _, k := range []string{"process", "varietals", "region", "producer", "altitude_min_m", "altitude_max_m", "tasting_notes"} {
+		if _, ok := keys[k]; !ok {
+			return Attributes{}, fmt.Errorf("reply is missing %q", k)
+		}
+	}
+
+	var a Attributes
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return Attributes{}, fmt.Errorf("reply doesn't match the schema: %w", err)
+	}
+	if err := a.validate(); err != nil {
+		return Attributes{}, err
+	}
+	return a.normalize(), nil
+}
+
+// stripFences removes a Markdown code fence some models wrap JSON in.
+func stripFences(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:] // Drop the language tag
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+func (a Attributes) validate() error {
+	if _, ok := Processes[strings.ToLower(a.Process)]; !ok {
+		return fmt.Errorf("unknown process %q", a.Process)
+	}
+	if len(a.Varietals) > maxVarietals {
+		return fmt.Errorf("%d varietals, at most %d allowed", len(a.Varietals), maxVarietals)
+	}
+	if len(a.TastingNotes) > maxNotes {
+		return fmt.Errorf("%d tasting notes, at most %d allowed", len(a.TastingNotes), maxNotes)
+	}
+	for _, s := range slices.Concat(a.Varietals, a.TastingNotes) {
+		if len(s) > maxItemLen {
+			return fmt.Errorf("%q is longer than %d characters", s, maxItemLen)
+		}
+	}
+	if len(a.Region) > maxRegionLen {
+		return fmt.Errorf("region is longer than %d characters", maxRegionLen)
+	}
+	if len(a.Producer) > maxRegionLen {
+		return fmt.Errorf("producer is longer than %d characters", maxRegionLen)
+	}
+	for _, alt := range []int{a.AltitudeMin, a.AltitudeMax} {
+		if alt != 0 && (alt < minAltitude || alt > maxAltitude) {
+			return fmt.Errorf("altitude %dm is outside %d-%dm", alt, minAltitude, maxAltitude)
+		}
+	}
+	if a.AltitudeMin > 0 && a.AltitudeMax > 0 && a.AltitudeMin > a.AltitudeMax {
+		return fmt.Errorf("altitude range %d-%dm is backwards", a.AltitudeMin, a.AltitudeMax)
+	}
+	return nil
+}
+
+// normalize tidies a valid reply: trimmed, de-duplicated lists and a single
+// stated altitude used for both ends of the range.
+func (a Attributes) normalize() Attributes {
+	a.Process = strings.ToLower(a.Process)
+	a.Region = strings.TrimSpace(a.Region)
+	a.Producer = strings.TrimSpace(a.Producer)
+	a.Varietals = tidy(a.Varietals)
+	a.TastingNotes = tidy(a.TastingNotes)
+	if a.AltitudeMin == 0 {
+		a.AltitudeMin = a.AltitudeMax
+	}
+	if a.AltitudeMax == 0 {
+		a.AltitudeMax = a.AltitudeMin
+	}
+	return a
+}
+
+func tidy(list []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[strings.ToLower(s)] {
+			continue
+		}
+		seen[strings.ToLower(s)] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// Enrichment converts the attributes to the columns stored on a coffee.
+func (a Attributes) Enrichment() db.Enrichment {
+	return db.Enrichment{
+		Processing:   Processes[a.Process],
+		Region:       a.Region,
+		Producer:     a.Producer,
+		TastingNotes: strings.Join(a.TastingNotes, ", "),
+		Varietals:    strings.Join(a.Varietals, ", "),
+		AltitudeMin:  a.AltitudeMin,
+		AltitudeMax:  a.AltitudeMax,
+	}
+}
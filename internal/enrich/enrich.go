@@ -0,0 +1,190 @@
+// Package enrich extracts structured attributes (process, varietals, region,
+// producer, altitude, tasting notes) from free-text coffee descriptions with a
+// generative model. Replies must match Schema; results are cached by the hash
+// of the text they came from, so unchanged listings are never sent twice.
+package enrich
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// promptVersion is part of every cache key. Bump it when the prompt or schema
+// changes so old answers are asked again.
+const promptVersion = "enrich/v1"
+
+// DefaultMaxRetries is how often a rate-limited or failed request is retried.
+const DefaultMaxRetries = 5
+
+const systemPrompt = `You extract facts about a single-origin green coffee from its listing.
+Reply with one JSON object matching this JSON Schema and nothing else:
+` + Schema + `
+Rules:
+- Only use what the listing states. Never guess; use "unknown", "", 0 or [] instead.
+- process is how the coffee was processed; "unknown" if it isn't stated.
+- region is the growing region, farm area or washing station locality, not the country.
+- producer is the farm, estate, washing station or cooperative the coffee came from, e.g. "Finca El Paraíso"; "" if it isn't named.
+- Altitudes are in metres above sea level; convert feet (1 ft = 0.3048 m). A single altitude goes in both fields.
+- tasting_notes are short flavour descriptors from the cupping notes, e.g. "blackcurrant", "milk chocolate".`
+
+// Options control a Run.
+type Options struct {
+	RPM        int  // Requests per minute, 0 = unlimited
+	MaxRetries int  // Retries per coffee after rate limits or server errors, 0 takes the default, -1 for none
+	Redo       bool // Ask again even where the stored attributes or cache are current
+	Limit      int  // Stop after this many coffees, 0 = all
+}
+
+// Failure is a coffee whose attributes couldn't be extracted.
+type Failure struct {
+	URL string
+	Err error
+}
+
+// Summary reports what a Run did.
+type Summary struct {
+	Pending   int // Coffees whose attributes were missing or out of date
+	Enriched  int
+	FromCache int // Of those enriched, how many reused an earlier answer
+	Requests  int // Model requests sent, including retries
+	Failures  []Failure
+}
+
+// Input is the text sent to the model for a coffee. Name and origin give the
+// description context; the origin also stops the model reporting the country
+// as the region.
+func Input(c db.EnrichCandidate) string {
+	return fmt.Sprintf("Name: %s\nOrigin: %s\nDescription: %s", c.Name, c.Origin, c.Description)
+}
+
+// Hash is the cache key for an input, covering the prompt version too.
+func Hash(input string) string {
+	return db.ContentHash(promptVersion + "\n" + input)
+}
+
+// Run extracts attributes for every active coffee with a description whose
+// stored attributes are missing or came from different text. Coffees are done
+// one at a time, paced to the RPM limit.
+func Run(ctx context.Context, database *sql.DB, gen ai.Generator, opts Options) (Summary, error) {
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	var summary Summary
+	candidates, err := db.GetEnrichCandidates(ctx, database)
+	if err != nil {
+		return summary, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+
+	r := runner{database: database, gen: gen, opts: opts, summary: &summary}
+	if opts.RPM > 0 {
+		r.interval = time.Minute / time.Duration(opts.RPM)
+	}
+	for _, c := range candidates {
+		if strings.TrimSpace(c.Description) == "" {
+			continue // Nothing to extract from
+		}
+		input := Input(c)
+		hash := Hash(input)
+		if c.Hash == hash && !opts.Redo {
+			continue
+		}
+		if opts.Limit > 0 && summary.Pending >= opts.Limit {
+			break
+		}
+		summary.Pending++
+
+		if err := r.enrich(ctx, c.URL, input, hash); err != nil {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			summary.Failures = append(summary.Failures, Failure{URL: c.URL, Err: err})
+			continue
+		}
+		summary.Enriched++
+	}
+	return summary, nil
+}
+
+type runner struct {
+	database *sql.DB
+	gen      ai.Generator
+	opts     Options
+	summary  *Summary
+	interval time.Duration // Minimum gap between requests
+	last     time.Time
+}
+
+// enrich fills in one coffee, from the cache when the same text was answered before.
+func (r *runner) enrich(ctx context.Context, url, input, hash string) error {
+	model := r.gen.Model()
+	var attrs Attributes
+	cached, err := db.GetEnrichment(ctx, r.database, hash, model)
+	switch {
+	case err == nil && !r.opts.Redo:
+		if attrs, err = Parse(cached); err != nil {
+			return fmt.Errorf("cached attributes are invalid: %w", err)
+		}
+		r.summary.FromCache++
+	case err == nil || errors.Is(err, db.ErrNotFound):
+		if attrs, err = r.extract(ctx, input); err != nil {
+			return err
+		}
+		data, err := json.Marshal(attrs)
+		if err != nil {
+			return err
+		}
+		if err := db.SaveEnrichment(ctx, r.database, hash, model, string(data)); err != nil {
+			return fmt.Errorf("failed to cache attributes: %w", err)
+		}
+	default:
+		return err
+	}
+	return db.ApplyEnrichment(ctx, r.database, url, hash, attrs.Enrichment())
+}
+
+// extract asks the model, retrying rate limits and server errors with backoff.
+// A reply that fails validation is not retried: temperature 0 would only
+// repeat it.
+func (r *runner) extract(ctx context.Context, input string) (Attributes, error) {
+	prompt := ai.Prompt{System: systemPrompt, User: input, JSON: true}
+	for attempt := 0; ; attempt++ {
+		if err := r.pace(ctx); err != nil {
+			return Attributes{}, err
+		}
+		r.summary.Requests++
+		reply, err := r.gen.Generate(ctx, prompt)
+		if err == nil {
+			return Parse(reply)
+		}
+
+		retry, after := ai.RetryHint(err)
+		if !retry || r.opts.MaxRetries < 0 || attempt >= r.opts.MaxRetries {
+			return Attributes{}, err
+		}
+		delay := ai.Backoff(attempt, after)
+		log.Printf("⏳ Extraction request failed (%v); retrying in %s", err, delay.Round(100*time.Millisecond))
+		if err := ai.Sleep(ctx, delay); err != nil {
+			return Attributes{}, err
+		}
+	}
+}
+
+// pace waits until the next request fits within the RPM limit.
+func (r *runner) pace(ctx context.Context) error {
+	if wait := time.Until(r.last.Add(r.interval)); wait > 0 {
+		if err := ai.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+	r.last = time.Now()
+	return nil
+}
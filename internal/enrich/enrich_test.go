@@ -0,0 +1,144 @@
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// fakeGenerator answers from canned replies keyed by a word in the prompt,
+// rate-limiting its first request.
+type fakeGenerator struct {
+	replies map[string]string
+	calls   int
+}
+
+func (f *fakeGenerator) Generate(ctx context.Context, p ai.Prompt) (string, error) {
+	f.calls++
+	if f.calls == 1 {
+		return "", &ai.APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down", RetryAfter: time.Millisecond}
+	}
+	if !p.JSON || !strings.Contains(p.System, Schema) {
+		return "", &ai.APIError{StatusCode: http.StatusBadRequest, Message: "expected a JSON prompt with the schema"}
+	}
+	for word, reply := range f.replies {
+		if strings.Contains(p.User, word) {
+			return reply, nil
+		}
+	}
+	return `{"process": "unknown", "varietals": [], "region": "", "producer": "", "altitude_min_m": 0, "altitude_max_m": 0, "tasting_notes": []}`, nil
+}
+
+func (f *fakeGenerator) Model() string { return "fake/chat" }
+func (f *fakeGenerator) Close()        {}
+
+func TestParse(t *testing.T) {
+	a, err := Parse("```json\n" + `{"process": "Washed", "varietals": ["SL28", " SL34", "sl28"], "region": " Nyeri ", "producer": " Gitura Washing Station",
+		"altitude_min_m": 0, "altitude_max_m": 1800, "tasting_notes": ["blackcurrant", "tomato"]}` + "\n```")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	e := a.Enrichment()
+	want := db.Enrichment{Processing: "Washed", Region: "Nyeri", TastingNotes: "blackcurrant, tomato",
+		Varietals: "SL28, SL34", Producer: "Gitura Washing Station", AltitudeMin: 1800, AltitudeMax: 1800}
+	if e != want {
+		t.Errorf("Enrichment = %+v, want %+v", e, want)
+	}
+
+	valid := `"varietals": [], "region": "", "producer": "", "altitude_min_m": 0, "altitude_max_m": 0, "tasting_notes": []`
+	for name, reply := range map[string]string{
+		"not JSON":        "The process is washed.",
+		"missing key":     `{"process": "washed", "varietals": [], "region": "", "producer": "", "altitude_min_m": 0, "tasting_notes": []}`,
+		"extra key":       `{"process": "washed", "country": "Kenya", ` + valid + `}`,
+		"unknown process": `{"process": "fermented", ` + valid + `}`,
+		"wrong type":      `{"process": "washed", "varietals": "SL28", "region": "", "producer": "", "altitude_min_m": 0, "altitude_max_m": 0, "tasting_notes": []}`,
+		"feet":            `{"process": "washed", "varietals": [], "region": "", "producer": "", "altitude_min_m": 5900, "altitude_max_m": 6200, "tasting_notes": []}`,
+		"backwards":       `{"process": "washed", "varietals": [], "region": "", "producer": "", "altitude_min_m": 2000, "altitude_max_m": 1500, "tasting_notes": []}`,
+		"too many notes":  `{"process": "washed", "varietals": [], "region": "", "producer": "", "altitude_min_m": 0, "altitude_max_m": 0, "tasting_notes": ["a","b","c","d","e","f","g","h","i"]}`,
+	} {
+		if _, err := Parse(reply); err == nil {
+			t.Errorf("%s: expected %q to be rejected", name, reply)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+
+	kenya := "Washed SL28 from Nyeri at 1800m. Blackcurrant and tomato."
+	items := []models.CoffeeItem{
+		{URL: "https://example.com/a", Name: "Kenya AA", Origin: "Kenya", Description: kenya},
+		{URL: "https://example.com/a-sample", Name: "Kenya AA", Origin: "Kenya", Description: kenya},
+		{URL: "https://example.com/b", Name: "Kenya AB", Origin: "Kenya", Region: "Kirinyaga", Description: "Dried on raised beds, blackcurrant"},
+		{URL: "https://example.com/c", Name: "Mystery", Origin: "Brazil", Description: "garbled"},
+		{URL: "https://example.com/d", Name: "No description"},
+	}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeGenerator{replies: map[string]string{
+		"SL28":        `{"process": "washed", "varietals": ["SL28"], "region": "Nyeri", "producer": "", "altitude_min_m": 1800, "altitude_max_m": 1800, "tasting_notes": ["blackcurrant", "tomato"]}`,
+		"raised beds": `{"process": "natural", "varietals": [], "region": "Nyeri", "producer": "", "altitude_min_m": 0, "altitude_max_m": 0, "tasting_notes": ["blackcurrant"]}`,
+		"garbled":     `{"process": "washed", "region": "Minas Gerais"}`,
+	}}
+	summary, err := Run(ctx, database, fake, Options{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Identical text is asked once; the rate-limited request is retried
+	if summary.Pending != 4 || summary.Enriched != 3 || summary.FromCache != 1 || summary.Requests != 4 {
+		t.Errorf("Summary = %+v, want 4 pending, 3 enriched (1 cached) in 4 requests", summary)
+	}
+	if len(summary.Failures) != 1 || summary.Failures[0].URL != "https://example.com/c" {
+		t.Errorf("Failures = %+v, want only the invalid reply", summary.Failures)
+	}
+
+	a, err := db.FindCoffee(ctx, database, "https://example.com/a-sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Processing != "Washed" || a.Region != "Nyeri" || a.Varietals != "SL28" || a.AltitudeMin != 1800 || a.TastingNotes != "blackcurrant, tomato" {
+		t.Errorf("Enriched coffee = %+v", a.CoffeeItem)
+	}
+	// What the vendor listed wins over what was extracted
+	b, err := db.FindCoffee(ctx, database, "https://example.com/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Region != "Kirinyaga" || b.Processing != "Natural" {
+		t.Errorf("Region = %q, process = %q; want the scraped region kept", b.Region, b.Processing)
+	}
+	c, err := db.FindCoffee(ctx, database, "https://example.com/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Region != "" || c.Processing != "" {
+		t.Errorf("Invalid reply was applied: %+v", c.CoffeeItem)
+	}
+
+	// Only the failure and changed listings are asked again
+	before := fake.calls
+	if _, err := db.SaveData(ctx, database, []models.CoffeeItem{{URL: "https://example.com/b", Name: "Kenya AB", Origin: "Kenya", Region: "Kirinyaga", Description: "Dried on raised beds, now sold out"}}); err != nil {
+		t.Fatal(err)
+	}
+	summary, err = Run(ctx, database, fake, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Pending != 2 || fake.calls-before != 2 {
+		t.Errorf("Second run = %+v after %d calls; want the failure and the edit asked again", summary, fake.calls-before)
+	}
+}
@@ -0,0 +1,176 @@
+package matcher
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/textutil"
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// MinScore is the lowest similarity that is proposed as a link.
+const MinScore = 0.6
+
+// Feature weights. Embedding similarity is only used when both listings have vectors;
+// otherwise its weight is spread over the other features.
+const (
+	weightName      = 0.5
+	weightOrigin    = 0.2
+	weightRegion    = 0.1
+	weightProducer  = 0.2 // Farm or washing station, when extraction found one for both
+	weightEmbedding = 0.2
+)
+
+var (
+	// Things vendors change between listings of the same lot: sizes, crop years, packaging words.
+	reWeight   = regexp.MustCompile(`\b\d+(\.\d+)?\s*(lbs?|pounds?|kgs?|kilos?|g|grams?|oz)\b`)
+	reYear     = regexp.MustCompile(`\b(19|20)\d{2}(/\d{2,4})?\b`)
+	reNonWord  = regexp.MustCompile(`[^a-z0-9]+`)
+	noiseWords = map[string]bool{
+		"green": true, "coffee": true, "coffees": true, "bean": true, "beans": true, "new": true, "crop": true,
+		"lot": true, "sample": true, "unroasted": true, "the": true, "and": true, "of": true,
+	}
+)
+
+// NormalizeName lowercases a listing name and strips accents, weights, crop years,
+// punctuation and filler words, so relistings of one coffee normalize alike.
+func NormalizeName(name string) string {
+	s := textutil.Fold(name)
+	s = reWeight.ReplaceAllString(s, " ")
+	s = reYear.ReplaceAllString(s, " ")
+	s = reNonWord.ReplaceAllString(s, " ")
+
+	var kept []string
+	for _, tok := range strings.Fields(s) {
+		if !noiseWords[tok] {
+			kept = append(kept, tok)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func tokenSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, tok := range strings.Fields(s) {
+		set[tok] = true
+	}
+	return set
+}
+
+// jaccard is the overlap of two token sets (0 to 1).
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for tok := range a {
+		if b[tok] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+func sameText(a, b string) bool {
+	a, b = NormalizeName(a), NormalizeName(b)
+	return a != "" && a == b
+}
+
+// Score compares two listings and returns a similarity from 0 to 1 along with
+// a short human-readable explanation of what matched.
+func Score(a, b db.LotCandidate) (float64, string) {
+	var reasons []string
+	total, weights := 0.0, 0.0
+
+	nameSim := jaccard(tokenSet(NormalizeName(a.Name)), tokenSet(NormalizeName(b.Name)))
+	total += weightName * nameSim
+	weights += weightName
+	reasons = append(reasons, fmt.Sprintf("name %.0f%%", nameSim*100))
+
+	if a.Origin != "" && b.Origin != "" {
+		weights += weightOrigin
+		if sameText(a.Origin, b.Origin) {
+			total += weightOrigin
+			reasons = append(reasons, "same origin")
+		}
+	}
+	if a.Region != "" && b.Region != "" {
+		weights += weightRegion
+		if sameText(a.Region, b.Region) {
+			total += weightRegion
+			reasons = append(reasons, "same region")
+		}
+	}
+	if a.Producer != "" && b.Producer != "" {
+		weights += weightProducer
+		if sameText(a.Producer, b.Producer) {
+			total += weightProducer
+			reasons = append(reasons, "same producer")
+		}
+	}
+	if len(a.Vector) > 0 && len(b.Vector) > 0 && a.Model == b.Model {
+		va, errA := vector.Decode(a.Vector)
+		vb, errB := vector.Decode(b.Vector)
+		if errA == nil && errB == nil && va.Len() == vb.Len() {
+			sim := float64(va.Dot(vb.Floats()))
+			total += weightEmbedding * sim
+			weights += weightEmbedding
+			reasons = append(reasons, fmt.Sprintf("description %.0f%%", sim*100))
+		}
+	}
+
+	// A name with nothing in common is never the same coffee, whatever else matches
+	if nameSim == 0 || weights == 0 {
+		return 0, strings.Join(reasons, ", ")
+	}
+	return total / weights, strings.Join(reasons, ", ")
+}
+
+// Propose compares every active, not-yet-linked listing against the inactive ones
+// and returns the likely matches (score >= MinScore), best first.
+func Propose(candidates []db.LotCandidate) []models.LotLink {
+	var fresh, retired []db.LotCandidate
+	for _, c := range candidates {
+		switch {
+		case c.IsActive && !c.Linked:
+			fresh = append(fresh, c)
+		case !c.IsActive:
+			retired = append(retired, c)
+		}
+	}
+
+	var links []models.LotLink
+	for _, n := range fresh {
+		for _, o := range retired {
+			if n.LotID == o.LotID {
+				continue
+			}
+			score, reasons := Score(n, o)
+			if score >= MinScore {
+				links = append(links, models.LotLink{
+					NewURL: n.URL, NewID: n.ID, NewName: n.Name,
+					OldURL: o.URL, OldID: o.ID, OldName: o.Name,
+					Score: score, Reasons: reasons, Status: models.LinkPending,
+				})
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Score > links[j].Score })
+	return links
+}
+
+// Run proposes links for the current database and stores the new ones.
+// It returns how many new proposals were added.
+func Run(ctx context.Context, database *sql.DB) (int, error) {
+	candidates, err := db.GetLotCandidates(ctx, database)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load coffees: %w", err)
+	}
+	return db.SaveLotProposals(ctx, database, Propose(candidates))
+}
@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+func TestNormalizeName(t *testing.T) {
+	testCases := []struct {
+		input, expected string
+	}{
+		{"Kenya AA – 5 lb", "kenya aa"},
+		{"Colombia Huila Finca El Paraíso 2024/25 New Crop", "colombia huila finca el paraiso"},
+		{"Ethiopia Guji (Sample 250g)", "ethiopia guji"},
+	}
+	for _, tc := range testCases {
+		if got := NormalizeName(tc.input); got != tc.expected {
+			t.Errorf("NormalizeName(%q): expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestPropose(t *testing.T) {
+	candidates := []db.LotCandidate{
+		{ID: 1, LotID: 1, URL: "https://x/old-kenya", Name: "Kenya Kiambu Handege 2024", Origin: "Kenya"},
+		{ID: 2, LotID: 2, URL: "https://x/old-brazil", Name: "Brazil Cerrado", Origin: "Brazil"},
+		{ID: 3, LotID: 3, URL: "https://x/new-kenya", Name: "Kenya Kiambu Handege 2025", Origin: "Kenya", IsActive: true},
+		{ID: 4, LotID: 1, URL: "https://x/linked", Name: "Kenya Kiambu Handege", Origin: "Kenya", IsActive: true, Linked: true},
+	}
+
+	links := Propose(candidates)
+	if len(links) != 1 {
+		t.Fatalf("Expected 1 proposal, got %d: %+v", len(links), links)
+	}
+	if links[0].NewURL != "https://x/new-kenya" || links[0].OldURL != "https://x/old-kenya" {
+		t.Errorf("Unexpected proposal %s -> %s", links[0].NewURL, links[0].OldURL)
+	}
+}
+
+func TestScoreProducer(t *testing.T) {
+	old := db.LotCandidate{Name: "Colombia Huila 2024", Origin: "Colombia", Producer: "Finca El Paraíso"}
+	relisted := db.LotCandidate{Name: "Colombia Huila Pink Bourbon", Origin: "Colombia", Producer: "Finca El Paraiso"}
+	neighbour, unknown := relisted, relisted
+	neighbour.Producer, unknown.Producer = "Finca La Esperanza", ""
+
+	same, reasons := Score(relisted, old)
+	if !strings.Contains(reasons, "same producer") {
+		t.Errorf("Reasons = %q, want the producer to match", reasons)
+	}
+	other, _ := Score(neighbour, old)
+	if same < MinScore || other >= MinScore {
+		t.Errorf("Same producer scored %.2f, another producer %.2f; want only the first proposed", same, other)
+	}
+	// Without a producer on both sides the feature doesn't count either way
+	if s, _ := Score(unknown, old); s <= other || s >= same {
+		t.Errorf("Unknown producer scored %.2f, want between %.2f and %.2f", s, other, same)
+	}
+}
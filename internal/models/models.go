@@ -1,5 +1,16 @@
 package models
 
+import (
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRating is the top of the personal rating scale (1 to MaxRating, 0 = unrated).
+const MaxRating = 5
+
 // CoffeeItem holds the scraped data for a single product.
 type CoffeeItem struct {
 	URL          string
@@ -12,4 +23,261 @@ type CoffeeItem struct {
 	Processing   string
 	Description  string
 	StockStatus  string
+
+	// Filled in by attribute extraction ('brew-buddy enrich'); never scraped
+	Varietals   string // Comma-separated, e.g. "SL28, SL34"
+	AltitudeMin int    // Metres above sea level, 0 if unknown
+	AltitudeMax int
+}
+
+// ManualURLPrefix starts the synthetic URL of coffees entered by hand rather than
+// scraped, e.g. "manual://co-op/kenya-aa-1a2b3c". The URL still uniquely keys the
+// coffee for notes, stock and roasts; scrapes never touch these rows.
+const ManualURLPrefix = "manual://"
+
+// IsManualURL reports whether a coffee URL is a synthetic manual-entry ID.
+func IsManualURL(url string) bool {
+	return strings.HasPrefix(url, ManualURLPrefix)
+}
+
+// IsManual reports whether the coffee was entered by hand.
+func (c CoffeeItem) IsManual() bool {
+	return IsManualURL(c.URL)
+}
+
+// Vendor is the site a coffee is listed on: the URL's host without "www.", or the
+// vendor part of a manual entry's synthetic URL.
+func (c CoffeeItem) Vendor() string {
+	u, err := neturl.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+}
+
+// InventoryItem is a stored coffee as shown in listings and detail views,
+// combining the scraped data with database state and the user's own rating.
+type InventoryItem struct {
+	CoffeeItem
+	ID       int64
+	IsActive bool
+	LotID    int64 // Shared by relistings of the same coffee, defaults to ID
+	Rating   int   // Latest personal rating across the lot, 0 if unrated
+	Hidden   bool  // On the ignore list, directly or through its vendor
+}
+
+// Note is a personal tasting note attached to a coffee.
+type Note struct {
+	ID          int64
+	CoffeeURL   string
+	CoffeeName  string
+	Rating      int // 0 if unrated
+	Notes       string
+	PurchasedAt time.Time
+}
+
+// Ledger entry kinds. Purchases add weight, everything else withdraws it.
+const (
+	LedgerPurchase = "purchase"
+	LedgerRoast    = "roast"
+	LedgerSample   = "sample"
+	LedgerGift     = "gift"
+)
+
+// LedgerEntry is a single movement of green coffee in or out of our stock.
+type LedgerEntry struct {
+	ID         int64
+	CoffeeURL  string
+	CoffeeName string
+	Kind       string  // One of the Ledger* constants
+	WeightLbs  float64 // Always positive, Kind decides the direction
+	PricePaid  float64 // Total paid, purchases only
+	Vendor     string
+	OccurredAt time.Time
+	Notes      string
+}
+
+// StockSummary is the running balance of one coffee in the ledger.
+type StockSummary struct {
+	CoffeeID     int64
+	CoffeeURL    string
+	CoffeeName   string
+	PurchasedLbs float64
+	WithdrawnLbs float64
+	TotalPaid    float64
+	ListingPrice float64 // Latest scraped price, for PackLbs of coffee
+	PackLbs      float64 // Size the listing sells, from its name; 0 if it doesn't say
+}
+
+// RemainingLbs is how much green coffee is left.
+func (s StockSummary) RemainingLbs() float64 {
+	return s.PurchasedLbs - s.WithdrawnLbs
+}
+
+// CostPerLb is the average price paid per pound across all purchases.
+func (s StockSummary) CostPerLb() float64 {
+	if s.PurchasedLbs == 0 {
+		return 0
+	}
+	return s.TotalPaid / s.PurchasedLbs
+}
+
+// CostBasis is what the remaining stock cost us, at the average purchase price.
+func (s StockSummary) CostBasis() float64 {
+	return s.RemainingLbs() * s.CostPerLb()
+}
+
+// ListingPricePerLb is the current listing price per pound, or 0 if the
+// listing doesn't say how much coffee the price buys.
+func (s StockSummary) ListingPricePerLb() float64 {
+	if s.PackLbs == 0 {
+		return 0
+	}
+	return s.ListingPrice / s.PackLbs
+}
+
+// EstimatedValue is what replacing the remaining stock would cost at the current
+// listing price, or 0 if the listing's pack size is unknown.
+func (s StockSummary) EstimatedValue() float64 {
+	return s.RemainingLbs() * s.ListingPricePerLb()
+}
+
+// GramsPerLb converts between the roast log (grams) and the ledger (pounds).
+const GramsPerLb = 453.59237
+
+// Roast is one roast batch of a coffee.
+type Roast struct {
+	ID            int64
+	CoffeeID      int64
+	CoffeeURL     string
+	CoffeeName    string
+	RoastedAt     time.Time
+	ChargeGrams   float64
+	YieldGrams    float64
+	FirstCrackSec int // Seconds from charge, 0 if not recorded
+	DropSec       int // Total roast time in seconds, 0 if not recorded
+	RoastLevel    string
+	Notes         string
+}
+
+// WeightLossPct is the percentage of the green weight lost during roasting.
+func (r Roast) WeightLossPct() float64 {
+	if r.ChargeGrams <= 0 || r.YieldGrams <= 0 {
+		return 0
+	}
+	return (r.ChargeGrams - r.YieldGrams) / r.ChargeGrams * 100
+}
+
+// DevelopmentSec is the time between first crack and drop.
+func (r Roast) DevelopmentSec() int {
+	if r.FirstCrackSec <= 0 || r.DropSec <= r.FirstCrackSec {
+		return 0
+	}
+	return r.DropSec - r.FirstCrackSec
+}
+
+// DTRPct is the development time ratio: development time as a percentage of total roast time.
+func (r Roast) DTRPct() float64 {
+	if r.DropSec <= 0 {
+		return 0
+	}
+	return float64(r.DevelopmentSec()) / float64(r.DropSec) * 100
+}
+
+// ParseMinSec parses a "m:ss" duration (or plain seconds) into seconds. Empty input yields 0.
+func ParseMinSec(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	mins, secs, found := strings.Cut(s, ":")
+	if !found {
+		mins, secs = "0", s
+	}
+	m, err := strconv.Atoi(mins)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("invalid minutes in %q", s)
+	}
+	sec, err := strconv.Atoi(secs)
+	if err != nil || sec < 0 || (found && sec >= 60) {
+		return 0, fmt.Errorf("invalid seconds in %q", s)
+	}
+	return m*60 + sec, nil
+}
+
+// FormatMinSec renders seconds as "m:ss", or "–" when not recorded.
+func FormatMinSec(sec int) string {
+	if sec <= 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%d:%02d", sec/60, sec%60)
+}
+
+// CoffeeRecord is the full archived state of one coffee, as exported and imported.
+// CoffeeItem holds the listing as scraped; extracted attributes travel separately
+// so an import never mistakes them for what the vendor wrote.
+type CoffeeRecord struct {
+	CoffeeItem
+	IsActive       bool
+	FirstScrapedAt time.Time
+	LastSeenAt     time.Time
+	Notes          []Note
+
+	// Attribute extraction (varietals and altitudes are in CoffeeItem), with the
+	// ContentHash of the text it came from; an empty EnrichHash means none
+	EnrichedProcessing   string
+	EnrichedRegion       string
+	EnrichedTastingNotes string
+	Producer             string
+	EnrichHash           string
+}
+
+// CoffeeChange is one field of a listing that changed between scrapes.
+type CoffeeChange struct {
+	ID         int64
+	CoffeeURL  string
+	CoffeeName string
+	Field      string
+	OldValue   string
+	NewValue   string
+	ChangedAt  time.Time
+}
+
+// Lot link statuses.
+const (
+	LinkPending  = "pending"
+	LinkAccepted = "accepted"
+	LinkRejected = "rejected"
+)
+
+// LotLink is a proposed match between a new listing and an older, inactive one
+// that looks like the same coffee (moved URL or next year's crop).
+type LotLink struct {
+	ID        int64
+	NewURL    string
+	NewID     int64
+	NewName   string
+	OldURL    string
+	OldID     int64
+	OldName   string
+	Score     float64
+	Reasons   string
+	Status    string
+	CreatedAt time.Time
+}
+
+// Ignore list entry kinds.
+const (
+	IgnoreCoffee = "coffee"
+	IgnoreVendor = "vendor"
+)
+
+// IgnoreEntry hides a coffee, or every coffee from a vendor, from listings and search.
+type IgnoreEntry struct {
+	ID        int64
+	Kind      string // IgnoreCoffee or IgnoreVendor
+	Value     string // Coffee URL or vendor host
+	Name      string // Coffee name, for coffee entries
+	Reason    string
+	CreatedAt time.Time
 }
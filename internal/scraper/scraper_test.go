@@ -1,17 +1,16 @@
-package main
+package scraper
 
 import (
-	"database/sql"
 	"testing"
 
-	_ "github.com/mattn/go-sqlite3"
+	"mspro-labs/brew-buddy/internal/config"
 )
 
 // TestParseHTML provides a static HTML string and a mock configuration to test parsing.
 func TestParseHTML(t *testing.T) {
 	// 1. Mock Configuration that matches our sample HTML below
-	mockCfg := &SiteConfig{
-		Selectors: Selectors{
+	mockCfg := &config.SiteConfig{
+		Selectors: config.Selectors{
 			ProductRow:           "tbody.product-items tr.product-item",
 			Link:                 "a.product-item-link",
 			Price:                "span.price",
@@ -131,75 +130,6 @@ func TestParseHTML(t *testing.T) {
 	}
 }
 
-// TestDatabaseUPSERT tests the insert, update, and is_active logic.
-func TestDatabaseUPSERT(t *testing.T) {
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Failed to open in-memory db: %v", err)
-	}
-	defer db.Close()
-
-	if err := createSchema(db); err != nil {
-		t.Fatalf("Failed to create schema: %v", err)
-	}
-
-	// 1. Test INSERT
-	item1 := CoffeeItem{
-		URL:   "https://example.com/coffee1",
-		Name:  "Test Coffee",
-		Price: 10.00,
-	}
-	items := []CoffeeItem{item1}
-
-	count, err := saveData(db, items)
-	if err != nil {
-		t.Fatalf("saveData (insert) failed: %v", err)
-	}
-	if count != 1 {
-		t.Errorf("Expected 1 row affected for insert, got %d", count)
-	}
-
-	// Verify insert and default is_active status
-	var name string
-	var isActive int
-	err = db.QueryRow("SELECT name, is_active FROM coffee WHERE url = ?", item1.URL).Scan(&name, &isActive)
-	if err != nil {
-		t.Fatalf("Failed to query inserted data: %v", err)
-	}
-	if name != "Test Coffee" {
-		t.Errorf("Inserted name mismatch. Got '%s'", name)
-	}
-	if isActive != 1 {
-		t.Errorf("New item should be active (1), got %d", isActive)
-	}
-
-	// 2. Test UPDATE (ON CONFLICT)
-	// We also test that it stays active
-	item2 := CoffeeItem{
-		URL:   "https://example.com/coffee1", // Same URL
-		Name:  "Test Coffee Updated",
-		Price: 12.50,
-	}
-	items = []CoffeeItem{item2}
-
-	_, err = saveData(db, items)
-	if err != nil {
-		t.Fatalf("saveData (update) failed: %v", err)
-	}
-
-	// Verify update
-	err = db.QueryRow("SELECT name, price, is_active FROM coffee WHERE url = ?", item2.URL).Scan(&name, &item2.Price, &isActive)
-	if err != nil {
-		t.Fatalf("Failed to query updated data: %v", err)
-	}
-	if name != "Test Coffee Updated" {
-		t.Errorf("Updated name mismatch. Got '%s'", name)
-	}
-	if isActive != 1 {
-		t.Errorf("Updated item should remain active (1), got %d", isActive)
-	}
-}
-
 func TestParsePrice(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -0,0 +1,125 @@
+package searcher
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// interpretVersion is part of every cached interpretation's key. Bump it when
+// the prompt changes.
+const interpretVersion = "query/v1"
+
+const interpretPrompt = `You turn a coffee search into filters and a flavour description.
+Reply with one JSON object with exactly these keys and nothing else:
+{"origins": [country names], "process": "washed" | "natural" | "honey" | "anaerobic" | "wet-hulled" | "",
+ "max_price": number (0 if none), "in_stock": boolean, "vendor": string (one of the vendors listed, or ""),
+ "vibe": string}
+Rules:
+- Only fill a filter the search clearly asks for.
+- origins are countries, in English, e.g. "Kenya" for "Kenyan".
+- vibe is the rest of the search: flavours, body, mood. Leave out the words used for filters. "" if nothing is left.`
+
+// interpretation is a model's reply, exactly as it must be returned.
+type interpretation struct {
+	Origins  []string `json:"origins"`
+	Process  string   `json:"process"`
+	MaxPrice float64  `json:"max_price"`
+	InStock  bool     `json:"in_stock"`
+	Vendor   string   `json:"vendor"`
+	Vibe     string   `json:"vibe"`
+}
+
+// Interpret asks a generative model to split a query, for phrasings the rules
+// miss. Answers are checked against what the rules know (origins, processes,
+// vendors) and cached by query; if the model fails or answers something the
+// checks reject, the query falls back to Parse.
+func Interpret(ctx context.Context, database *sql.DB, gen ai.Generator, text string, vendors []string) Query {
+	q, err := interpret(ctx, database, gen, text, vendors)
+	if err != nil {
+		log.Printf("⚠️ Falling back to rules for %q: %v", text, err)
+		return Parse(text, vendors)
+	}
+	return q
+}
+
+func interpret(ctx context.Context, database *sql.DB, gen ai.Generator, text string, vendors []string) (Query, error) {
+	input := fmt.Sprintf("Vendors: %s\nSearch: %s", strings.Join(vendors, ", "), strings.TrimSpace(text))
+	hash := db.ContentHash(interpretVersion + "\n" + db.NormalizeQuery(input))
+
+	reply, err := db.GetInterpretation(ctx, database, hash, gen.Model())
+	if errors.Is(err, db.ErrNotFound) {
+		reply, err = gen.Generate(ctx, ai.Prompt{System: interpretPrompt, User: input, JSON: true})
+		if err != nil {
+			return Query{}, err
+		}
+		q, err := parseInterpretation(text, reply, vendors)
+		if err != nil {
+			return Query{}, err
+		}
+		if err := db.SaveInterpretation(ctx, database, hash, gen.Model(), reply); err != nil {
+			log.Printf("Warning: failed to cache query interpretation: %v", err)
+		}
+		q.Parser = gen.Model()
+		return q, nil
+	}
+	if err != nil {
+		return Query{}, err
+	}
+	q, err := parseInterpretation(text, reply, vendors)
+	q.Parser = gen.Model()
+	return q, err
+}
+
+// parseInterpretation validates a reply strictly and converts it to a Query.
+func parseInterpretation(text, reply string, vendors []string) (Query, error) {
+	var in interpretation
+	dec := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(reply))))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return Query{}, fmt.Errorf("reply doesn't match the schema: %w", err)
+	}
+
+	q := Query{Text: text, Vibe: strings.TrimSpace(in.Vibe)}
+	for _, o := range in.Origins {
+		origin, ok := origins[strings.ToLower(strings.TrimSpace(o))]
+		if !ok {
+			return Query{}, fmt.Errorf("unknown origin %q", o)
+		}
+		if !slices.Contains(q.Filter.Origins, origin) {
+			q.Filter.Origins = append(q.Filter.Origins, origin)
+		}
+	}
+	if in.Process != "" {
+		process, ok := processes[strings.ToLower(in.Process)]
+		if !ok {
+			return Query{}, fmt.Errorf("unknown process %q", in.Process)
+		}
+		q.Filter.Process = process
+	}
+	if in.MaxPrice < 0 || in.MaxPrice > 1000 {
+		return Query{}, fmt.Errorf("implausible price ceiling %.2f", in.MaxPrice)
+	}
+	q.Filter.MaxPrice = in.MaxPrice
+	q.Filter.InStock = in.InStock
+	if in.Vendor != "" {
+		vendor := strings.ToLower(strings.TrimPrefix(in.Vendor, "www."))
+		if !slices.Contains(vendors, vendor) {
+			return Query{}, fmt.Errorf("unknown vendor %q", in.Vendor)
+		}
+		q.Filter.Vendor = vendor
+	}
+	if len(q.Vibe) > len(text) {
+		return Query{}, fmt.Errorf("vibe is longer than the search")
+	}
+	return q, nil
+}
@@ -0,0 +1,224 @@
+package searcher
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+// Query is a search split into hard filters, applied in SQL, and the vibe: the
+// flavour text that is embedded and ranked by similarity.
+type Query struct {
+	Text   string // As typed
+	Filter db.SearchFilter
+	Vibe   string // "" lists the filtered coffees, cheapest first
+	Parser string // What interpreted the text: "rules", a generative model, or "none"
+}
+
+// Raw searches the whole text by similarity with no filters.
+func Raw(text string) Query {
+	return Query{Text: text, Vibe: strings.TrimSpace(text), Parser: "none"}
+}
+
+// Terms describes the interpretation for display, one term per filter.
+func (q Query) Terms() []string {
+	var terms []string
+	f := q.Filter
+	if len(f.Origins) > 0 {
+		terms = append(terms, "origin: "+strings.Join(f.Origins, " or "))
+	}
+	if f.Process != "" {
+		terms = append(terms, "process: "+f.Process)
+	}
+	if f.MaxPrice > 0 {
+		terms = append(terms, fmt.Sprintf("max $%.2f", f.MaxPrice))
+	}
+	if f.InStock {
+		terms = append(terms, "in stock")
+	}
+	if f.Vendor != "" {
+		terms = append(terms, "vendor: "+f.Vendor)
+	}
+	return terms
+}
+
+// origins maps country names and demonyms to the origin they filter on.
+var origins = aliases([][]string{
+	{"Bolivia", "bolivian"}, {"Brazil", "brazilian"}, {"Burundi", "burundian"},
+	{"Colombia", "colombian"}, {"Congo", "congolese", "drc"}, {"Costa Rica", "costa rican"},
+	{"Dominican Republic"}, {"Ecuador", "ecuadorian"}, {"El Salvador", "salvadoran"},
+	{"Ethiopia", "ethiopian"}, {"Guatemala", "guatemalan"}, {"Haiti", "haitian"},
+	{"Hawaii", "hawaiian", "kona"}, {"Honduras", "honduran"}, {"India", "indian"},
+	{"Indonesia", "indonesian"}, {"Jamaica", "jamaican"}, {"Kenya", "kenyan"},
+	{"Malawi"}, {"Mexico", "mexican"}, {"Myanmar"}, {"Nicaragua", "nicaraguan"},
+	{"Panama", "panamanian"}, {"Papua New Guinea", "png"}, {"Peru", "peruvian"},
+	{"Rwanda", "rwandan"}, {"Sulawesi"}, {"Sumatra", "sumatran"}, {"Tanzania", "tanzanian"},
+	{"Timor", "timorese"}, {"Uganda", "ugandan"}, {"Vietnam", "vietnamese"},
+	{"Yemen", "yemeni"}, {"Zambia", "zambian"},
+})
+
+// processes maps phrases naming a process to the process.
+var processes = aliases([][]string{
+	{"Washed", "fully washed", "wet processed", "wet process"},
+	{"Natural", "naturals", "dry processed", "dry process", "sun dried"},
+	{"Honey", "honey processed", "honey process", "pulped natural"},
+	{"Anaerobic"},
+	{"Wet Hulled", "wet-hulled", "giling basah"},
+})
+
+// aliases maps each name, lowercased, and its aliases to the name, which
+// comes first in each group.
+func aliases(groups [][]string) map[string]string {
+	m := make(map[string]string)
+	for _, g := range groups {
+		for _, alias := range g {
+			m[strings.ToLower(alias)] = g[0]
+		}
+	}
+	return m
+}
+
+var (
+	reOrigin = phraseRegexp(origins)
+	// A bare "honey" is more often a flavour than a process
+	reProcess = phraseRegexp(processes, "honey")
+
+	// A price ceiling: "under $9", "below 8.50 per lb", "$10/lb or less".
+	// Numbers without a currency or unit ("under 2000m") are not prices.
+	rePriceBefore = regexp.MustCompile(`(?i)\b(?:under|below|less than|cheaper than|at most|up to|no more than|max(?:imum)?)\s*(\$)?\s*(\d+(?:\.\d{1,2})?)\s*(dollars|bucks|usd)?\s*((?:/|per|a)\s*(?:lb|pound))?`)
+	rePriceAfter  = regexp.MustCompile(`(?i)(?:\$\s*(\d+(?:\.\d{1,2})?)|(\d+(?:\.\d{1,2})?)\s*(?:dollars|bucks))\s*(?:(?:/|per|a)\s*(?:lb|pound)\s*)?(?:or less|or under|or cheaper|max)\b`)
+	reInStock     = regexp.MustCompile(`(?i)\b(?:in[ -]stock|available(?: now)?)\b`)
+	reDomain      = regexp.MustCompile(`(?i)\b(?:from|at|on|via)\s+(?:www\.)?([a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,})\b`)
+)
+
+// phraseRegexp matches any key of m but the excluded ones as whole words,
+// longest first so "costa rican" wins over "costa rica".
+func phraseRegexp(m map[string]string, exclude ...string) *regexp.Regexp {
+	phrases := make([]string, 0, len(m))
+	for p := range m {
+		if !slices.Contains(exclude, p) {
+			phrases = append(phrases, regexp.QuoteMeta(p))
+		}
+	}
+	sort.Slice(phrases, func(i, j int) bool { return len(phrases[i]) > len(phrases[j]) })
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(phrases, "|") + `)\b`)
+}
+
+// fillers are dropped from the start and end of each clause of the vibe.
+var fillers = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "with": true, "from": true,
+	"in": true, "at": true, "on": true, "of": true, "for": true, "that": true, "is": true, "are": true, "by": true,
+	"something": true, "some": true, "any": true, "coffee": true, "coffees": true, "bean": true, "beans": true,
+	"green": true, "one": true, "ones": true, "i": true, "want": true, "show": true, "me": true, "find": true,
+	"looking": true, "please": true, "which": true, "thats": true, "its": true, "process": true, "processed": true,
+	// A filter-only search is listed cheapest first anyway
+	"cheap": true, "cheaper": true, "cheapest": true, "affordable": true, "inexpensive": true, "budget": true,
+}
+
+// Parse interprets a query with rules: known origins and processes, a price
+// ceiling, "in stock", and a vendor named by its host (from vendors, or any
+// "from example.com"). Whatever is left becomes the vibe.
+func Parse(text string, vendors []string) Query {
+	q := Query{Text: text, Parser: "rules"}
+	rest := text
+
+	rest = reOrigin.ReplaceAllStringFunc(rest, func(m string) string {
+		if o := origins[strings.ToLower(m)]; !slices.Contains(q.Filter.Origins, o) {
+			q.Filter.Origins = append(q.Filter.Origins, o)
+		}
+		return ","
+	})
+	rest = reProcess.ReplaceAllStringFunc(rest, func(m string) string {
+		if q.Filter.Process != "" {
+			return m // Only one process; a second stays part of the vibe
+		}
+		q.Filter.Process = processes[strings.ToLower(m)]
+		return ","
+	})
+	rest = rePriceBefore.ReplaceAllStringFunc(rest, func(m string) string {
+		g := rePriceBefore.FindStringSubmatch(m)
+		if g[1] == "" && g[3] == "" && g[4] == "" {
+			return m
+		}
+		q.Filter.MaxPrice = parsePrice(g[2])
+		return ","
+	})
+	rest = rePriceAfter.ReplaceAllStringFunc(rest, func(m string) string {
+		g := rePriceAfter.FindStringSubmatch(m)
+		q.Filter.MaxPrice = parsePrice(g[1] + g[2])
+		return ","
+	})
+	rest = reInStock.ReplaceAllStringFunc(rest, func(string) string {
+		q.Filter.InStock = true
+		return ","
+	})
+	rest = reDomain.ReplaceAllStringFunc(rest, func(m string) string {
+		q.Filter.Vendor = strings.ToLower(reDomain.FindStringSubmatch(m)[1])
+		return ","
+	})
+	if q.Filter.Vendor == "" {
+		rest = matchVendor(rest, vendors, &q.Filter.Vendor)
+	}
+
+	q.Vibe = cleanVibe(rest)
+	return q
+}
+
+func parsePrice(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// matchVendor looks for a known vendor's host, or the name part of it
+// ("sweetmarias" for sweetmarias.com, "local co op" for a manual vendor
+// "local-co-op").
+func matchVendor(text string, vendors []string, vendor *string) string {
+	for _, v := range vendors {
+		name, _, _ := strings.Cut(v, ".")
+		for _, alias := range []string{v, name} {
+			if len(alias) < 4 {
+				continue
+			}
+			pattern := strings.ReplaceAll(regexp.QuoteMeta(alias), "-", `[-\s]`)
+			re := regexp.MustCompile(`(?i)\b(?:(?:from|at|on|via|by)\s+)?` + pattern + `\b`)
+			if loc := re.FindStringIndex(text); loc != nil {
+				*vendor = v
+				return text[:loc[0]] + "," + text[loc[1]:]
+			}
+		}
+	}
+	return text
+}
+
+var (
+	reClause = regexp.MustCompile(`[,;.!?]+`)
+	reWord   = regexp.MustCompile(`[\p{L}\p{N}'’-]+`)
+)
+
+// cleanVibe splits what's left of a query into clauses and trims filler words
+// from each end of them, so "something bright and juicy from" reads
+// "bright and juicy".
+func cleanVibe(rest string) string {
+	var clauses []string
+	for _, clause := range reClause.Split(rest, -1) {
+		words := reWord.FindAllString(clause, -1)
+		isFiller := func(w string) bool {
+			return fillers[strings.ToLower(strings.NewReplacer("'", "", "’", "").Replace(w))]
+		}
+		for len(words) > 0 && isFiller(words[0]) {
+			words = words[1:]
+		}
+		for len(words) > 0 && isFiller(words[len(words)-1]) {
+			words = words[:len(words)-1]
+		}
+		if len(words) > 0 {
+			clauses = append(clauses, strings.Join(words, " "))
+		}
+	}
+	return strings.Join(clauses, ", ")
+}
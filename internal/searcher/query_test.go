@@ -0,0 +1,92 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mspro-labs/brew-buddy/internal/ai"
+	"mspro-labs/brew-buddy/internal/db"
+)
+
+func TestParse(t *testing.T) {
+	vendors := []string{"sweetmarias.com", "local-co-op"}
+	tests := []struct {
+		text   string
+		filter db.SearchFilter
+		vibe   string
+	}{
+		{"washed Kenyan under $9/lb in stock, bright and juicy",
+			db.SearchFilter{Origins: []string{"Kenya"}, Process: "Washed", MaxPrice: 9, InStock: true}, "bright and juicy"},
+		{"funky and fruity with berry notes", db.SearchFilter{}, "funky and fruity with berry notes"},
+		{"something with honey sweetness from Costa Rica or Panama",
+			db.SearchFilter{Origins: []string{"Costa Rica", "Panama"}}, "honey sweetness"},
+		{"cheap naturals in stock", db.SearchFilter{Process: "Natural", InStock: true}, ""},
+		{"honey processed $7.50 or less", db.SearchFilter{Process: "Honey", MaxPrice: 7.5}, ""},
+		{"grown under 2000m, chocolatey from sweetmarias", db.SearchFilter{Vendor: "sweetmarias.com"}, "grown under 2000m, chocolatey"},
+		{"Local Co-op naturals", db.SearchFilter{Process: "Natural", Vendor: "local-co-op"}, ""},
+		{"juicy coffee from www.example.org", db.SearchFilter{Vendor: "example.org"}, "juicy"},
+	}
+	for _, tt := range tests {
+		q := Parse(tt.text, vendors)
+		if !reflect.DeepEqual(q.Filter, tt.filter) || q.Vibe != tt.vibe {
+			t.Errorf("Parse(%q) = %+v, vibe %q; want %+v, vibe %q", tt.text, q.Filter, q.Vibe, tt.filter, tt.vibe)
+		}
+	}
+}
+
+// fakeGenerator returns a canned reply and counts calls.
+type fakeGenerator struct {
+	reply string
+	calls int
+}
+
+func (f *fakeGenerator) Generate(ctx context.Context, p ai.Prompt) (string, error) {
+	f.calls++
+	if f.reply == "" {
+		return "", errors.New("model is down")
+	}
+	return f.reply, nil
+}
+
+func (f *fakeGenerator) Model() string { return "fake/chat" }
+func (f *fakeGenerator) Close()        {}
+
+func TestInterpret(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Connect(ctx, filepath.Join(t.TempDir(), "coffee.db"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer database.Close()
+	vendors := []string{"sweetmarias.com"}
+	text := "a cheap-ish Kenyan from Sweet Maria's, nothing over nine bucks, tastes like ribena"
+
+	gen := &fakeGenerator{reply: `{"origins": ["Kenya"], "process": "", "max_price": 9, "in_stock": false,
+		"vendor": "sweetmarias.com", "vibe": "tastes like ribena"}`}
+	want := db.SearchFilter{Origins: []string{"Kenya"}, MaxPrice: 9, Vendor: "sweetmarias.com"}
+	for range 2 {
+		q := Interpret(ctx, database, gen, text, vendors)
+		if !reflect.DeepEqual(q.Filter, want) || q.Vibe != "tastes like ribena" || q.Parser != "fake/chat" {
+			t.Errorf("Interpret = %+v", q)
+		}
+	}
+	if gen.calls != 1 {
+		t.Errorf("Model called %d times, want the second answer cached", gen.calls)
+	}
+
+	// Answers outside what the rules know, and failures, fall back to the rules
+	for _, reply := range []string{
+		`{"origins": ["Atlantis"], "process": "", "max_price": 0, "in_stock": false, "vendor": "", "vibe": ""}`,
+		`{"origins": [], "process": "", "max_price": 0, "in_stock": false, "vendor": "evil.example", "vibe": ""}`,
+		`{"origins": [], "process": "", "max_price": 0, "in_stock": false, "vendor": "", "vibe": "", "sql": "DROP TABLE coffee"}`,
+		"",
+	} {
+		q := Interpret(ctx, database, &fakeGenerator{reply: reply}, "washed Kenyan", vendors)
+		if q.Parser != "rules" || q.Filter.Process != "Washed" {
+			t.Errorf("Reply %q gave %+v; want the rules' interpretation", reply, q)
+		}
+	}
+}
@@ -3,43 +3,74 @@ package searcher
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
+	"time"
 
 	"mspro-labs/brew-buddy/internal/ai"
 	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/variants"
+	"mspro-labs/brew-buddy/internal/vector"
 )
 
 // Result holds a single search match.
 type Result struct {
-	Item  db.CoffeeVector
-	Score float32
+	Item     db.CoffeeVector
+	Score    float32
+	Variants []string // Labels of other sizes or samples of the same coffee, folded into this result
 }
 
-// Perform executes a semantic search.
-func Perform(ctx context.Context, database *sql.DB, aiClient *ai.Client, queryText string) ([]Result, error) {
+// ErrNoEmbedder is returned for a query that isn't cached when there is no
+// embedder to embed it with.
+var ErrNoEmbedder = errors.New("query is not cached and no AI client is available")
+
+// Result limits for a zero Options.Limit.
+const (
+	DefaultLimit       = 5  // Ranked by vibe
+	DefaultFilterLimit = 20 // Filters alone, cheapest first
+)
+
+// Options tune a search.
+type Options struct {
+	Cache         db.CachePolicy
+	AsOf          time.Time         // Search the catalogue as it was at this time; zero means current
+	IncludeHidden bool              // Also return coffees on the ignore list
+	Variants      *variants.Grouper // Folds sizes and samples of one coffee together; nil uses the built-in patterns
+	Limit         int               // Most results returned; 0 takes the defaults above
+}
+
+// Perform executes a search: the query's filters narrow the catalogue in SQL
+// and its vibe ranks what's left by similarity. A query with no vibe lists
+// the matching coffees cheapest first and needs no embedder.
+func Perform(ctx context.Context, database *sql.DB, aiClient ai.Embedder, q Query, opts Options) ([]Result, error) {
+	if q.Vibe == "" {
+		return list(ctx, database, q, opts)
+	}
+
 	// 1. Get Query Vector (Try cache first, then AI)
-	queryVector, err := getQueryVector(ctx, database, aiClient, queryText)
+	queryVector, model, err := getQueryVector(ctx, database, aiClient, q.Vibe, opts.Cache)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Load all coffee vectors
-	coffees, err := db.GetCoffeeVectors(database)
+	// 2. Load the matching coffees' vectors made by the same model
+	coffees, err := db.SearchCoffeeVectors(ctx, database, model, opts.AsOf, q.Filter, opts.IncludeHidden)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load coffees: %w", err)
 	}
 
-	// 3. Compare and score
-	var results []Result
+	// 3. Compare and score: stored vectors are unit length, so once the query
+	// is too the cosine similarity is a dot product
+	query := vector.Normalize(queryVector)
+	results := make([]Result, 0, len(coffees))
 	for _, coffee := range coffees {
-		coffeeFloats, err := ai.BytesToFloats(coffee.Vector)
-		if err != nil {
+		v, err := vector.Decode(coffee.Vector)
+		if err != nil || v.Len() != len(query) {
 			continue
 		}
-		score := ai.CosineSimilarity(queryVector, coffeeFloats)
-		results = append(results, Result{Item: coffee, Score: score})
+		results = append(results, Result{Item: coffee, Score: v.Dot(query)})
 	}
 
 	// 4. Sort by descending score
@@ -47,34 +78,94 @@ func Perform(ctx context.Context, database *sql.DB, aiClient *ai.Client, queryTe
 		return results[i].Score > results[j].Score
 	})
 
-	// Limit to Top 5
-	if len(results) > 5 {
-		results = results[:5]
+	// 5. Collapse variants, keeping the best-scoring listing of each coffee
+	results = collapse(opts.Variants, results)
+
+	// 6. Keep the top results
+	return limit(results, opts.Limit, DefaultLimit), nil
+}
+
+// list returns the coffees matching a query's filters, cheapest first.
+func list(ctx context.Context, database *sql.DB, q Query, opts Options) ([]Result, error) {
+	coffees, err := db.SearchCoffeeVectors(ctx, database, "", opts.AsOf, q.Filter, opts.IncludeHidden)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load coffees: %w", err)
+	}
+	results := make([]Result, len(coffees))
+	for i, coffee := range coffees {
+		results[i] = Result{Item: coffee}
 	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Item.Price < results[j].Item.Price
+	})
+	return limit(collapse(opts.Variants, results), opts.Limit, DefaultFilterLimit), nil
+}
 
-	return results, nil
+func limit(results []Result, n, def int) []Result {
+	if n <= 0 {
+		n = def
+	}
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results
 }
 
-// getQueryVector handles the "cache-aside" logic for query embeddings.
-func getQueryVector(ctx context.Context, database *sql.DB, aiClient *ai.Client, text string) ([]float32, error) {
+// getQueryVector handles the "cache-aside" logic for query embeddings. It
+// returns the vector with the model that made it: the client's, or without a
+// client whichever model the cached vector came from.
+func getQueryVector(ctx context.Context, database *sql.DB, aiClient ai.Embedder, text string, cache db.CachePolicy) ([]float32, string, error) {
+	model := ""
+	if aiClient != nil {
+		model = aiClient.Model()
+	}
+
 	// A. Try Cache
-	blob, err := db.GetCachedQuery(database, text)
+	cached, err := db.GetCachedQuery(ctx, database, text, model, cache)
 	if err == nil {
 		// Cache hit
-		return ai.BytesToFloats(blob)
+		v, err := vector.Decode(cached.Vector)
+		return v.Floats(), cached.Model, err
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, "", fmt.Errorf("failed to read query cache: %w", err)
 	}
 
 	// B. Cache Miss - Use AI
-	log.Printf("🤖 Cache miss for '%s'. Calling Gemini...", text)
+	if aiClient == nil {
+		return nil, "", ErrNoEmbedder
+	}
+	log.Printf("🤖 Cache miss for '%s'. Embedding...", text)
 	blob, floats, err := aiClient.EmbedString(ctx, text)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// C. Save to Cache (don't fail the request if cache save fails)
-	if err := db.SaveCachedQuery(database, text, blob); err != nil {
+	if err := db.SaveCachedQuery(ctx, database, text, model, blob, cache); err != nil {
 		log.Printf("Warning: failed to save query to cache: %v", err)
 	}
 
-	return floats, nil
+	return floats, model, nil
+}
+
+// collapse folds results that are variants of one coffee into the first (best)
+// of them. The input must already be sorted.
+func collapse(grouper *variants.Grouper, results []Result) []Result {
+	groups := variants.Group(grouper, results, func(r Result) variants.Listing {
+		return variants.Listing{URL: r.Item.URL, Name: r.Item.Name, Description: r.Item.Description}
+	})
+	collapsed := make([]Result, 0, len(groups))
+	for _, g := range groups {
+		best := g[0]
+		for _, v := range g[1:] {
+			label := grouper.Label(v.Item.Name)
+			if label == "" {
+				label = v.Item.Name
+			}
+			best.Variants = append(best.Variants, label)
+		}
+		collapsed = append(collapsed, best)
+	}
+	return collapsed
 }
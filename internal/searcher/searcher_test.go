@@ -0,0 +1,153 @@
+package searcher
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mspro-labs/brew-buddy/internal/db"
+	"mspro-labs/brew-buddy/internal/models"
+	"mspro-labs/brew-buddy/internal/vector"
+)
+
+// fakeEmbedder returns the same vector for every text.
+type fakeEmbedder struct{ v []float32 }
+
+func (f fakeEmbedder) EmbedString(ctx context.Context, text string) ([]byte, []float32, error) {
+	blob, err := vector.Encode(f.v, vector.Float32)
+	return blob, f.v, err
+}
+
+func (fakeEmbedder) Model() string { return "fake/embed" }
+func (fakeEmbedder) Close()        {}
+
+func openDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	database, err := db.Connect(context.Background(), filepath.Join(tb.TempDir(), "coffee.db"))
+	if err != nil {
+		tb.Fatalf("Connect failed: %v", err)
+	}
+	tb.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestPerformEncodings(t *testing.T) {
+	ctx := context.Background()
+	database := openDB(t)
+
+	// Stored raw (as before encodings), as float32 and as int8, each at a
+	// different angle from the query
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint32(raw, math.Float32bits(3))
+	binary.LittleEndian.PutUint32(raw[4:], math.Float32bits(4))
+	float32Blob, _ := vector.Encode([]float32{1, 0}, vector.Float32)
+	int8Blob, _ := vector.Encode([]float32{0, 2}, vector.Int8)
+	coffees := []struct {
+		url   string
+		blob  []byte
+		score float32
+	}{
+		{"https://example.com/raw", raw, 1},
+		{"https://example.com/float32", float32Blob, 0.6},
+		{"https://example.com/int8", int8Blob, 0.8},
+	}
+	for _, c := range coffees {
+		if _, err := db.SaveData(ctx, database, []models.CoffeeItem{{URL: c.url, Name: c.url}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateEmbedding(ctx, database, c.url, "fake/embed", "", c.blob); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Perform(ctx, database, fakeEmbedder{[]float32{6, 8}}, Raw("anything"), Options{})
+	if err != nil {
+		t.Fatalf("Perform failed: %v", err)
+	}
+	if len(results) != 3 || results[2].Item.URL != coffees[1].url {
+		t.Fatalf("Results = %+v, want the float32 coffee last", results)
+	}
+	for _, r := range results {
+		for _, c := range coffees {
+			if r.Item.URL == c.url && math.Abs(float64(r.Score-c.score)) > 0.01 {
+				t.Errorf("%s scored %f, want %f", c.url, r.Score, c.score)
+			}
+		}
+	}
+}
+
+// BenchmarkPerformArchive searches a catalogue whose archive has grown to
+// tens of thousands of delisted coffees. Searching today, or as of a recent
+// scrape, should only cost what that catalogue holds; the whole archive is
+// only scored as of the scrape that listed it all.
+func BenchmarkPerformArchive(b *testing.B) {
+	const archived, listed, dims = 20000, 500, 768
+	ctx := context.Background()
+	database := openDB(b)
+
+	items := make([]models.CoffeeItem, archived)
+	for i := range items {
+		items[i] = models.CoffeeItem{URL: fmt.Sprintf("https://example.com/%d", i), Name: fmt.Sprintf("Coffee %d", i),
+			Price: 7, StockStatus: "In Stock"}
+	}
+	if _, err := db.SaveData(ctx, database, items); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := database.ExecContext(ctx, `UPDATE scrape_runs SET run_at = '2025-03-10 12:00:00'`); err != nil {
+		b.Fatal(err)
+	}
+	if err := db.MarkAllAsInactive(ctx, database); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := db.SaveData(ctx, database, items[:listed]); err != nil {
+		b.Fatal(err)
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	random := func() []float32 {
+		v := make([]float32, dims)
+		for i := range v {
+			v[i] = float32(r.NormFloat64())
+		}
+		return v
+	}
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, item := range items {
+		blob, _ := vector.Encode(random(), vector.Float32)
+		if _, err := tx.ExecContext(ctx, `INSERT INTO embeddings (coffee_url, model, dims, encoding, vector, created_at)
+			VALUES (?, 'fake/embed', ?, ?, ?, CURRENT_TIMESTAMP)`, item.URL, dims, vector.Float32, blob); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	embedder := fakeEmbedder{random()}
+	for _, bm := range []struct {
+		name string
+		asOf time.Time
+	}{
+		{"current", time.Time{}},
+		{"as-of-latest", time.Now().Add(time.Hour)},
+		{"whole-archive", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for b.Loop() {
+				results, err := Perform(ctx, database, embedder, Raw("juicy"), Options{AsOf: bm.asOf})
+				if err != nil || len(results) != DefaultLimit {
+					b.Fatalf("Perform = %d results, %v", len(results), err)
+				}
+			}
+		})
+	}
+}
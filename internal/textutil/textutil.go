@@ -0,0 +1,14 @@
+// Package textutil holds text normalization shared by the packages that
+// compare coffee names and descriptions.
+package textutil
+
+import "strings"
+
+var accents = strings.NewReplacer("á", "a", "à", "a", "ä", "a", "â", "a", "é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ï", "i", "ó", "o", "ö", "o", "ô", "o", "ú", "u", "ü", "u", "ñ", "n", "ç", "c")
+
+// Fold lowercases s and strips the accents common in coffee names, so
+// "Café Peñas" and "cafe penas" compare equal.
+func Fold(s string) string {
+	return accents.Replace(strings.ToLower(s))
+}
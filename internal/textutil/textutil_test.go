@@ -0,0 +1,17 @@
+package textutil
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Café Peñas":       "cafe penas",
+		"FINCA EL PARAÍSO": "finca el paraiso",
+		"Ngöro Çaturra":    "ngoro caturra",
+		"plain":            "plain",
+	}
+	for in, want := range tests {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
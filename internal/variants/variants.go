@@ -0,0 +1,192 @@
+// Package variants clusters listings that are really one coffee sold in several
+// sizes (e.g. "Kenya AA – 1 lb", "Kenya AA – 5 lb", "Kenya AA (Sample)"), so
+// inventory and search can show them as a single logical coffee.
+package variants
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mspro-labs/brew-buddy/internal/models"
+)
+
+// Pack sizes: a weight such as "5 lb" or "250g", or "half pound"/"quarter pound".
+var (
+	reWeight   = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s*(lbs?|pounds?|kgs?|kilos?|g|grams?|oz)\b`)
+	reFraction = regexp.MustCompile(`(?i)\b(half|quarter)[\s-]pound\b`)
+)
+
+// builtin match the size and packaging suffixes most vendors use.
+var builtin = []*regexp.Regexp{
+	reWeight,
+	reFraction,
+	regexp.MustCompile(`(?i)\b(full|half)\s+(bag|box|sack)\b`),
+	regexp.MustCompile(`(?i)\bsamples?(\s+size)?\b`),
+	regexp.MustCompile(`(?i)\bbulk\b`),
+}
+
+var reNonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// minDescription is how long a description must be (after normalizing) before two
+// listings sharing it are taken to be the same coffee. Short ones like "Coming
+// soon" are too generic.
+const minDescription = 60
+
+// Grouper decides which listings are variants of each other. A nil Grouper uses
+// only the built-in patterns.
+type Grouper struct {
+	extra []*regexp.Regexp
+}
+
+// New returns a Grouper that also strips the given site-specific patterns
+// (regular expressions, matched case-insensitively) from listing names.
+func New(patterns []string) (*Grouper, error) {
+	g := &Grouper{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(`(?i)` + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid variant pattern %q: %w", p, err)
+		}
+		g.extra = append(g.extra, re)
+	}
+	return g, nil
+}
+
+func (g *Grouper) patterns() []*regexp.Regexp {
+	if g == nil {
+		return builtin
+	}
+	return append(g.extra[:len(g.extra):len(g.extra)], builtin...)
+}
+
+// Label returns the part of a listing name that marks its variant, e.g. "5 lb"
+// or "Sample 250g", or "" if the name has none.
+func (g *Grouper) Label(name string) string {
+	var spans [][]int
+	for _, re := range g.patterns() {
+		spans = append(spans, re.FindAllStringIndex(name, -1)...)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var parts []string
+	end := 0
+	for _, s := range spans {
+		if s[0] < end {
+			continue // Overlaps a match already taken
+		}
+		if m := strings.TrimSpace(name[s[0]:s[1]]); m != "" {
+			parts = append(parts, m)
+		}
+		end = s[1]
+	}
+	return strings.Join(parts, " ")
+}
+
+// lbsPer converts the units reWeight matches, without a plural "s", to pounds.
+var lbsPer = map[string]float64{
+	"lb": 1, "pound": 1, "oz": 1.0 / 16,
+	"g": 1 / models.GramsPerLb, "gram": 1 / models.GramsPerLb,
+	"kg": 1000 / models.GramsPerLb, "kilo": 1000 / models.GramsPerLb,
+}
+
+// PackLbs is the weight a listing sells, in pounds, read from the size in its
+// name (e.g. "5 lb", "1 kg" or "Half Pound"), or 0 if the name gives none.
+func PackLbs(name string) float64 {
+	if m := reWeight.FindStringSubmatch(name); m != nil {
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0
+		}
+		return n * lbsPer[strings.TrimSuffix(strings.ToLower(m[2]), "s")]
+	}
+	if m := reFraction.FindStringSubmatch(name); m != nil {
+		if strings.EqualFold(m[1], "half") {
+			return 0.5
+		}
+		return 0.25
+	}
+	return 0
+}
+
+// BaseName is a listing name with its variant markers, punctuation and case removed.
+func (g *Grouper) BaseName(name string) string {
+	s := name
+	for _, re := range g.patterns() {
+		s = re.ReplaceAllString(s, " ")
+	}
+	return strings.Join(strings.Fields(reNonWord.ReplaceAllString(strings.ToLower(s), " ")), " ")
+}
+
+// Listing is what grouping looks at.
+type Listing struct {
+	URL         string
+	Name        string
+	Description string
+}
+
+// Group clusters items whose listings come from the same vendor and either
+// share a base name or have the same (non-trivial) description. Groups are in
+// order of their first member, and members keep their input order, so the
+// first item of each group is the one to show.
+func Group[T any](g *Grouper, items []T, listing func(T) Listing) [][]T {
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		// Keep the earliest item as the root so groups stay in input order
+		if ra < rb {
+			parent[rb] = ra
+		} else if rb < ra {
+			parent[ra] = rb
+		}
+	}
+
+	byName := make(map[string]int)
+	byDescription := make(map[string]int)
+	for i, item := range items {
+		l := listing(item)
+		vendor := models.CoffeeItem{URL: l.URL}.Vendor()
+		if base := g.BaseName(l.Name); base != "" {
+			key := vendor + "\x00" + base
+			if first, ok := byName[key]; ok {
+				union(first, i)
+			} else {
+				byName[key] = i
+			}
+		}
+		if desc := strings.Join(strings.Fields(strings.ToLower(l.Description)), " "); len(desc) >= minDescription {
+			key := vendor + "\x00" + desc
+			if first, ok := byDescription[key]; ok {
+				union(first, i)
+			} else {
+				byDescription[key] = i
+			}
+		}
+	}
+
+	var groups [][]T
+	index := make(map[int]int) // root -> position in groups
+	for i, item := range items {
+		root := find(i)
+		pos, ok := index[root]
+		if !ok {
+			pos = len(groups)
+			index[root] = pos
+			groups = append(groups, nil)
+		}
+		groups[pos] = append(groups[pos], item)
+	}
+	return groups
+}
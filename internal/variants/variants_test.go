@@ -0,0 +1,89 @@
+package variants
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	testCases := []struct {
+		input, base, label string
+	}{
+		{"Kenya AA – 5 lb", "kenya aa", "5 lb"},
+		{"Kenya AA (Sample 250g)", "kenya aa", "Sample 250g"},
+		{"Brazil Cerrado Half Pound", "brazil cerrado", "Half Pound"},
+		{"Ethiopia Guji", "ethiopia guji", ""},
+	}
+	var g *Grouper
+	for _, tc := range testCases {
+		if got := g.BaseName(tc.input); got != tc.base {
+			t.Errorf("BaseName(%q): expected %q, got %q", tc.input, tc.base, got)
+		}
+		if got := g.Label(tc.input); got != tc.label {
+			t.Errorf("Label(%q): expected %q, got %q", tc.input, tc.label, got)
+		}
+	}
+}
+
+func TestPackLbs(t *testing.T) {
+	testCases := map[string]float64{
+		"Kenya AA – 5 lb":              5,
+		"Kenya AA (Sample 250g)":       250 / 453.59237,
+		"Brazil Cerrado Half Pound":    0.5,
+		"Colombia Huila 2.5 kg":        2500 / 453.59237,
+		"Guatemala Huehue 12oz":        0.75,
+		"Ethiopia Guji":                0,
+		"Sumatra 22 lbs Full Box":      22,
+		"Peru Cajamarca Quarter-pound": 0.25,
+	}
+	for name, want := range testCases {
+		if got := PackLbs(name); math.Abs(got-want) > 1e-9 {
+			t.Errorf("PackLbs(%q) = %f, want %f", name, got, want)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	description := "Bright and juicy with blackcurrant, grapefruit and a long brown sugar finish."
+	listings := []Listing{
+		{URL: "https://x.test/kenya-1", Name: "Kenya AA – 1 lb"},
+		{URL: "https://x.test/guji", Name: "Ethiopia Guji"},
+		{URL: "https://x.test/kenya-5", Name: "Kenya AA – 5 lb"},
+		{URL: "https://x.test/kenya-sample", Name: "Kenya AA Sample"},
+		{URL: "https://y.test/kenya", Name: "Kenya AA 1 lb"},                              // Another vendor
+		{URL: "https://x.test/taster", Name: "Nyeri Taster", Description: description},    // Site pattern
+		{URL: "https://x.test/nyeri", Name: "Nyeri Gatomboya", Description: description},  // Same description
+		{URL: "https://x.test/short", Name: "Something Else", Description: "Coming soon"}, // Too short to match
+		{URL: "https://x.test/short-2", Name: "Something Different", Description: "Coming soon"},
+	}
+	g, err := New([]string{`\btaster\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := Group(g, listings, func(l Listing) Listing { return l })
+	var names [][]string
+	for _, group := range groups {
+		var members []string
+		for _, l := range group {
+			members = append(members, l.Name)
+		}
+		names = append(names, members)
+	}
+	want := [][]string{
+		{"Kenya AA – 1 lb", "Kenya AA – 5 lb", "Kenya AA Sample"},
+		{"Ethiopia Guji"},
+		{"Kenya AA 1 lb"},
+		{"Nyeri Taster", "Nyeri Gatomboya"},
+		{"Something Else"},
+		{"Something Different"},
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Group:\n got %q\nwant %q", names, want)
+	}
+
+	if _, err := New([]string{"("}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,281 @@
+// Package vector stores embeddings compactly and scores them quickly. Vectors
+// are normalized to unit length before they are stored, so similarity is a
+// plain dot product, and can be quantized to int8 to take a quarter of the
+// space. Decoding reuses the stored bytes where the platform allows it.
+package vector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unsafe"
+)
+
+// Encoding is how a vector is laid out in a blob.
+type Encoding string
+
+const (
+	// Raw is little-endian float32 as returned by the provider, not normalized.
+	// Vectors stored before encodings existed are raw.
+	Raw Encoding = "raw"
+	// Float32 is a unit-length vector as little-endian float32.
+	Float32 Encoding = "float32"
+	// Int8 is a unit-length vector quantized to int8, with a float32 scale.
+	Int8 Encoding = "int8"
+)
+
+// ParseEncoding checks a configured encoding; empty means Float32.
+func ParseEncoding(s string) (Encoding, error) {
+	switch e := Encoding(strings.ToLower(strings.TrimSpace(s))); e {
+	case "":
+		return Float32, nil
+	case Float32, Int8:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown vector encoding %q (want float32 or int8)", s)
+	}
+}
+
+// Encoded blobs start with a 4-byte header: a tag byte then 0x00 0xC0 0x7F.
+// Read as a little-endian float32 the header is a NaN, which no provider
+// returns, so it can't be mistaken for the first value of a raw vector.
+const headerSize = 4
+
+var headerTail = [3]byte{0x00, 0xC0, 0x7F}
+
+const (
+	tagFloat32 byte = 1
+	tagInt8    byte = 2
+)
+
+// EncodingOf reports how a blob is laid out.
+func EncodingOf(b []byte) Encoding {
+	if len(b) < headerSize || [3]byte(b[1:4]) != headerTail {
+		return Raw
+	}
+	switch b[0] {
+	case tagFloat32:
+		return Float32
+	case tagInt8:
+		return Int8
+	}
+	return Raw
+}
+
+// Dims is the number of dimensions in a blob.
+func Dims(b []byte) int {
+	switch EncodingOf(b) {
+	case Float32:
+		return (len(b) - headerSize) / 4
+	case Int8:
+		return max(len(b)-headerSize-4, 0)
+	}
+	return len(b) / 4
+}
+
+// Encode normalizes v and packs it for storage as Float32 or Int8.
+func Encode(v []float32, enc Encoding) ([]byte, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("cannot encode an empty vector")
+	}
+	switch enc {
+	case Float32:
+		b := make([]byte, headerSize+4*len(v))
+		header(b, tagFloat32)
+		putFloats(b[headerSize:], Normalize(v))
+		return b, nil
+	case Int8:
+		unit := Normalize(v)
+		var peak float32
+		for _, x := range unit {
+			peak = max(peak, abs(x))
+		}
+		scale := peak / 127
+		b := make([]byte, headerSize+4+len(v))
+		header(b, tagInt8)
+		binary.LittleEndian.PutUint32(b[headerSize:], math.Float32bits(scale))
+		if scale > 0 {
+			for i, x := range unit {
+				b[headerSize+4+i] = byte(int8(math.Round(float64(x / scale))))
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown vector encoding %q", enc)
+	}
+}
+
+func header(b []byte, tag byte) {
+	b[0] = tag
+	copy(b[1:headerSize], headerTail[:])
+}
+
+func putFloats(b []byte, v []float32) {
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+}
+
+// Vector is a decoded unit-length vector, ready to be scored.
+type Vector struct {
+	floats []float32 // Set for Float32 and Raw blobs
+	ints   []int8    // Set for Int8 blobs, each value times scale
+	scale  float32
+}
+
+// Decode unpacks a blob of any encoding. Raw vectors are normalized into a
+// copy; Float32 and Int8 vectors share b's memory where the platform allows,
+// so b must not be modified while the vector is in use.
+func Decode(b []byte) (Vector, error) {
+	switch EncodingOf(b) {
+	case Float32:
+		data := b[headerSize:]
+		if len(data)%4 != 0 {
+			return Vector{}, fmt.Errorf("invalid byte length %d for a float32 vector", len(b))
+		}
+		return Vector{floats: floats(data)}, nil
+	case Int8:
+		if len(b) < headerSize+4 {
+			return Vector{}, fmt.Errorf("invalid byte length %d for an int8 vector", len(b))
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(b[headerSize:]))
+		data := b[headerSize+4:]
+		var ints []int8
+		if len(data) > 0 {
+			ints = unsafe.Slice((*int8)(unsafe.Pointer(&data[0])), len(data))
+		}
+		return Vector{ints: ints, scale: scale}, nil
+	default:
+		if len(b)%4 != 0 {
+			return Vector{}, fmt.Errorf("invalid byte length %d for a float32 vector", len(b))
+		}
+		v := copyFloats(b)
+		normalize(v)
+		return Vector{floats: v}, nil
+	}
+}
+
+// littleEndian is whether float32s in memory have the stored byte order.
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// floats views b as float32s without copying when the byte order and
+// alignment allow it.
+func floats(b []byte) []float32 {
+	if len(b) == 0 {
+		return nil
+	}
+	if littleEndian && uintptr(unsafe.Pointer(&b[0]))%unsafe.Alignof(float32(0)) == 0 {
+		return unsafe.Slice((*float32)(unsafe.Pointer(&b[0])), len(b)/4)
+	}
+	return copyFloats(b)
+}
+
+func copyFloats(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+// Len is the number of dimensions.
+func (v Vector) Len() int {
+	if v.ints != nil {
+		return len(v.ints)
+	}
+	return len(v.floats)
+}
+
+// Floats returns the values; for an Int8 vector, a dequantized copy.
+func (v Vector) Floats() []float32 {
+	if v.ints == nil {
+		return v.floats
+	}
+	out := make([]float32, len(v.ints))
+	for i, q := range v.ints {
+		out[i] = float32(q) * v.scale
+	}
+	return out
+}
+
+// Dot is the dot product with q, which is the cosine similarity when q is
+// unit length too (see Normalize). It is 0 if the lengths differ.
+func (v Vector) Dot(q []float32) float32 {
+	if v.ints != nil {
+		if len(v.ints) != len(q) {
+			return 0
+		}
+		return dotInt8(v.ints, q) * v.scale
+	}
+	if len(v.floats) != len(q) {
+		return 0
+	}
+	return Dot(v.floats, q)
+}
+
+// Dot is the dot product of two vectors of the same length.
+func Dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+func dotInt8(a []int8, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += float32(a[i]) * b[i]
+		s1 += float32(a[i+1]) * b[i+1]
+		s2 += float32(a[i+2]) * b[i+2]
+		s3 += float32(a[i+3]) * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += float32(a[i]) * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// Normalize returns a unit-length copy of v. A zero vector stays zero, so it
+// scores 0 against everything.
+func Normalize(v []float32) []float32 {
+	out := make([]float32, len(v))
+	copy(out, v)
+	normalize(out)
+	return out
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,150 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// cosine is the similarity the stored encodings have to reproduce.
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+func random(r *rand.Rand, dims int) []float32 {
+	v := make([]float32, dims)
+	for i := range v {
+		v[i] = float32(r.NormFloat64())
+	}
+	return v
+}
+
+// raw packs v the way vectors were stored before encodings.
+func raw(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func TestEncodings(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	a, b := random(r, 768), random(r, 768)
+	want := cosine(a, b)
+	query := Normalize(b)
+
+	tests := []struct {
+		enc       Encoding
+		size      int
+		tolerance float64
+	}{
+		{Float32, 4 + 4*768, 1e-5},
+		{Int8, 8 + 768, 0.01},
+	}
+	for _, tt := range tests {
+		blob, err := Encode(a, tt.enc)
+		if err != nil {
+			t.Fatalf("Encode(%s) failed: %v", tt.enc, err)
+		}
+		if len(blob) != tt.size || EncodingOf(blob) != tt.enc || Dims(blob) != 768 {
+			t.Errorf("%s blob is %d bytes, %s, %d dims", tt.enc, len(blob), EncodingOf(blob), Dims(blob))
+		}
+		v, err := Decode(blob)
+		if err != nil || v.Len() != 768 {
+			t.Fatalf("Decode(%s) = %d dims, %v", tt.enc, v.Len(), err)
+		}
+		if got := v.Dot(query); math.Abs(float64(got-want)) > tt.tolerance {
+			t.Errorf("%s similarity = %f, want %f", tt.enc, got, want)
+		}
+	}
+
+	// Raw vectors are still read, normalized on the way
+	blob := raw(a)
+	if EncodingOf(blob) != Raw || Dims(blob) != 768 {
+		t.Errorf("Raw blob read as %s, %d dims", EncodingOf(blob), Dims(blob))
+	}
+	if v, err := Decode(blob); err != nil || math.Abs(float64(v.Dot(query)-want)) > 1e-5 {
+		t.Errorf("Raw similarity = %f, %v; want %f", v.Dot(query), err, want)
+	}
+
+	// A blob at an odd address decodes by copying
+	blob, _ = Encode(a, Float32)
+	shifted := make([]byte, len(blob)+1)
+	copy(shifted[1:], blob)
+	if v, err := Decode(shifted[1:]); err != nil || math.Abs(float64(v.Dot(query)-want)) > 1e-5 {
+		t.Errorf("Unaligned similarity = %f, %v; want %f", v.Dot(query), err, want)
+	}
+
+	if _, err := Decode(blob[:len(blob)-1]); err == nil {
+		t.Error("Expected a truncated vector to be rejected")
+	}
+	if _, err := ParseEncoding("float16"); err == nil {
+		t.Error("Expected an unknown encoding to be rejected")
+	}
+	if v, _ := Decode(raw([]float32{0, 0})); v.Dot([]float32{1, 0}) != 0 {
+		t.Error("Expected a zero vector to score 0")
+	}
+}
+
+// The benchmarks score a query against a catalogue the size of a long
+// archive, from the stored blobs as search loads them.
+
+const benchDims, benchCoffees = 768, 20000
+
+func benchBlobs(encode func([]float32) []byte) ([][]byte, []float32) {
+	r := rand.New(rand.NewPCG(3, 4))
+	blobs := make([][]byte, benchCoffees)
+	for i := range blobs {
+		blobs[i] = encode(random(r, benchDims))
+	}
+	return blobs, Normalize(random(r, benchDims))
+}
+
+func benchScore(b *testing.B, encode func([]float32) []byte) {
+	blobs, query := benchBlobs(encode)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, blob := range blobs {
+			v, err := Decode(blob)
+			if err != nil {
+				b.Fatal(err)
+			}
+			v.Dot(query)
+		}
+	}
+}
+
+func BenchmarkScoreRaw(b *testing.B) { benchScore(b, raw) }
+
+func BenchmarkScoreFloat32(b *testing.B) {
+	benchScore(b, func(v []float32) []byte { blob, _ := Encode(v, Float32); return blob })
+}
+
+func BenchmarkScoreInt8(b *testing.B) {
+	benchScore(b, func(v []float32) []byte { blob, _ := Encode(v, Int8); return blob })
+}
+
+// BenchmarkScoreLegacy is how search scored before: decoding with
+// binary.Read and computing both norms every time.
+func BenchmarkScoreLegacy(b *testing.B) {
+	blobs, query := benchBlobs(raw)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, blob := range blobs {
+			v := make([]float32, len(blob)/4)
+			if err := binary.Read(bytes.NewReader(blob), binary.LittleEndian, &v); err != nil {
+				b.Fatal(err)
+			}
+			cosine(query, v)
+		}
+	}
+}